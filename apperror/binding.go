@@ -0,0 +1,81 @@
+package apperror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+// FromBinding converts an error returned by gin's ShouldBind* helpers into a
+// validation error with one entry per rejected field.
+func FromBinding(err error) *Error {
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		fields := make([]FieldError, 0, len(validationErrors))
+		for _, fe := range validationErrors {
+			fields = append(fields, FieldError{Field: fieldName(fe), Message: ruleMessage(fe)})
+		}
+		return &Error{Kind: KindValidation, Code: "validation_failed", Message: "Request validation failed", Fields: fields, Err: err}
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, io.EOF):
+		return &Error{Kind: KindValidation, Code: "empty_body", Message: "Request body is required", Err: err}
+	case errors.As(err, &syntaxErr):
+		return &Error{Kind: KindValidation, Code: "malformed_json", Message: "Request body is not valid JSON", Err: err}
+	case errors.As(err, &typeErr):
+		return &Error{
+			Kind:    KindValidation,
+			Code:    "validation_failed",
+			Message: "Request validation failed",
+			Fields:  []FieldError{{Field: typeErr.Field, Message: fmt.Sprintf("must be of type %s", typeErr.Type)}},
+			Err:     err,
+		}
+	}
+
+	return &Error{Kind: KindValidation, Code: "invalid_request", Message: "Request could not be parsed", Err: err}
+}
+
+// FromQuery maps a lookup error to a not found error when no row matched and
+// to an internal error otherwise.
+func FromQuery(err error, code, message string) *Error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &Error{Kind: KindNotFound, Code: code, Message: message, Err: err}
+	}
+	return Internal(err)
+}
+
+// fieldName returns the JSON path of the field, without the top level struct name.
+func fieldName(fe validator.FieldError) string {
+	namespace := fe.Namespace()
+	if i := strings.Index(namespace, "."); i >= 0 {
+		namespace = namespace[i+1:]
+	}
+	return namespace
+}
+
+func ruleMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "max":
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "email":
+		return "must be a valid email address"
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", fe.Param())
+	case "timezone":
+		return "must be an IANA time zone, e.g. Asia/Tokyo"
+	default:
+		return fmt.Sprintf("failed the %s rule", fe.Tag())
+	}
+}
@@ -0,0 +1,213 @@
+package controllers
+
+import (
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Llane00/ramen-backend/apperror"
+	"github.com/Llane00/ramen-backend/initializers"
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/pagination"
+	"github.com/Llane00/ramen-backend/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type AdminController struct {
+	DB *gorm.DB
+}
+
+// EmailListSpec declares the filters and sort fields accepted by FindEmails.
+var EmailListSpec = pagination.Spec{
+	Filters: []pagination.Filter{
+		{Param: "status", Column: "status", Op: "=", Values: []string{models.EmailPending, models.EmailSent, models.EmailFailed}},
+		{Param: "to", Column: "to_email", Op: "contains"},
+		{Param: "template", Column: "template", Op: "="},
+		{Param: "user", Column: "user_id", Op: "=", Kind: pagination.UUID},
+	},
+	Sorts: []pagination.SortField{
+		pagination.CreatedAt,
+		{Param: "next_attempt_at", Column: "next_attempt_at", Kind: pagination.Time},
+	},
+}
+
+func NewAdminController(DB *gorm.DB) AdminController {
+	return AdminController{DB}
+}
+
+// UnlockUser lifts a sign-in lockout and resets the failure counter.
+func (ac *AdminController) UnlockUser(ctx *gin.Context) {
+	userId, err := uuidParam(ctx, "userId", "user")
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	result := ac.DB.WithContext(ctx.Request.Context()).Model(&models.User{}).Where("id = ?", userId).
+		UpdateColumns(map[string]any{"failed_login_count": 0, "locked_until": nil})
+	if result.Error != nil {
+		ctx.Error(apperror.Internal(result.Error))
+		return
+	}
+	if result.RowsAffected == 0 {
+		ctx.Error(apperror.NotFound("user_not_found", "User not found"))
+		return
+	}
+
+	admin := ctx.MustGet("currentUser").(models.User)
+	slog.InfoContext(ctx.Request.Context(), "account unlocked", "user_id", userId, "admin_id", admin.ID)
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "message": "Account unlocked"})
+}
+
+// FindEmails lists outbox emails, e.g. ?status=failed for the dead letters.
+func (ac *AdminController) FindEmails(ctx *gin.Context) {
+	query, err := pagination.Parse(ctx, EmailListSpec)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	page, err := pagination.Find[models.EmailOutbox](ac.DB.WithContext(ctx.Request.Context()), query)
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, page)
+}
+
+func (ac *AdminController) FindEmailById(ctx *gin.Context) {
+	emailId, err := uuidParam(ctx, "emailId", "email")
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	var email models.EmailOutbox
+	result := ac.DB.WithContext(ctx.Request.Context()).First(&email, "id = ?", emailId)
+	if result.Error != nil {
+		ctx.Error(apperror.FromQuery(result.Error, "email_not_found", "Email not found"))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": email})
+}
+
+// RetryEmail puts a failed email back in the queue with a fresh set of
+// attempts. The worker sends it on its next poll.
+func (ac *AdminController) RetryEmail(ctx *gin.Context) {
+	emailId, err := uuidParam(ctx, "emailId", "email")
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	var email models.EmailOutbox
+	err = ac.DB.WithContext(ctx.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&email, "id = ?", emailId).Error; err != nil {
+			return apperror.FromQuery(err, "email_not_found", "Email not found")
+		}
+		if email.Status != models.EmailFailed {
+			return apperror.Conflict("email_not_failed", "Only failed emails can be retried")
+		}
+
+		email.Status = models.EmailPending
+		email.Attempts = 0
+		email.NextAttemptAt = time.Now()
+		email.UpdatedAt = email.NextAttemptAt
+		return tx.Model(&email).UpdateColumns(map[string]any{
+			"status":          email.Status,
+			"attempts":        email.Attempts,
+			"next_attempt_at": email.NextAttemptAt,
+			"updated_at":      email.UpdatedAt,
+		}).Error
+	})
+	if err != nil {
+		ctx.Error(apperror.From(err))
+		return
+	}
+
+	admin := ctx.MustGet("currentUser").(models.User)
+	slog.InfoContext(ctx.Request.Context(), "email requeued", "email_id", email.ID, "admin_id", admin.ID)
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": email})
+}
+
+type EmailTemplateResponse struct {
+	Name    string   `json:"name"`
+	Locales []string `json:"locales"`
+}
+
+// ListEmailTemplates lists the email templates and their translations.
+func (ac *AdminController) ListEmailTemplates(ctx *gin.Context) {
+	var templates []EmailTemplateResponse
+	for name, locales := range utils.EmailTemplates() {
+		templates = append(templates, EmailTemplateResponse{Name: name, Locales: locales})
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": templates})
+}
+
+// PreviewEmailTemplate renders a template with sample data in ?locale=,
+// falling back as a real email would.
+func (ac *AdminController) PreviewEmailTemplate(ctx *gin.Context) {
+	name := ctx.Param("template")
+	if _, ok := utils.EmailTemplates()[name]; !ok {
+		ctx.Error(apperror.NotFound("email_template_not_found", "Email template not found"))
+		return
+	}
+
+	locale := utils.DefaultLocale
+	if raw := ctx.Query("locale"); raw != "" {
+		if locale = utils.NormalizeLocale(raw); locale == "" {
+			ctx.Error(apperror.Validation("invalid_locale", "Invalid locale",
+				apperror.FieldError{Field: "locale", Message: "must be a BCP 47 language tag"}))
+			return
+		}
+	}
+
+	config, err := initializers.LoadConfig(".")
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+
+	data := sampleEmailData(&config)
+	rendered, err := utils.RenderEmail(locale, name, &data)
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": rendered})
+}
+
+// sampleEmailData fills in every field the templates use.
+func sampleEmailData(config *initializers.Config) utils.EmailData {
+	currency := config.OrderCurrency()
+	return utils.EmailData{
+		URL:       config.ClientOrigin + "/preview",
+		FirstName: "Ramen",
+		IPAddress: "203.0.113.10",
+		Device:    "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_5) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Safari/605.1.15",
+		Time:      time.Now().UTC().Format(time.RFC1123),
+		NewEmail:  "new.address@example.com",
+		Order: &utils.OrderEmailData{
+			Number:   "1A2B3C4D",
+			ShopName: "Ichiran Shibuya",
+			Status:   string(models.OrderStatusShipping),
+			Items: []utils.OrderLineData{
+				{Name: "Tonkotsu ramen", Quantity: 2, UnitPrice: utils.FormatCents(1450, currency), Subtotal: utils.FormatCents(2900, currency)},
+				{Name: "Ajitama egg", Quantity: 1, UnitPrice: utils.FormatCents(250, currency), Subtotal: utils.FormatCents(250, currency)},
+			},
+			Total:         utils.FormatCents(3150, currency),
+			PaymentMethod: "card",
+			Amount:        utils.FormatCents(3150, currency),
+		},
+	}
+}
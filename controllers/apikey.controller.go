@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Llane00/ramen-backend/apperror"
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultAPIKeyLifetime = 90 * 24 * time.Hour
+	maxActiveAPIKeys      = 20
+)
+
+type APIKeyController struct {
+	DB *gorm.DB
+}
+
+func NewAPIKeyController(DB *gorm.DB) APIKeyController {
+	return APIKeyController{DB}
+}
+
+func (kc *APIKeyController) ListAPIKeys(ctx *gin.Context) {
+	currentUser := ctx.MustGet("currentUser").(models.User)
+
+	var keys []models.APIKey
+	if err := kc.DB.WithContext(ctx.Request.Context()).Where("user_id = ?", currentUser.ID).Order("created_at DESC").Find(&keys).Error; err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+
+	data := make([]models.APIKeyResponse, len(keys))
+	for i := range keys {
+		data[i] = models.NewAPIKeyResponse(&keys[i])
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": data})
+}
+
+// CreateAPIKey issues a new key. The full key is only part of this response.
+func (kc *APIKeyController) CreateAPIKey(ctx *gin.Context) {
+	currentUser := ctx.MustGet("currentUser").(models.User)
+	var payload *models.CreateAPIKeyInput
+
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.Error(apperror.FromBinding(err))
+		return
+	}
+
+	db := kc.DB.WithContext(ctx.Request.Context())
+	now := time.Now()
+
+	var active int64
+	if err := db.Model(&models.APIKey{}).Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", currentUser.ID, now).Count(&active).Error; err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+	if active >= maxActiveAPIKeys {
+		ctx.Error(apperror.Conflict("too_many_api_keys", "Revoke an existing API key before creating another"))
+		return
+	}
+
+	lifetime := defaultAPIKeyLifetime
+	if payload.ExpiresInDays > 0 {
+		lifetime = time.Duration(payload.ExpiresInDays) * 24 * time.Hour
+	}
+
+	key, prefix := utils.GenerateAPIKey()
+	apiKey := models.APIKey{
+		UserID:    currentUser.ID,
+		Name:      payload.Name,
+		Prefix:    prefix,
+		KeyHash:   utils.HashToken(key),
+		Scopes:    payload.Scopes,
+		ExpiresAt: now.Add(lifetime),
+		CreatedAt: now,
+	}
+	if err := db.Omit("User").Create(&apiKey).Error; err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+
+	response := models.NewAPIKeyResponse(&apiKey)
+	response.Key = key
+	ctx.JSON(http.StatusCreated, gin.H{"status": "success", "data": response})
+}
+
+func (kc *APIKeyController) RevokeAPIKey(ctx *gin.Context) {
+	currentUser := ctx.MustGet("currentUser").(models.User)
+	keyId, err := uuidParam(ctx, "keyId", "api_key")
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	result := kc.DB.WithContext(ctx.Request.Context()).Model(&models.APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", keyId, currentUser.ID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		ctx.Error(apperror.Internal(result.Error))
+		return
+	}
+	if result.RowsAffected == 0 {
+		ctx.Error(apperror.NotFound("api_key_not_found", "No active API key with that ID exists"))
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
@@ -1,17 +1,19 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"strings"
-	"time"
 
+	"github.com/Llane00/ramen-backend/apperror"
 	"github.com/Llane00/ramen-backend/initializers"
+	"github.com/Llane00/ramen-backend/keyset"
+	"github.com/Llane00/ramen-backend/metrics"
 	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/outbox"
 	"github.com/Llane00/ramen-backend/utils"
 	"github.com/gin-gonic/gin"
-	"github.com/thanhpk/randstr"
 	"gorm.io/gorm"
 )
 
@@ -27,18 +29,19 @@ func (ac *AuthController) SignUpUser(ctx *gin.Context) {
 	var payload *models.SignUpInput
 
 	if err := ctx.ShouldBindJSON(&payload); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		ctx.Error(apperror.FromBinding(err))
 		return
 	}
 
 	if payload.Password != payload.PasswordConfirm {
-		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Passwords do not match"})
+		ctx.Error(apperror.Validation("password_mismatch", "Passwords do not match",
+			apperror.FieldError{Field: "passwordConfirm", Message: "must match password"}))
 		return
 	}
 
-	hashedPassword, err := utils.HashPassword(payload.Password)
+	hashedPassword, err := utils.HashPassword(ctx.Request.Context(), payload.Password)
 	if err != nil {
-		ctx.JSON(http.StatusBadGateway, gin.H{"status": "error", "message": err.Error()})
+		ctx.Error(apperror.Internal(err))
 		return
 	}
 
@@ -48,110 +51,202 @@ func (ac *AuthController) SignUpUser(ctx *gin.Context) {
 		Password: hashedPassword,
 		Roles:    models.UserRoles{models.RoleUser},
 		Verified: false,
+		Locale:   requestLocale(ctx, ""),
 		Photo:    payload.Photo,
 		Provider: "local",
 	}
 
-	result := ac.DB.Create(&newUser)
+	config, _ := initializers.LoadConfig(".")
+
+	err = ac.DB.WithContext(ctx.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&newUser).Error; err != nil {
+			return err
+		}
+		return queueVerificationEmail(tx, &config, &newUser, ctx.ClientIP())
+	})
 
-	if result.Error != nil && strings.Contains(result.Error.Error(), "duplicate key value violates unique") {
-		ctx.JSON(http.StatusConflict, gin.H{"status": "fail", "message": "User with that email already exists"})
+	if err != nil && strings.Contains(err.Error(), "duplicate key value violates unique") {
+		ctx.Error(apperror.Conflict("email_taken", "User with that email already exists"))
 		return
-	} else if result.Error != nil {
-		ctx.JSON(http.StatusBadGateway, gin.H{"status": "error", "message": "Some Error happened"})
+	} else if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+	metrics.SignUps.WithLabelValues(newUser.Provider).Inc()
+
+	ctx.JSON(http.StatusCreated, gin.H{"status": "success", "message": "We sent an email with a verification code to " + newUser.Email})
+}
+
+func (ac *AuthController) SignInUser(ctx *gin.Context) {
+	var payload *models.SignInInput
+
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.Error(apperror.FromBinding(err))
 		return
 	}
 
 	config, _ := initializers.LoadConfig(".")
+	policy := config.LoginPolicy()
 
-	// Generate Verification Code
-	code := randstr.String(20)
+	if err := ac.checkIPBlocked(ctx, policy); err != nil {
+		ctx.Error(err)
+		return
+	}
 
-	verification_code := utils.Encode(code)
+	email := strings.ToLower(payload.Email)
 
-	// Update user with verification code
-	ac.DB.Model(&newUser).Update("verification_code", verification_code)
+	var user models.User
+	result := ac.DB.WithContext(ctx.Request.Context()).First(&user, "email = ?", email)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		metrics.Logins.WithLabelValues("failure").Inc()
+		ac.recordLoginAttempt(ctx, nil, truncate(email, 255), "", false)
+		ctx.Error(apperror.Unauthorized("invalid_credentials", "Invalid email or Password"))
+		return
+	} else if result.Error != nil {
+		ctx.Error(apperror.Internal(result.Error))
+		return
+	}
 
-	// 👇 Send Email
-	var firstName = newUser.Name
+	if user.Password == "" {
+		ctx.Error(apperror.Unauthorized("use_oauth_provider", fmt.Sprintf("Use %v OAuth instead", user.Provider)))
+		return
+	}
 
-	if strings.Contains(firstName, " ") {
-		firstName = strings.Split(firstName, " ")[1]
+	if err := checkAccountLocked(ctx, &user); err != nil {
+		ctx.Error(err)
+		return
 	}
-	emailData := utils.EmailData{
-		URL:       config.ClientOrigin + "/verifyemail/" + code,
-		FirstName: firstName,
-		Subject:   "Your account verification code",
+
+	delayLogin(ctx, policy, user.FailedLoginCount)
+
+	if err := utils.VerifyPassword(ctx.Request.Context(), user.Password, payload.Password); err != nil {
+		if err := ac.loginFailed(ctx, policy, &user); err != nil {
+			ctx.Error(apperror.Internal(err))
+			return
+		}
+		ctx.Error(apperror.Unauthorized("invalid_credentials", "Invalid email or Password"))
+		return
 	}
 
-	var message string
-	err = utils.SendEmail(&newUser, &emailData, "verificationCode.html")
-	if err != nil {
-		// Log the error
-		log.Printf("Failed to send email: %v", err)
+	if !user.Verified {
+		ctx.Error(apperror.Forbidden("email_not_verified", "Please verify your email"))
+		return
+	}
 
-		// Optionally, you can choose to return an error response to the client
-		// ctx.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to send verification email"})
-		// return
+	// With two-factor enabled the password only earns a short-lived challenge
+	// token; the session is issued by VerifyMFALogin.
+	if user.TOTPEnabled {
+		mfa_token, err := utils.CreatePurposeToken(config.MFATokenTTL(), user.ID, utils.PurposeMFA, keyset.Access)
+		if err != nil {
+			ctx.Error(apperror.Internal(err))
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"status": "mfa_required", "mfa_token": mfa_token})
+		return
+	}
+
+	if err := ac.loginSucceeded(ctx, &user); err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
 
-		message = "Account created, but we couldn't send the verification email. Please contact support."
-	} else {
-		message = "We sent an email with a verification code to " + newUser.Email
+	access_token, err := issueSession(ctx, &config, &user)
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
 	}
 
-	ctx.JSON(http.StatusCreated, gin.H{"status": "success", "message": message})
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "access_token": access_token})
 }
 
-func (ac *AuthController) SignInUser(ctx *gin.Context) {
-	var payload *models.SignInInput
+// VerifyMFALogin completes a two-factor sign-in with the challenge token from
+// SignInUser and a TOTP or recovery code. Wrong codes count towards the
+// account lockout like wrong passwords.
+func (ac *AuthController) VerifyMFALogin(ctx *gin.Context) {
+	var payload *models.MFALoginInput
 
 	if err := ctx.ShouldBindJSON(&payload); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		ctx.Error(apperror.FromBinding(err))
 		return
 	}
 
-	var user models.User
-	result := ac.DB.First(&user, "email = ?", strings.ToLower(payload.Email))
-	if result.Error != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid email or Password"})
+	config, _ := initializers.LoadConfig(".")
+	policy := config.LoginPolicy()
+
+	if err := ac.checkIPBlocked(ctx, policy); err != nil {
+		ctx.Error(err)
 		return
 	}
 
-	if user.Provider == "Google" {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"status": "fail", "message": fmt.Sprintf("Use %v OAuth instead", user.Provider)})
+	sub, err := utils.ValidatePurposeToken(payload.MFAToken, utils.PurposeMFA, keyset.Access)
+	if err != nil {
+		ctx.Error(&apperror.Error{Kind: apperror.KindUnauthorized, Code: "invalid_mfa_token", Message: "The sign-in attempt is invalid or has expired, please sign in again", Err: err})
 		return
 	}
 
-	if !user.Verified {
-		ctx.JSON(http.StatusForbidden, gin.H{"status": "fail", "message": "Please verify your email"})
+	var user models.User
+	result := ac.DB.WithContext(ctx.Request.Context()).First(&user, "id = ?", fmt.Sprint(sub))
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		ctx.Error(apperror.Unauthorized("invalid_mfa_token", "The sign-in attempt is invalid or has expired, please sign in again"))
+		return
+	} else if result.Error != nil {
+		ctx.Error(apperror.Internal(result.Error))
 		return
 	}
 
-	if err := utils.VerifyPassword(user.Password, payload.Password); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid email or Password"})
+	if err := checkAccountLocked(ctx, &user); err != nil {
+		ctx.Error(err)
 		return
 	}
 
-	config, _ := initializers.LoadConfig(".")
+	delayLogin(ctx, policy, user.FailedLoginCount)
 
-	// Generate Tokens
-	access_token, err := utils.CreateToken(config.AccessTokenExpiresIn, user.ID, config.AccessTokenPrivateKey)
+	ok, err := verifySecondFactor(ctx.Request.Context(), ac.DB, &user, payload.Code)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+	if !ok || !user.TOTPEnabled {
+		if err := ac.loginFailed(ctx, policy, &user); err != nil {
+			ctx.Error(apperror.Internal(err))
+			return
+		}
+		ctx.Error(apperror.Unauthorized("invalid_mfa_code", "Invalid two-factor code"))
+		return
+	}
+
+	if err := ac.loginSucceeded(ctx, &user); err != nil {
+		ctx.Error(apperror.Internal(err))
 		return
 	}
 
-	refresh_token, err := utils.CreateToken(config.RefreshTokenExpiresIn, user.ID, config.RefreshTokenPrivateKey)
+	access_token, err := issueSession(ctx, &config, &user)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		ctx.Error(apperror.Internal(err))
 		return
 	}
 
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "access_token": access_token})
+}
+
+// issueSession creates the access and refresh tokens for user and sets the
+// session cookies. It returns the access token for the response body.
+func issueSession(ctx *gin.Context, config *initializers.Config, user *models.User) (string, error) {
+	access_token, err := utils.CreateToken(config.AccessTokenExpiresIn, user.ID, keyset.Access)
+	if err != nil {
+		return "", err
+	}
+
+	refresh_token, err := utils.CreateToken(config.RefreshTokenExpiresIn, user.ID, keyset.Refresh)
+	if err != nil {
+		return "", err
+	}
+
 	ctx.SetCookie("access_token", access_token, config.AccessTokenMaxAge*60, "/", "localhost", false, true)
 	ctx.SetCookie("refresh_token", refresh_token, config.RefreshTokenMaxAge*60, "/", "localhost", false, true)
 	ctx.SetCookie("logged_in", "true", config.AccessTokenMaxAge*60, "/", "localhost", false, false)
 
-	ctx.JSON(http.StatusOK, gin.H{"status": "success", "access_token": access_token})
+	return access_token, nil
 }
 
 func (ac *AuthController) RefreshAccessToken(ctx *gin.Context) {
@@ -160,28 +255,31 @@ func (ac *AuthController) RefreshAccessToken(ctx *gin.Context) {
 	cookie, err := ctx.Cookie("refresh_token")
 
 	if err != nil {
-		ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"status": "fail", "message": message})
+		ctx.Error(apperror.Forbidden("invalid_refresh_token", message))
 		return
 	}
 
 	config, _ := initializers.LoadConfig(".")
 
-	sub, err := utils.ValidateToken(cookie, config.RefreshTokenPublicKey)
+	sub, err := utils.ValidateToken(cookie, keyset.Refresh)
 	if err != nil {
-		ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"status": "fail", "message": err.Error()})
+		ctx.Error(&apperror.Error{Kind: apperror.KindForbidden, Code: "invalid_refresh_token", Message: message, Err: err})
 		return
 	}
 
 	var user models.User
-	result := ac.DB.First(&user, "id = ?", fmt.Sprint(sub))
-	if result.Error != nil {
-		ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"status": "fail", "message": "the user belonging to this token no logger exists"})
+	result := ac.DB.WithContext(ctx.Request.Context()).First(&user, "id = ?", fmt.Sprint(sub))
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		ctx.Error(apperror.Forbidden("user_not_found", "The user belonging to this token no longer exists"))
+		return
+	} else if result.Error != nil {
+		ctx.Error(apperror.Internal(result.Error))
 		return
 	}
 
-	access_token, err := utils.CreateToken(config.AccessTokenExpiresIn, user.ID, config.AccessTokenPrivateKey)
+	access_token, err := utils.CreateToken(config.AccessTokenExpiresIn, user.ID, keyset.Access)
 	if err != nil {
-		ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"status": "fail", "message": err.Error()})
+		ctx.Error(apperror.Internal(err))
 		return
 	}
 
@@ -202,23 +300,22 @@ func (ac *AuthController) LogoutUser(ctx *gin.Context) {
 func (ac *AuthController) VerifyEmail(ctx *gin.Context) {
 
 	code := ctx.Params.ByName("verificationCode")
-	verification_code := utils.Encode(code)
 
-	var updatedUser models.User
-	result := ac.DB.First(&updatedUser, "verification_code = ?", verification_code)
-	if result.Error != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid verification code or user doesn't exists"})
+	db := ac.DB.WithContext(ctx.Request.Context())
+	token, err := consumeUserToken(db, models.TokenEmailVerification, code)
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
 		return
 	}
-
-	if updatedUser.Verified {
-		ctx.JSON(http.StatusConflict, gin.H{"status": "fail", "message": "User already verified"})
+	if token == nil {
+		ctx.Error(apperror.Validation("invalid_verification_code", "Invalid verification code or user doesn't exists"))
 		return
 	}
 
-	updatedUser.VerificationCode = ""
-	updatedUser.Verified = true
-	ac.DB.Save(&updatedUser)
+	if err := db.Model(&models.User{}).Where("id = ?", token.UserID).Update("verified", true).Error; err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
 
 	ctx.JSON(http.StatusOK, gin.H{"status": "success", "message": "Email verified successfully"})
 }
@@ -227,52 +324,57 @@ func (ac *AuthController) ForgotPassword(ctx *gin.Context) {
 	var payload *models.ForgotPasswordInput
 
 	if err := ctx.ShouldBindJSON(&payload); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		ctx.Error(apperror.FromBinding(err))
 		return
 	}
 
 	message := "You will receive a reset email if user with that email exist"
 
 	var user models.User
-	result := ac.DB.First(&user, "email = ?", strings.ToLower(payload.Email))
-	if result.Error != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Invalid email or Password"})
+	result := ac.DB.WithContext(ctx.Request.Context()).First(&user, "email = ?", strings.ToLower(payload.Email))
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		ctx.Error(apperror.Validation("invalid_email", "Invalid email or Password"))
+		return
+	} else if result.Error != nil {
+		ctx.Error(apperror.Internal(result.Error))
 		return
 	}
 
 	if !user.Verified {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"status": "error", "message": "Account not verified"})
+		ctx.Error(apperror.Forbidden("email_not_verified", "Account not verified"))
 		return
 	}
 
 	config, err := initializers.LoadConfig(".")
 	if err != nil {
-		log.Fatal("Could not load config", err)
+		ctx.Error(apperror.Internal(err))
+		return
 	}
 
-	// Generate Verification Code
-	resetToken := randstr.String(20)
-
-	passwordResetToken := utils.Encode(resetToken)
-	user.PasswordResetToken = passwordResetToken
-	user.PasswordResetAt = time.Now().Add(time.Minute * 15)
-	ac.DB.Save(&user)
+	err = ac.DB.WithContext(ctx.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		resetToken, err := issueUserToken(tx,
+			models.UserToken{UserID: user.ID, Purpose: models.TokenPasswordReset, IPAddress: ctx.ClientIP()}, passwordResetTTL)
+		if err != nil {
+			return err
+		}
 
-	var firstName = user.Name
+		var firstName = user.Name
 
-	if strings.Contains(firstName, " ") {
-		firstName = strings.Split(firstName, " ")[1]
-	}
+		if strings.Contains(firstName, " ") {
+			firstName = strings.Split(firstName, " ")[1]
+		}
 
-	// Send Email
-	emailData := utils.EmailData{
-		URL:       config.ClientOrigin + "/resetpassword/" + resetToken,
-		FirstName: firstName,
-		Subject:   "Your password reset token (valid for 10min)",
+		emailData := utils.EmailData{
+			URL:       config.ClientOrigin + "/resetpassword/" + resetToken,
+			FirstName: firstName,
+		}
+		return outbox.Enqueue(tx, &user, &emailData, "resetPassword.html")
+	})
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
 	}
 
-	utils.SendEmail(&user, &emailData, "resetPassword.html")
-
 	ctx.JSON(http.StatusOK, gin.H{"status": "success", "message": message})
 }
 
@@ -281,102 +383,50 @@ func (ac *AuthController) ResetPassword(ctx *gin.Context) {
 	resetToken := ctx.Params.ByName("resetToken")
 
 	if err := ctx.ShouldBindJSON(&payload); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
+		ctx.Error(apperror.FromBinding(err))
 		return
 	}
 
 	if payload.Password != payload.PasswordConfirm {
-		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "Passwords do not match"})
-		return
-	}
-
-	hashedPassword, _ := utils.HashPassword(payload.Password)
-
-	passwordResetToken := utils.Encode(resetToken)
-
-	var updatedUser models.User
-	result := ac.DB.First(&updatedUser, "password_reset_token = ? AND password_reset_at > ?", passwordResetToken, time.Now())
-	if result.Error != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": "The reset token is invalid or has expired"})
+		ctx.Error(apperror.Validation("password_mismatch", "Passwords do not match",
+			apperror.FieldError{Field: "passwordConfirm", Message: "must match password"}))
 		return
 	}
 
-	updatedUser.Password = hashedPassword
-	updatedUser.PasswordResetToken = ""
-	ac.DB.Save(&updatedUser)
-
-	ctx.SetCookie("token", "", -1, "/", "localhost", false, true)
-
-	ctx.JSON(http.StatusOK, gin.H{"status": "success", "message": "Password data updated successfully"})
-}
-
-func (ac *AuthController) GoogleOAuth(ctx *gin.Context) {
-	code := ctx.Query("code")
-	log.Printf("Received authorization code: %s", code)
-	var pathUrl string = "/"
-
-	if ctx.Query("state") != "" {
-		pathUrl = ctx.Query("state")
-	}
-
-	if code == "" {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"status": "fail", "message": "Authorization code not provided!"})
-		return
-	}
-
-	tokenRes, err := utils.GetGoogleOauthToken(code)
+	hashedPassword, err := utils.HashPassword(ctx.Request.Context(), payload.Password)
 	if err != nil {
-		log.Printf("Failed to exchange token: %v", err)
-		errorMessage := "Failed to get Google OAuth token"
-		errorMessage += ": " + err.Error()
-		ctx.JSON(http.StatusBadGateway, gin.H{"status": "fail", "message": errorMessage})
+		ctx.Error(apperror.Internal(err))
 		return
 	}
 
-	google_user, err := utils.GetGoogleUser(tokenRes.Access_token, tokenRes.Id_token)
-
+	// Spending the token, setting the password and invalidating every other
+	// outstanding token happen together, so a leaked older reset or sign-in
+	// link stops working with the new password.
+	err = ac.DB.WithContext(ctx.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		token, err := consumeUserToken(tx, models.TokenPasswordReset, resetToken)
+		if err != nil {
+			return err
+		}
+		if token == nil {
+			return apperror.Validation("invalid_reset_token", "The reset token is invalid or has expired")
+		}
+
+		err = tx.Model(&models.User{}).Where("id = ?", token.UserID).Updates(map[string]any{
+			"password":           hashedPassword,
+			"failed_login_count": 0,
+			"locked_until":       nil,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return invalidateUserTokens(tx, token.UserID)
+	})
 	if err != nil {
-		ctx.JSON(http.StatusBadGateway, gin.H{"status": "fail", "message": err.Error()})
+		ctx.Error(apperror.From(err))
 		return
 	}
 
-	email := strings.ToLower(google_user.Email)
-
-	user_data := models.User{
-		Name:     google_user.Name,
-		Email:    email,
-		Password: "",
-		Roles:    models.UserRoles{models.RoleUser},
-		Verified: true,
-		Photo:    google_user.Picture,
-		Provider: "Google",
-	}
-
-	if initializers.DB.Model(&user_data).Where("email = ?", email).Updates(&user_data).RowsAffected == 0 {
-		initializers.DB.Create(&user_data)
-	}
-
-	var user models.User
-	initializers.DB.First(&user, "email = ?", email)
-
-	config, _ := initializers.LoadConfig(".")
-
-	// Generate Tokens
-	access_token, err := utils.CreateToken(config.AccessTokenExpiresIn, user.ID, config.AccessTokenPrivateKey)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
-		return
-	}
-
-	refresh_token, err := utils.CreateToken(config.RefreshTokenExpiresIn, user.ID, config.RefreshTokenPrivateKey)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"status": "fail", "message": err.Error()})
-		return
-	}
-
-	ctx.SetCookie("access_token", access_token, config.AccessTokenMaxAge*60, "/", "localhost", false, true)
-	ctx.SetCookie("refresh_token", refresh_token, config.RefreshTokenMaxAge*60, "/", "localhost", false, true)
-	ctx.SetCookie("logged_in", "true", config.AccessTokenMaxAge*60, "/", "localhost", false, false)
+	ctx.SetCookie("token", "", -1, "/", "localhost", false, true)
 
-	ctx.Redirect(http.StatusTemporaryRedirect, fmt.Sprint(config.ClientOrigin, pathUrl))
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "message": "Password data updated successfully"})
 }
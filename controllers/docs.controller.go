@@ -0,0 +1,31 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/Llane00/ramen-backend/openapi"
+	"github.com/gin-gonic/gin"
+)
+
+type DocsController struct {
+	Spec []byte
+}
+
+func NewDocsController(spec []byte) DocsController {
+	return DocsController{spec}
+}
+
+// OpenAPISpec serves the generated OpenAPI 3 document.
+func (dc *DocsController) OpenAPISpec(ctx *gin.Context) {
+	ctx.Data(http.StatusOK, "application/json; charset=utf-8", dc.Spec)
+}
+
+// DocsUI serves the interactive API documentation.
+func (dc *DocsController) DocsUI(ctx *gin.Context) {
+	ctx.Data(http.StatusOK, "text/html; charset=utf-8", openapi.DocsHTML)
+}
+
+// DocsAsset serves a Swagger UI asset embedded in the binary.
+func (dc *DocsController) DocsAsset(ctx *gin.Context) {
+	ctx.FileFromFS(ctx.Param("asset"), http.FS(openapi.SwaggerUI))
+}
@@ -0,0 +1,204 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Llane00/ramen-backend/apperror"
+	"github.com/Llane00/ramen-backend/initializers"
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/outbox"
+	"github.com/Llane00/ramen-backend/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// verificationResendCooldown is the minimum time between two verification
+// emails to the same account.
+const verificationResendCooldown = time.Minute
+
+// queueVerificationEmail issues a new verification code for user and queues
+// the email carrying it.
+func queueVerificationEmail(db *gorm.DB, config *initializers.Config, user *models.User, ip string) error {
+	code, err := issueUserToken(db,
+		models.UserToken{UserID: user.ID, Purpose: models.TokenEmailVerification, IPAddress: ip}, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	emailData := utils.EmailData{
+		URL:       config.ClientOrigin + "/verifyemail/" + code,
+		FirstName: firstName(user.Name),
+	}
+	return outbox.Enqueue(db, user, &emailData, "verificationCode.html")
+}
+
+// ResendVerificationEmail sends a new verification email to an unverified
+// account. Like RequestMagicLink it answers the same for every address and
+// only queues the email.
+func (ac *AuthController) ResendVerificationEmail(ctx *gin.Context) {
+	var payload *models.ResendVerificationInput
+
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.Error(apperror.FromBinding(err))
+		return
+	}
+
+	config, err := initializers.LoadConfig(".")
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+
+	ac.queueVerificationResend(ctx.Request.Context(), &config, strings.ToLower(payload.Email), ctx.ClientIP())
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "message": "You will receive a verification email if an unverified user with that email exists"})
+}
+
+// queueVerificationResend queues a new verification email for the
+// unverified user with email, at most once per cooldown. Failures are only
+// logged so the response stays the same.
+func (ac *AuthController) queueVerificationResend(ctx context.Context, config *initializers.Config, email string, ip string) {
+	db := ac.DB.WithContext(ctx)
+
+	var user models.User
+	if err := db.First(&user, "email = ?", email).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			slog.ErrorContext(ctx, "could not look up user for verification email", "error", err)
+		}
+		return
+	}
+	if user.Verified {
+		return
+	}
+
+	recent, err := recentUserToken(db, user.ID, models.TokenEmailVerification, time.Now().Add(-verificationResendCooldown))
+	if err != nil {
+		slog.ErrorContext(ctx, "could not check verification email cooldown", "user_id", user.ID, "error", err)
+		return
+	}
+	if recent {
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		return queueVerificationEmail(tx, config, &user, ip)
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "could not queue verification email", "user_id", user.ID, "error", err)
+	}
+}
+
+// ChangeEmail starts changing the current user's address. The new address
+// gets a confirmation link and the old one a notice; User.Email only changes
+// once the link is followed.
+func (uc *UserController) ChangeEmail(ctx *gin.Context) {
+	currentUser := ctx.MustGet("currentUser").(models.User)
+	var payload *models.ChangeEmailInput
+
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.Error(apperror.FromBinding(err))
+		return
+	}
+
+	newEmail := strings.ToLower(payload.Email)
+	if newEmail == currentUser.Email {
+		ctx.Error(apperror.Validation("email_unchanged", "That is already your email address",
+			apperror.FieldError{Field: "email", Message: "must differ from the current address"}))
+		return
+	}
+
+	if err := reauthenticate(ctx, uc.DB, &currentUser, payload.Password, payload.Code); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	db := uc.DB.WithContext(ctx.Request.Context())
+
+	var taken int64
+	if err := db.Model(&models.User{}).Where("email = ?", newEmail).Count(&taken).Error; err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+	if taken > 0 {
+		ctx.Error(apperror.Conflict("email_taken", "User with that email already exists"))
+		return
+	}
+
+	config, err := initializers.LoadConfig(".")
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		token, err := issueUserToken(tx,
+			models.UserToken{UserID: currentUser.ID, Purpose: models.TokenEmailChange, Email: newEmail, IPAddress: ctx.ClientIP()}, emailChangeTTL)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		recipient := currentUser
+		recipient.Email = newEmail
+		confirmation := utils.EmailData{
+			URL:       config.ClientOrigin + "/confirmemail/" + token,
+			FirstName: firstName(currentUser.Name),
+			Time:      now.Add(emailChangeTTL).UTC().Format(time.RFC1123),
+		}
+		if err := outbox.Enqueue(tx, &recipient, &confirmation, "emailChange.html"); err != nil {
+			return err
+		}
+
+		notice := utils.EmailData{
+			URL:       config.ClientOrigin + "/forgotpassword",
+			FirstName: firstName(currentUser.Name),
+			IPAddress: ctx.ClientIP(),
+			Device:    ctx.Request.UserAgent(),
+			Time:      now.UTC().Format(time.RFC1123),
+			NewEmail:  newEmail,
+		}
+		return outbox.Enqueue(tx, &currentUser, &notice, "emailChangeNotice.html")
+	})
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{"status": "success", "message": "We sent a confirmation link to " + newEmail})
+}
+
+// ConfirmEmailChange swaps the user's address for the one the token was sent
+// to. Links emailed to the old address stop working.
+func (ac *AuthController) ConfirmEmailChange(ctx *gin.Context) {
+	code := ctx.Params.ByName("token")
+
+	err := ac.DB.WithContext(ctx.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		token, err := consumeUserToken(tx, models.TokenEmailChange, code)
+		if err != nil {
+			return err
+		}
+		if token == nil {
+			return apperror.Validation("invalid_email_change_token", "The confirmation link is invalid, expired or already used")
+		}
+
+		err = tx.Model(&models.User{}).Where("id = ?", token.UserID).
+			Updates(map[string]any{"email": token.Email, "verified": true}).Error
+		if err != nil && strings.Contains(err.Error(), "duplicate key value violates unique") {
+			return apperror.Conflict("email_taken", "User with that email already exists")
+		} else if err != nil {
+			return err
+		}
+		return invalidateUserTokens(tx, token.UserID)
+	})
+	if err != nil {
+		ctx.Error(apperror.From(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "message": "Email address changed successfully"})
+}
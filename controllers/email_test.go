@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/testdb"
+)
+
+func TestResendVerificationEmailQueuesEmail(t *testing.T) {
+	testdb.Config(t)
+	db := testdb.Open(t, &models.User{}, &models.UserToken{}, &models.EmailOutbox{})
+	ac := NewAuthController(db)
+
+	unverified := createTestUser(t, db)
+	db.Model(&unverified).Update("verified", false)
+	verified := createTestUser(t, db)
+
+	// The second request for the same address falls within the cooldown.
+	for _, email := range []string{unverified.Email, unverified.Email, verified.Email, "nobody@ramen.example"} {
+		ctx, rec := newTestContext(http.MethodPost, "/api/auth/verifyemail/resend", models.ResendVerificationInput{Email: email})
+		ac.ResendVerificationEmail(ctx)
+		if rec.Code != http.StatusOK || len(ctx.Errors) > 0 {
+			t.Fatalf("ResendVerificationEmail(%s) = %d %v, want 200", email, rec.Code, ctx.Errors)
+		}
+	}
+
+	var queued []models.EmailOutbox
+	if err := db.Where("template = ?", "verificationCode.html").Find(&queued).Error; err != nil {
+		t.Fatalf("find emails: %v", err)
+	}
+	if len(queued) != 1 || queued[0].ToEmail != unverified.Email {
+		t.Fatalf("queued verification emails = %+v, want one to %s", queued, unverified.Email)
+	}
+}
@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Llane00/ramen-backend/health"
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const readinessTimeout = 2 * time.Second
+
+type HealthController struct {
+	DB *gorm.DB
+}
+
+func NewHealthController(DB *gorm.DB) HealthController {
+	return HealthController{DB}
+}
+
+type healthCheck struct {
+	Status   string `json:"status"`
+	Message  string `json:"message,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Healthchecker is the original static health endpoint, kept for existing clients.
+func (hc *HealthController) Healthchecker(ctx *gin.Context) {
+	message := "Welcome to Golang with Gorm and Postgres"
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "message": message})
+}
+
+// Livez reports whether the process is running. It never touches dependencies
+// so a slow database does not get the container restarted.
+func (hc *HealthController) Livez(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readyz reports whether the instance can serve traffic: the database answers,
+// the schema is migrated, background workers are beating and the server is
+// not draining for shutdown.
+func (hc *HealthController) Readyz(ctx *gin.Context) {
+	checkCtx, cancel := context.WithTimeout(ctx.Request.Context(), readinessTimeout)
+	defer cancel()
+
+	checks := map[string]healthCheck{
+		"shutdown":   runCheck(hc.checkDraining),
+		"database":   runCheck(func() error { return hc.checkDatabase(checkCtx) }),
+		"migrations": runCheck(func() error { return hc.checkMigrations(checkCtx) }),
+		"workers":    runCheck(hc.checkWorkers),
+	}
+
+	status, code := "ready", http.StatusOK
+	for _, check := range checks {
+		if check.Status != "ok" {
+			status, code = "not_ready", http.StatusServiceUnavailable
+			break
+		}
+	}
+
+	ctx.JSON(code, gin.H{"status": status, "checks": checks, "workers": health.Workers()})
+}
+
+func runCheck(check func() error) healthCheck {
+	start := time.Now()
+	err := check()
+	result := healthCheck{Status: "ok", Duration: time.Since(start).String()}
+	if err != nil {
+		result.Status = "fail"
+		result.Message = err.Error()
+	}
+	return result
+}
+
+func (hc *HealthController) checkDraining() error {
+	if health.IsDraining() {
+		return fmt.Errorf("server is shutting down")
+	}
+	return nil
+}
+
+func (hc *HealthController) checkDatabase(ctx context.Context) error {
+	sqlDB, err := hc.DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+func (hc *HealthController) checkMigrations(ctx context.Context) error {
+	var version int
+	err := hc.DB.WithContext(ctx).Model(&models.SchemaMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	if err != nil {
+		return err
+	}
+	if version < models.SchemaVersion {
+		return fmt.Errorf("schema version %d is behind expected version %d", version, models.SchemaVersion)
+	}
+	return nil
+}
+
+func (hc *HealthController) checkWorkers() error {
+	for _, worker := range health.Workers() {
+		if !worker.Healthy {
+			return fmt.Errorf("worker %s is not healthy", worker.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Llane00/ramen-backend/apperror"
+	"github.com/Llane00/ramen-backend/initializers"
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/oauth"
+	"github.com/Llane00/ramen-backend/outbox"
+	"github.com/Llane00/ramen-backend/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// reauthenticationCooldown is the minimum time between two confirmation
+// emails.
+const reauthenticationCooldown = time.Minute
+
+type IdentityController struct {
+	DB        *gorm.DB
+	Providers oauth.Registry
+}
+
+func NewIdentityController(DB *gorm.DB, providers oauth.Registry) IdentityController {
+	return IdentityController{DB, providers}
+}
+
+func (ic *IdentityController) ListIdentities(ctx *gin.Context) {
+	currentUser := ctx.MustGet("currentUser").(models.User)
+
+	var identities []models.UserIdentity
+	err := ic.DB.WithContext(ctx.Request.Context()).
+		Where("user_id = ?", currentUser.ID).Order("created_at").Find(&identities).Error
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+
+	data := make([]models.UserIdentityResponse, len(identities))
+	for i, identity := range identities {
+		data[i] = models.UserIdentityResponse{
+			ID:            identity.ID,
+			Provider:      identity.Provider,
+			Email:         identity.Email,
+			EmailVerified: identity.EmailVerified,
+			LastUsedAt:    identity.LastUsedAt,
+			CreatedAt:     identity.CreatedAt,
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": data})
+}
+
+// LinkIdentity starts linking an account at the provider to the current
+// user. The client sends the browser to the returned URL; the provider
+// callback completes the link.
+func (ic *IdentityController) LinkIdentity(ctx *gin.Context) {
+	currentUser := ctx.MustGet("currentUser").(models.User)
+	var payload *models.LinkIdentityInput
+
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.Error(apperror.FromBinding(err))
+		return
+	}
+
+	provider, ok := ic.Providers[ctx.Param("provider")]
+	if !ok {
+		ctx.Error(apperror.NotFound("oauth_provider_not_found", "Unknown sign-in provider"))
+		return
+	}
+
+	redirect := payload.Redirect
+	if redirect == "" {
+		redirect = "/"
+	}
+	if !oauth.SafeRedirect(redirect) {
+		ctx.Error(apperror.Validation("invalid_redirect", "Invalid redirect",
+			apperror.FieldError{Field: "redirect", Message: "must be a path on the client"}))
+		return
+	}
+
+	if err := reauthenticate(ctx, ic.DB, &currentUser, payload.Password, payload.Code); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	authURL, err := startOAuth(ctx, provider, oauthState{Redirect: redirect, Link: currentUser.ID.String()})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": models.LinkIdentityResponse{AuthURL: authURL}})
+}
+
+// UnlinkIdentity removes an identity from the current user. The last way to
+// sign in cannot be removed.
+func (ic *IdentityController) UnlinkIdentity(ctx *gin.Context) {
+	currentUser := ctx.MustGet("currentUser").(models.User)
+	var payload *models.ReauthenticateInput
+
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.Error(apperror.FromBinding(err))
+		return
+	}
+
+	identityId, err := uuidParam(ctx, "identityId", "identity")
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	db := ic.DB.WithContext(ctx.Request.Context())
+
+	var identity models.UserIdentity
+	result := db.First(&identity, "id = ? AND user_id = ?", identityId, currentUser.ID)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		ctx.Error(apperror.NotFound("identity_not_found", "No linked account with that ID exists"))
+		return
+	} else if result.Error != nil {
+		ctx.Error(apperror.Internal(result.Error))
+		return
+	}
+
+	if currentUser.Password == "" {
+		var remaining int64
+		if err := db.Model(&models.UserIdentity{}).Where("user_id = ?", currentUser.ID).Count(&remaining).Error; err != nil {
+			ctx.Error(apperror.Internal(err))
+			return
+		}
+		if remaining <= 1 {
+			ctx.Error(apperror.Conflict("last_sign_in_method", "Set a password or link another account before removing your only way to sign in"))
+			return
+		}
+	}
+
+	if err := reauthenticate(ctx, ic.DB, &currentUser, payload.Password, payload.Code); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	if err := db.Delete(&identity).Error; err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, nil)
+}
+
+// RequestReauthentication emails a single-use confirmation link to a user
+// who has neither a password nor two-factor to confirm sensitive changes
+// with. The client sends the code from the link with the change.
+func (ic *IdentityController) RequestReauthentication(ctx *gin.Context) {
+	currentUser := ctx.MustGet("currentUser").(models.User)
+
+	if currentUser.Password != "" || currentUser.TOTPEnabled {
+		ctx.Error(apperror.Conflict("reauthentication_email_unavailable", "Confirm changes with your password or two-factor code"))
+		return
+	}
+
+	config, err := initializers.LoadConfig(".")
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+
+	db := ic.DB.WithContext(ctx.Request.Context())
+	recent, err := recentUserToken(db, currentUser.ID, models.TokenReauthentication, time.Now().Add(-reauthenticationCooldown))
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+	if recent {
+		ctx.Error(apperror.TooManyRequests("reauthentication_recently_requested", "A confirmation email was just sent, please check your inbox"))
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		userToken := models.UserToken{UserID: currentUser.ID, Purpose: models.TokenReauthentication, IPAddress: ctx.ClientIP()}
+		code, err := issueUserToken(tx, userToken, reauthenticationTTL)
+		if err != nil {
+			return err
+		}
+
+		emailData := utils.EmailData{
+			URL:       config.ClientOrigin + "/confirm#code=" + code,
+			FirstName: firstName(currentUser.Name),
+			Time:      time.Now().Add(reauthenticationTTL).UTC().Format(time.RFC1123),
+		}
+		return outbox.Enqueue(tx, &currentUser, &emailData, "reauthCode.html")
+	})
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{"status": "success", "message": "We sent you an email to confirm it's you"})
+}
+
+// reauthenticate confirms a sensitive change made with an existing session.
+// The password is required when the user has one and a second factor when
+// two-factor is enabled. Users with neither, who sign in through providers
+// only, confirm with the code emailed by RequestReauthentication.
+func reauthenticate(ctx *gin.Context, db *gorm.DB, user *models.User, password string, code string) error {
+	if user.Password == "" && !user.TOTPEnabled {
+		userToken, err := consumeUserToken(db.WithContext(ctx.Request.Context()).Where("user_id = ?", user.ID), models.TokenReauthentication, code)
+		if err != nil {
+			return apperror.Internal(err)
+		}
+		if userToken == nil {
+			return apperror.Unauthorized("invalid_reauthentication_code", "The confirmation code is invalid, expired or already used")
+		}
+		return nil
+	}
+
+	if user.Password != "" {
+		if err := utils.VerifyPassword(ctx.Request.Context(), user.Password, password); err != nil {
+			return apperror.Unauthorized("invalid_credentials", "Invalid password")
+		}
+	}
+
+	if user.TOTPEnabled {
+		ok, err := verifySecondFactor(ctx.Request.Context(), db, user, code)
+		if err != nil {
+			return apperror.Internal(err)
+		}
+		if !ok {
+			return apperror.Unauthorized("invalid_mfa_code", "Invalid two-factor code")
+		}
+	}
+	return nil
+}
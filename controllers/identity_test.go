@@ -0,0 +1,204 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Llane00/ramen-backend/apperror"
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/oauth"
+	"github.com/Llane00/ramen-backend/testdb"
+	"github.com/Llane00/ramen-backend/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// fakeProvider is an OAuth provider whose consent page is never visited.
+type fakeProvider struct{}
+
+func (fakeProvider) Name() string { return "fake" }
+
+func (fakeProvider) AuthCodeURL(_ context.Context, state, challenge, nonce string) (string, error) {
+	return "https://provider.example/authorize?state=" + state, nil
+}
+
+func (fakeProvider) Exchange(context.Context, string, string, string) (*oauth.Identity, error) {
+	return nil, nil
+}
+
+func newTestIdentityController(t *testing.T) (IdentityController, *gorm.DB) {
+	t.Helper()
+	testdb.Config(t)
+	db := testdb.Open(t, &models.User{}, &models.UserIdentity{}, &models.UserToken{}, &models.EmailOutbox{})
+	useTestKeys(t, db)
+	return NewIdentityController(db, oauth.Registry{"fake": fakeProvider{}}), db
+}
+
+func createTestIdentity(t *testing.T, db *gorm.DB, user *models.User) models.UserIdentity {
+	t.Helper()
+	identity := models.UserIdentity{UserID: user.ID, Provider: "fake", Subject: uuid.NewString(), CreatedAt: time.Now()}
+	if err := db.Create(&identity).Error; err != nil {
+		t.Fatalf("create identity: %v", err)
+	}
+	return identity
+}
+
+// errorCode returns the code of the error recorded on ctx, if any.
+func errorCode(ctx *gin.Context) string {
+	err := ctx.Errors.Last()
+	if err == nil {
+		return ""
+	}
+	var appErr *apperror.Error
+	if errors.As(err.Err, &appErr) {
+		return appErr.Code
+	}
+	return err.Error()
+}
+
+func TestLinkIdentity(t *testing.T) {
+	ic, db := newTestIdentityController(t)
+	user := createTestUser(t, db)
+	hashed, _ := utils.HashPassword(context.Background(), "password123")
+	db.Model(&user).Update("password", hashed)
+	user.Password = hashed
+
+	link := func(password string) (*gin.Context, string) {
+		ctx, rec := newTestContext(http.MethodPost, "/api/users/me/identities/fake", models.LinkIdentityInput{Password: password})
+		ctx.Params = gin.Params{{Key: "provider", Value: "fake"}}
+		ctx.Set("currentUser", user)
+		ic.LinkIdentity(ctx)
+		return ctx, rec.Body.String()
+	}
+
+	if ctx, _ := link("wrong"); errorCode(ctx) != "invalid_credentials" {
+		t.Fatalf("link with a wrong password: error = %q, want invalid_credentials", errorCode(ctx))
+	}
+
+	ctx, body := link("password123")
+	if errorCode(ctx) != "" {
+		t.Fatalf("link: %s", errorCode(ctx))
+	}
+	var response struct {
+		Data models.LinkIdentityResponse `json:"data"`
+	}
+	json.Unmarshal([]byte(body), &response)
+	if !strings.HasPrefix(response.Data.AuthURL, "https://provider.example/authorize?state=") {
+		t.Fatalf("auth_url = %q, want the provider's consent page", response.Data.AuthURL)
+	}
+
+	// The signed state carries the user the identity is linked to.
+	state, err := parseOAuthState(strings.TrimPrefix(response.Data.AuthURL, "https://provider.example/authorize?state="))
+	if err != nil || state.Link != user.ID.String() {
+		t.Fatalf("state = %+v, %v, want a link to %s", state, err, user.ID)
+	}
+
+	// The callback attaches the identity, and it cannot be attached to a
+	// second user.
+	oc := NewOAuthController(db, ic.Providers)
+	identity := &oauth.Identity{Provider: "fake", Subject: "subject-1", Email: user.Email, EmailVerified: true}
+	cbCtx, _ := newTestContext(http.MethodGet, "/api/auth/oauth/fake/callback", nil)
+	if err := oc.linkIdentity(cbCtx, user.ID.String(), identity); err != nil {
+		t.Fatalf("linkIdentity: %v", err)
+	}
+	var count int64
+	db.Model(&models.UserIdentity{}).Where("user_id = ? AND subject = ?", user.ID, "subject-1").Count(&count)
+	if count != 1 {
+		t.Fatalf("linked identities = %d, want 1", count)
+	}
+
+	other := createTestUser(t, db)
+	err = oc.linkIdentity(cbCtx, other.ID.String(), identity)
+	var appErr *apperror.Error
+	if !errors.As(err, &appErr) || appErr.Code != "identity_in_use" {
+		t.Fatalf("linking to a second user: %v, want identity_in_use", err)
+	}
+}
+
+func TestUnlinkIdentity(t *testing.T) {
+	ic, db := newTestIdentityController(t)
+	// A user who only signs in through providers.
+	user := createTestUser(t, db)
+	db.Model(&user).Update("password", "")
+	user.Password = ""
+	first := createTestIdentity(t, db, &user)
+
+	unlink := func(identity models.UserIdentity, code string) *gin.Context {
+		ctx, _ := newTestContext(http.MethodDelete, "/api/users/me/identities/"+identity.ID.String(), models.ReauthenticateInput{Code: code})
+		ctx.Params = gin.Params{{Key: "identityId", Value: identity.ID.String()}}
+		ctx.Set("currentUser", user)
+		ic.UnlinkIdentity(ctx)
+		return ctx
+	}
+
+	// The only way to sign in cannot be removed.
+	if ctx := unlink(first, ""); errorCode(ctx) != "last_sign_in_method" {
+		t.Fatalf("unlink the last identity: error = %q, want last_sign_in_method", errorCode(ctx))
+	}
+
+	second := createTestIdentity(t, db, &user)
+
+	// Without a password or two-factor the change needs an emailed code.
+	if ctx := unlink(second, ""); errorCode(ctx) != "invalid_reauthentication_code" {
+		t.Fatalf("unlink without a code: error = %q, want invalid_reauthentication_code", errorCode(ctx))
+	}
+
+	ctx, _ := newTestContext(http.MethodPost, "/api/users/me/reauthentication", nil)
+	ctx.Set("currentUser", user)
+	ic.RequestReauthentication(ctx)
+	if errorCode(ctx) != "" {
+		t.Fatalf("RequestReauthentication: %s", errorCode(ctx))
+	}
+	var queued int64
+	db.Model(&models.EmailOutbox{}).Where("template = ? AND to_email = ?", "reauthCode.html", user.Email).Count(&queued)
+	if queued != 1 {
+		t.Fatalf("confirmation emails queued = %d, want 1", queued)
+	}
+
+	// The emailed code is a user token; issue one directly to learn it.
+	code, err := issueUserToken(db, models.UserToken{UserID: user.ID, Purpose: models.TokenReauthentication}, reauthenticationTTL)
+	if err != nil {
+		t.Fatalf("issueUserToken: %v", err)
+	}
+
+	// Another user's code does not work and is not spent.
+	other := createTestUser(t, db)
+	other.Password = ""
+	otherCtx, _ := newTestContext(http.MethodPost, "/", nil)
+	if err := reauthenticate(otherCtx, db, &other, "", code); err == nil {
+		t.Fatal("reauthenticate accepted another user's code")
+	}
+
+	if ctx := unlink(second, code); errorCode(ctx) != "" {
+		t.Fatalf("unlink with a code: %s", errorCode(ctx))
+	}
+	var remaining int64
+	db.Model(&models.UserIdentity{}).Where("user_id = ?", user.ID).Count(&remaining)
+	if remaining != 1 {
+		t.Fatalf("identities after unlinking = %d, want 1", remaining)
+	}
+
+	// The code works once.
+	third := createTestIdentity(t, db, &user)
+	if ctx := unlink(third, code); errorCode(ctx) != "invalid_reauthentication_code" {
+		t.Fatalf("reused code: error = %q, want invalid_reauthentication_code", errorCode(ctx))
+	}
+}
+
+func TestRequestReauthenticationNeedsPasswordlessUser(t *testing.T) {
+	ic, db := newTestIdentityController(t)
+	user := createTestUser(t, db)
+
+	ctx, _ := newTestContext(http.MethodPost, "/api/users/me/reauthentication", nil)
+	ctx.Set("currentUser", user)
+	ic.RequestReauthentication(ctx)
+	if errorCode(ctx) != "reauthentication_email_unavailable" {
+		t.Fatalf("error = %q, want reauthentication_email_unavailable", errorCode(ctx))
+	}
+}
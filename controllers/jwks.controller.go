@@ -0,0 +1,23 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/Llane00/ramen-backend/keyset"
+	"github.com/gin-gonic/gin"
+)
+
+type JWKSController struct {
+	Keys *keyset.KeySet
+}
+
+func NewJWKSController(keys *keyset.KeySet) JWKSController {
+	return JWKSController{keys}
+}
+
+// JWKS publishes the public keys that verify access tokens. Clients may cache
+// the response briefly; new keys are published well before they sign.
+func (jc *JWKSController) JWKS(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, jc.Keys.JWKS())
+}
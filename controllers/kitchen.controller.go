@@ -0,0 +1,221 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Llane00/ramen-backend/apperror"
+	"github.com/Llane00/ramen-backend/initializers"
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// kitchenQueueLimit caps the orders in the kitchen queue.
+const kitchenQueueLimit = 200
+
+// prepTimeWindow is the default period average preparation times cover.
+const prepTimeWindow = 30 * 24 * time.Hour
+
+// kitchenStatuses are the order statuses in the kitchen queue: paid orders
+// being prepared, and prepared orders waiting to be delivered.
+var kitchenStatuses = []models.OrderStatus{models.OrderStatusPaid, models.OrderStatusShipping}
+
+type KitchenController struct {
+	DB *gorm.DB
+}
+
+func NewKitchenController(DB *gorm.DB) KitchenController {
+	return KitchenController{DB}
+}
+
+// GetKitchenQueue lists the shop's paid orders that have not been delivered
+// with their items, longest paid first.
+func (kc *KitchenController) GetKitchenQueue(ctx *gin.Context) {
+	currentUser, err := requireUser(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	shopId, err := uuidParam(ctx, "shopId", "shop")
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	db := kc.DB.WithContext(ctx.Request.Context())
+	shop, err := ownedShop(db, shopId, &currentUser)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	orders := []models.Order{}
+	err = db.Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at, id")
+	}).Where("shop_id = ? AND status IN ?", shop.ID, kitchenStatuses).
+		Order("paid_at, created_at").Limit(kitchenQueueLimit).Find(&orders).Error
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": orders})
+}
+
+// UpdateKitchenItemStatus marks an item of a paid order as preparing or
+// ready. Once every item of the order is ready the order moves on to
+// shipping.
+func (kc *KitchenController) UpdateKitchenItemStatus(ctx *gin.Context) {
+	currentUser, err := requireUser(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	shopId, err := uuidParam(ctx, "shopId", "shop")
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	itemId, err := uuidParam(ctx, "itemId", "item")
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	var input models.UpdateOrderItemStatusInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.Error(apperror.FromBinding(err))
+		return
+	}
+
+	db := kc.DB.WithContext(ctx.Request.Context())
+	shop, err := ownedShop(db, shopId, &currentUser)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	config, err := initializers.LoadConfig(".")
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+
+	var item models.OrderItem
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("order_id").First(&item, itemId).Error; err != nil {
+			return apperror.FromQuery(err, "item_not_found", "Order item not found")
+		}
+
+		// Locking the order serializes the items' updates, so exactly one
+		// of them sees the last item become ready. The item is read again
+		// under the lock so a concurrent update is not overwritten.
+		var order models.Order
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&order, "id = ? AND shop_id = ?", item.OrderID, shop.ID).Error
+		if err != nil {
+			return apperror.FromQuery(err, "item_not_found", "Order item not found")
+		}
+		if err := tx.First(&item, itemId).Error; err != nil {
+			return apperror.FromQuery(err, "item_not_found", "Order item not found")
+		}
+		if order.Status != models.OrderStatusPaid {
+			return apperror.Conflict("order_not_in_preparation", "Only items of paid orders can be prepared")
+		}
+		if item.Status == input.Status {
+			return nil
+		}
+
+		now := time.Now()
+		item.Status = input.Status
+		switch input.Status {
+		case models.OrderItemStatusPreparing:
+			if item.PreparingAt == nil {
+				item.PreparingAt = &now
+			}
+			item.ReadyAt = nil
+		case models.OrderItemStatusReady:
+			item.ReadyAt = &now
+		}
+		if err := tx.Save(&item).Error; err != nil {
+			return err
+		}
+		if err := notifyItemEvent(tx, &order, &item); err != nil {
+			return err
+		}
+
+		var waiting int64
+		err = tx.Model(&models.OrderItem{}).
+			Where("order_id = ? AND status <> ?", order.ID, models.OrderItemStatusReady).Count(&waiting).Error
+		if err != nil || waiting > 0 {
+			return err
+		}
+
+		setOrderStatus(&order, models.OrderStatusShipping)
+		if err := tx.Save(&order).Error; err != nil {
+			return err
+		}
+		return orderStatusChanged(tx, &config, &order)
+	})
+	if err != nil {
+		ctx.Error(apperror.From(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": item})
+}
+
+// GetPrepTimes reports the shop's average preparation time per product over
+// the items made ready since ?since=, an RFC 3339 time, or the last 30 days.
+// Items marked ready without being started are left out.
+func (kc *KitchenController) GetPrepTimes(ctx *gin.Context) {
+	currentUser, err := requireUser(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	shopId, err := uuidParam(ctx, "shopId", "shop")
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	since := time.Now().Add(-prepTimeWindow)
+	if raw := ctx.Query("since"); raw != "" {
+		since, err = time.Parse(time.RFC3339, raw)
+		if err != nil {
+			ctx.Error(apperror.Validation("invalid_since", "Invalid since time",
+				apperror.FieldError{Field: "since", Message: "must be an RFC 3339 time"}))
+			return
+		}
+	}
+
+	db := kc.DB.WithContext(ctx.Request.Context())
+	shop, err := ownedShop(db, shopId, &currentUser)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	times := []models.ProductPrepTime{}
+	err = db.Model(&models.OrderItem{}).
+		Select("order_items.product_id, MAX(order_items.product_name) AS product_name, COUNT(*) AS items, "+
+			"AVG(EXTRACT(EPOCH FROM order_items.ready_at - order_items.preparing_at)) AS average_seconds").
+		Joins("JOIN orders ON orders.id = order_items.order_id").
+		Where("orders.shop_id = ? AND order_items.preparing_at IS NOT NULL AND order_items.ready_at >= ?", shop.ID, since).
+		Group("order_items.product_id").
+		Order("average_seconds DESC").
+		Scan(&times).Error
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"data": times})
+}
@@ -0,0 +1,237 @@
+package controllers
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/testdb"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// createKitchenOrder stores an order of one of each product with status,
+// paid at paidAt if set.
+func createKitchenOrder(t *testing.T, db *gorm.DB, customer models.User, shop models.Shop, status models.OrderStatus, paidAt *time.Time, products ...models.Product) models.Order {
+	t.Helper()
+	order := models.Order{UserID: customer.ID, ShopID: shop.ID, Status: status, PaidAt: paidAt}
+	for _, product := range products {
+		order.Items = append(order.Items, models.OrderItem{
+			ProductID: product.ID, ProductName: product.Name, ProductPrice: product.Price,
+			Quantity: 1, TotalPrice: product.Price, Status: models.OrderItemStatusQueued,
+		})
+		order.TotalPrice += product.Price
+	}
+	if err := db.Create(&order).Error; err != nil {
+		t.Fatalf("create order: %v", err)
+	}
+	return order
+}
+
+func kitchenContext(method, target string, body any, owner models.User, shop models.Shop, params ...gin.Param) (*gin.Context, *httptest.ResponseRecorder) {
+	ctx, rec := newTestContext(method, target, body)
+	ctx.Params = append(gin.Params{{Key: "shopId", Value: shop.ID.String()}}, params...)
+	ctx.Set("currentUser", owner)
+	return ctx, rec
+}
+
+func TestSetOrderStatusSetsPaidAtOnce(t *testing.T) {
+	var order models.Order
+	setOrderStatus(&order, models.OrderStatusPending)
+	if order.PaidAt != nil {
+		t.Fatalf("pending order has PaidAt %v", order.PaidAt)
+	}
+
+	setOrderStatus(&order, models.OrderStatusPaid)
+	if order.PaidAt == nil {
+		t.Fatal("paid order has no PaidAt")
+	}
+	paidAt := *order.PaidAt
+
+	setOrderStatus(&order, models.OrderStatusShipping)
+	setOrderStatus(&order, models.OrderStatusPaid)
+	if !order.PaidAt.Equal(paidAt) {
+		t.Errorf("PaidAt moved from %v to %v when paid again", paidAt, *order.PaidAt)
+	}
+}
+
+func TestGetKitchenQueueOrdersByPaidAt(t *testing.T) {
+	db := testdb.Open(t, orderModels...)
+	kc := NewKitchenController(db)
+
+	owner := createTestUser(t, db)
+	customer := createTestUser(t, db)
+	shop, products := createTestShop(t, db, owner, 1450)
+
+	now := time.Now()
+	paidAt := func(ago time.Duration) *time.Time { at := now.Add(-ago); return &at }
+	recent := createKitchenOrder(t, db, customer, shop, models.OrderStatusPaid, paidAt(time.Minute), products...)
+	oldest := createKitchenOrder(t, db, customer, shop, models.OrderStatusShipping, paidAt(time.Hour), products...)
+	older := createKitchenOrder(t, db, customer, shop, models.OrderStatusPaid, paidAt(10*time.Minute), products...)
+	createKitchenOrder(t, db, customer, shop, models.OrderStatusPending, nil, products...)
+	createKitchenOrder(t, db, customer, shop, models.OrderStatusDelivered, paidAt(2*time.Hour), products...)
+
+	ctx, rec := kitchenContext(http.MethodGet, "/api/shops/"+shop.ID.String()+"/kitchen/queue", nil, owner, shop)
+	kc.GetKitchenQueue(ctx)
+	if rec.Code != http.StatusOK || len(ctx.Errors) > 0 {
+		t.Fatalf("GetKitchenQueue = %d %v, want 200", rec.Code, ctx.Errors)
+	}
+
+	var body struct{ Data []models.Order }
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	want := []uuid.UUID{oldest.ID, older.ID, recent.ID}
+	if len(body.Data) != len(want) {
+		t.Fatalf("queue has %d orders, want %d", len(body.Data), len(want))
+	}
+	for i, order := range body.Data {
+		if order.ID != want[i] || len(order.Items) != 1 {
+			t.Errorf("queue[%d] = %s with %d items, want %s with 1", i, order.ID, len(order.Items), want[i])
+		}
+	}
+
+	// Only the owner sees the queue.
+	ctx, _ = kitchenContext(http.MethodGet, "/api/shops/"+shop.ID.String()+"/kitchen/queue", nil, customer, shop)
+	kc.GetKitchenQueue(ctx)
+	if code := errorCode(ctx); code != "shop_forbidden" {
+		t.Errorf("customer's GetKitchenQueue error = %q, want shop_forbidden", code)
+	}
+}
+
+func TestUpdateKitchenItemStatusShipsWhenAllItemsReady(t *testing.T) {
+	testdb.Config(t)
+	db := testdb.Open(t, orderModels...)
+	kc := NewKitchenController(db)
+
+	owner := createTestUser(t, db)
+	customer := createTestUser(t, db)
+	shop, products := createTestShop(t, db, owner, 1450, 250)
+	paidAt := time.Now().Add(-time.Hour)
+	order := createKitchenOrder(t, db, customer, shop, models.OrderStatusPaid, &paidAt, products...)
+
+	update := func(item models.OrderItem, status models.OrderItemStatus) *gin.Context {
+		t.Helper()
+		ctx, _ := kitchenContext(http.MethodPatch, "/api/shops/"+shop.ID.String()+"/kitchen/items/"+item.ID.String()+"/status",
+			models.UpdateOrderItemStatusInput{Status: status}, owner, shop, gin.Param{Key: "itemId", Value: item.ID.String()})
+		kc.UpdateKitchenItemStatus(ctx)
+		return ctx
+	}
+	reload := func() (models.Order, []models.OrderItem) {
+		var current models.Order
+		db.Preload("Items").First(&current, "id = ?", order.ID)
+		items := map[uuid.UUID]models.OrderItem{}
+		for _, item := range current.Items {
+			items[item.ID] = item
+		}
+		return current, []models.OrderItem{items[order.Items[0].ID], items[order.Items[1].ID]}
+	}
+
+	if ctx := update(order.Items[0], models.OrderItemStatusPreparing); len(ctx.Errors) > 0 {
+		t.Fatalf("preparing: %v", ctx.Errors)
+	}
+	if ctx := update(order.Items[0], models.OrderItemStatusReady); len(ctx.Errors) > 0 {
+		t.Fatalf("ready: %v", ctx.Errors)
+	}
+	current, items := reload()
+	if current.Status != models.OrderStatusPaid {
+		t.Fatalf("order with an item still queued is %s, want paid", current.Status)
+	}
+	if items[0].Status != models.OrderItemStatusReady || items[0].PreparingAt == nil || items[0].ReadyAt == nil {
+		t.Fatalf("first item = %s, preparing at %v, ready at %v, want ready with both times", items[0].Status, items[0].PreparingAt, items[0].ReadyAt)
+	}
+
+	// Marking the last item ready without starting it ships the order.
+	if ctx := update(order.Items[1], models.OrderItemStatusReady); len(ctx.Errors) > 0 {
+		t.Fatalf("ready: %v", ctx.Errors)
+	}
+	current, items = reload()
+	if current.Status != models.OrderStatusShipping {
+		t.Fatalf("order with every item ready is %s, want shipping", current.Status)
+	}
+	if items[1].PreparingAt != nil || items[1].ReadyAt == nil {
+		t.Errorf("second item preparing at %v, ready at %v, want only ready", items[1].PreparingAt, items[1].ReadyAt)
+	}
+	if current.PaidAt == nil || current.PaidAt.Sub(paidAt).Abs() > time.Millisecond {
+		t.Errorf("PaidAt = %v after shipping, want %v", current.PaidAt, paidAt)
+	}
+
+	// The customer is told the order is on its way.
+	var notifications int64
+	db.Model(&models.Notification{}).Where("user_id = ?", customer.ID).Count(&notifications)
+	if notifications != 1 {
+		t.Errorf("customer has %d notifications, want 1", notifications)
+	}
+
+	// Items of orders that left the kitchen cannot change.
+	if code := errorCode(update(order.Items[0], models.OrderItemStatusPreparing)); code != "order_not_in_preparation" {
+		t.Errorf("update of a shipping order's item error = %q, want order_not_in_preparation", code)
+	}
+}
+
+func TestGetPrepTimes(t *testing.T) {
+	db := testdb.Open(t, orderModels...)
+	kc := NewKitchenController(db)
+
+	owner := createTestUser(t, db)
+	customer := createTestUser(t, db)
+	shop, products := createTestShop(t, db, owner, 1450, 250)
+	paidAt := time.Now().Add(-2 * time.Hour)
+
+	now := time.Now()
+	prepare := func(product models.Product, preparing *time.Time, ready time.Time) {
+		t.Helper()
+		order := createKitchenOrder(t, db, customer, shop, models.OrderStatusShipping, &paidAt, product)
+		err := db.Model(&order.Items[0]).Updates(map[string]any{
+			"status": models.OrderItemStatusReady, "preparing_at": preparing, "ready_at": ready,
+		}).Error
+		if err != nil {
+			t.Fatalf("update item: %v", err)
+		}
+	}
+	at := func(ago time.Duration) *time.Time { started := now.Add(-ago); return &started }
+
+	prepare(products[0], at(20*time.Minute), now.Add(-10*time.Minute))
+	prepare(products[0], at(12*time.Minute), now.Add(-6*time.Minute))
+	// Marked ready without being started.
+	prepare(products[0], nil, now.Add(-time.Minute))
+	prepare(products[1], at(3*time.Minute), now.Add(-2*time.Minute))
+	// Ready before since.
+	prepare(products[1], at(50*time.Hour), now.Add(-48*time.Hour))
+
+	since := now.Add(-24 * time.Hour).Format(time.RFC3339)
+	ctx, rec := kitchenContext(http.MethodGet, "/api/shops/"+shop.ID.String()+"/kitchen/prep-times?since="+since, nil, owner, shop)
+	kc.GetPrepTimes(ctx)
+	if rec.Code != http.StatusOK || len(ctx.Errors) > 0 {
+		t.Fatalf("GetPrepTimes = %d %v, want 200", rec.Code, ctx.Errors)
+	}
+
+	var body struct{ Data []models.ProductPrepTime }
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	want := []models.ProductPrepTime{
+		{ProductID: products[0].ID, ProductName: products[0].Name, Items: 2, AverageSeconds: 480},
+		{ProductID: products[1].ID, ProductName: products[1].Name, Items: 1, AverageSeconds: 60},
+	}
+	if len(body.Data) != len(want) {
+		t.Fatalf("prep times = %+v, want %+v", body.Data, want)
+	}
+	for i, got := range body.Data {
+		if got.ProductID != want[i].ProductID || got.ProductName != want[i].ProductName || got.Items != want[i].Items ||
+			math.Abs(got.AverageSeconds-want[i].AverageSeconds) > 1 {
+			t.Errorf("prep times[%d] = %+v, want %+v", i, got, want[i])
+		}
+	}
+
+	ctx, _ = kitchenContext(http.MethodGet, "/api/shops/"+shop.ID.String()+"/kitchen/prep-times?since=yesterday", nil, owner, shop)
+	kc.GetPrepTimes(ctx)
+	if code := errorCode(ctx); code != "invalid_since" {
+		t.Errorf("GetPrepTimes(since=yesterday) error = %q, want invalid_since", code)
+	}
+}
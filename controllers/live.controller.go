@@ -0,0 +1,247 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Llane00/ramen-backend/apperror"
+	"github.com/Llane00/ramen-backend/initializers"
+	"github.com/Llane00/ramen-backend/middleware"
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/pubsub"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"gorm.io/gorm"
+)
+
+const (
+	// liveWriteWait bounds each write to a live connection.
+	liveWriteWait = 10 * time.Second
+	// livePongWait is how long a live connection may go without answering
+	// the pings sent every streamHeartbeat before it is closed.
+	livePongWait = 60 * time.Second
+	// liveReadLimit caps client messages; clients are not expected to send
+	// anything but control frames.
+	liveReadLimit = 512
+)
+
+type LiveController struct {
+	DB  *gorm.DB
+	Hub *pubsub.Hub
+}
+
+func NewLiveController(DB *gorm.DB, hub *pubsub.Hub) LiveController {
+	return LiveController{DB, hub}
+}
+
+// orderTopic is the pubsub topic of one order's live updates.
+func orderTopic(orderID uuid.UUID) string {
+	return "order:" + orderID.String()
+}
+
+// shopOrdersTopic is the pubsub topic of the live updates of every order of
+// a shop.
+func shopOrdersTopic(shopID uuid.UUID) string {
+	return "shop:" + shopID.String() + ":orders"
+}
+
+// newOrderEvent describes order, and payment for payment_status events.
+func newOrderEvent(order *models.Order, payment *models.Payment) models.OrderEvent {
+	event := models.OrderEvent{
+		OrderID:    order.ID,
+		ShopID:     order.ShopID,
+		Status:     order.Status,
+		TotalPrice: order.TotalPrice,
+		UpdatedAt:  order.UpdatedAt,
+	}
+	if payment != nil {
+		event.PaymentID = &payment.ID
+		event.PaymentStatus = payment.Status
+		if payment.UpdatedAt.After(event.UpdatedAt) {
+			event.UpdatedAt = payment.UpdatedAt
+		}
+	}
+	return event
+}
+
+// notifyOrderEvent sends event to the live subscribers of order and of its
+// shop once tx commits. payment is nil except for payment_status events.
+func notifyOrderEvent(tx *gorm.DB, event string, order *models.Order, payment *models.Payment) error {
+	msg, err := pubsub.NewMessage(event, newOrderEvent(order, payment))
+	if err != nil {
+		return err
+	}
+	return pubsub.Notify(tx, msg, orderTopic(order.ID), shopOrdersTopic(order.ShopID))
+}
+
+// notifyItemEvent sends the kitchen status of item, an item of order, to the
+// live subscribers of order and of its shop once tx commits.
+func notifyItemEvent(tx *gorm.DB, order *models.Order, item *models.OrderItem) error {
+	event := newOrderEvent(order, nil)
+	event.ItemID = &item.ID
+	event.ItemStatus = item.Status
+	if item.UpdatedAt.After(event.UpdatedAt) {
+		event.UpdatedAt = item.UpdatedAt
+	}
+
+	msg, err := pubsub.NewMessage(models.OrderEventItem, event)
+	if err != nil {
+		return err
+	}
+	return pubsub.Notify(tx, msg, orderTopic(order.ID), shopOrdersTopic(order.ShopID))
+}
+
+// SubscribeOrder upgrades to a WebSocket that receives the events of one
+// order. Its customer and the owner of its shop may subscribe. The first
+// message is an order_status event with the current state, so clients that
+// reconnect catch up on anything they missed.
+func (lc *LiveController) SubscribeOrder(ctx *gin.Context) {
+	currentUser := ctx.MustGet("currentUser").(models.User)
+	orderId, err := uuidParam(ctx, "orderId", "order")
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	var order models.Order
+	if err := lc.DB.WithContext(ctx.Request.Context()).Preload("Shop").First(&order, orderId).Error; err != nil {
+		ctx.Error(apperror.FromQuery(err, "order_not_found", "Order not found"))
+		return
+	}
+	if order.UserID != currentUser.ID && order.Shop.OwnerID != currentUser.ID && !currentUser.HasRole(models.RoleSuperAdmin) {
+		ctx.Error(apperror.Forbidden("order_forbidden", "You cannot follow this order"))
+		return
+	}
+
+	current, err := pubsub.NewMessage(models.OrderEventStatus, newOrderEvent(&order, nil))
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+	lc.serve(ctx, orderTopic(order.ID), &current)
+}
+
+// SubscribeShopOrders upgrades to a WebSocket that receives the events of
+// every order of a shop. Only the shop's owner may subscribe.
+func (lc *LiveController) SubscribeShopOrders(ctx *gin.Context) {
+	currentUser := ctx.MustGet("currentUser").(models.User)
+	shopId, err := uuidParam(ctx, "shopId", "shop")
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	shop, err := ownedShop(lc.DB.WithContext(ctx.Request.Context()), shopId, &currentUser)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	lc.serve(ctx, shopOrdersTopic(shop.ID), nil)
+}
+
+// serve upgrades the request and writes first, if any, and then every
+// message of topic as JSON until the client goes away or stops answering
+// pings, or the hub closes.
+func (lc *LiveController) serve(ctx *gin.Context, topic string, first *pubsub.Message) {
+	config, err := initializers.LoadConfig(".")
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+
+	upgrader := websocket.Upgrader{
+		Subprotocols: []string{middleware.AccessTokenProtocol},
+		CheckOrigin:  allowedOrigin(config.ClientOrigin),
+		Error: func(w http.ResponseWriter, r *http.Request, status int, reason error) {
+			ctx.Error(apperror.Validation("websocket_upgrade_failed", reason.Error()))
+		},
+	}
+	if !upgrader.CheckOrigin(ctx.Request) {
+		ctx.Error(apperror.Forbidden("origin_not_allowed", "WebSocket connections are not allowed from this origin"))
+		return
+	}
+
+	sub, err := lc.Hub.Subscribe(topic)
+	if errors.Is(err, pubsub.ErrTooManySubscribers) {
+		ctx.Error(apperror.TooManyRequests("too_many_streams", "Too many open live connections"))
+		return
+	} else if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+	defer sub.Close()
+
+	conn, err := upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// Reading handles pongs and the client's close frame.
+	conn.SetReadLimit(liveReadLimit)
+	conn.SetReadDeadline(time.Now().Add(livePongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(livePongWait))
+	})
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	write := func(msg pubsub.Message) error {
+		conn.SetWriteDeadline(time.Now().Add(liveWriteWait))
+		return conn.WriteJSON(msg)
+	}
+	if first != nil {
+		if err := write(*first); err != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-gone:
+			return
+		case <-heartbeat.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(liveWriteWait)); err != nil {
+				return
+			}
+		case msg, ok := <-sub.C:
+			if !ok {
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"), time.Now().Add(liveWriteWait))
+				return
+			}
+			if err := write(msg); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// allowedOrigin accepts handshakes from the client app, from the API's own
+// origin and from non-browser clients, which send no Origin. Browsers send
+// cookies with cross-site handshakes, so other origins are refused.
+func allowedOrigin(clientOrigin string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" || origin == clientOrigin {
+			return true
+		}
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, r.Host)
+	}
+}
@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Llane00/ramen-backend/apperror"
+	"github.com/Llane00/ramen-backend/initializers"
+	"github.com/Llane00/ramen-backend/metrics"
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/outbox"
+	"github.com/Llane00/ramen-backend/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/thanhpk/randstr"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// deviceCookie identifies a browser across sign-ins so new devices can be
+// reported to the account owner.
+const (
+	deviceCookie       = "device_id"
+	deviceCookieMaxAge = 365 * 24 * 60 * 60
+)
+
+// checkIPBlocked rejects sign-ins from an IP that failed too often within the
+// failure window, whichever accounts it targeted.
+func (ac *AuthController) checkIPBlocked(ctx *gin.Context, policy initializers.LoginPolicy) error {
+	var failures int64
+	err := ac.DB.WithContext(ctx.Request.Context()).Model(&models.LoginAttempt{}).
+		Where("ip_address = ? AND success = ? AND created_at > ?", ctx.ClientIP(), false, time.Now().Add(-policy.FailureWindow)).
+		Count(&failures).Error
+	if err != nil {
+		return apperror.Internal(err)
+	}
+	if failures < int64(policy.IPMaxFailures) {
+		return nil
+	}
+
+	metrics.Logins.WithLabelValues("blocked").Inc()
+	ctx.Header("Retry-After", strconv.Itoa(int(policy.FailureWindow.Seconds())))
+	return apperror.TooManyRequests("too_many_failed_logins", "Too many failed sign-in attempts, please try again later")
+}
+
+// checkAccountLocked rejects sign-ins to a locked account.
+func checkAccountLocked(ctx *gin.Context, user *models.User) error {
+	if user.LockedUntil == nil || !time.Now().Before(*user.LockedUntil) {
+		return nil
+	}
+
+	metrics.Logins.WithLabelValues("locked").Inc()
+	ctx.Header("Retry-After", strconv.Itoa(int(time.Until(*user.LockedUntil).Seconds())+1))
+	return apperror.Locked("account_locked", "Your account is temporarily locked after too many failed sign-in attempts")
+}
+
+// delayLogin slows down repeated guesses against one account. The delay
+// starts after the second consecutive failure and doubles with each one.
+func delayLogin(ctx *gin.Context, policy initializers.LoginPolicy, failures int) {
+	if failures < 2 {
+		return
+	}
+	delay := policy.DelayBase << min(failures-2, 16)
+	if delay > policy.MaxDelay {
+		delay = policy.MaxDelay
+	}
+
+	select {
+	case <-time.After(delay):
+	case <-ctx.Request.Context().Done():
+	}
+}
+
+// recordLoginAttempt stores the outcome of a sign-in. Failing to record it
+// must not fail the sign-in itself, so errors are only logged.
+func (ac *AuthController) recordLoginAttempt(ctx *gin.Context, userID *uuid.UUID, email string, deviceID string, success bool) {
+	attempt := models.LoginAttempt{
+		UserID:    userID,
+		Email:     email,
+		IPAddress: ctx.ClientIP(),
+		UserAgent: truncate(ctx.Request.UserAgent(), 512),
+		DeviceID:  deviceID,
+		Success:   success,
+		CreatedAt: time.Now(),
+	}
+	if err := ac.DB.WithContext(ctx.Request.Context()).Create(&attempt).Error; err != nil {
+		slog.ErrorContext(ctx.Request.Context(), "could not record login attempt", "error", err)
+	}
+}
+
+// loginFailed counts a wrong password against the account and locks it once
+// the threshold is reached, notifying the owner by email.
+func (ac *AuthController) loginFailed(ctx *gin.Context, policy initializers.LoginPolicy, user *models.User) error {
+	metrics.Logins.WithLabelValues("failure").Inc()
+	deviceID, _ := ctx.Cookie(deviceCookie)
+	ac.recordLoginAttempt(ctx, &user.ID, user.Email, truncate(deviceID, 64), false)
+
+	db := ac.DB.WithContext(ctx.Request.Context())
+	err := db.Model(user).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "failed_login_count"}}}).
+		UpdateColumn("failed_login_count", gorm.Expr("failed_login_count + 1")).Error
+	if err != nil {
+		return err
+	}
+	if user.FailedLoginCount < policy.MaxFailures {
+		return nil
+	}
+
+	lockedUntil := time.Now().Add(policy.LockoutDuration)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(user).UpdateColumns(map[string]any{"failed_login_count": 0, "locked_until": lockedUntil}).Error
+		if err != nil {
+			return err
+		}
+		return queueSecurityEmail(ctx, tx, user, "accountLocked.html", lockedUntil)
+	})
+	if err != nil {
+		return err
+	}
+	slog.WarnContext(ctx.Request.Context(), "account locked", "user_id", user.ID, "ip", ctx.ClientIP(), "until", lockedUntil)
+	return nil
+}
+
+// loginSucceeded clears the failure counter, remembers the device and warns
+// the owner when a known account signs in from a device it has not used
+// before.
+func (ac *AuthController) loginSucceeded(ctx *gin.Context, user *models.User) error {
+	metrics.Logins.WithLabelValues("success").Inc()
+	db := ac.DB.WithContext(ctx.Request.Context())
+
+	if user.FailedLoginCount > 0 || user.LockedUntil != nil {
+		err := db.Model(user).UpdateColumns(map[string]any{"failed_login_count": 0, "locked_until": nil}).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	deviceID, err := ctx.Cookie(deviceCookie)
+	if err != nil || len(deviceID) != 32 {
+		deviceID = randstr.Hex(32)
+	}
+	ctx.SetCookie(deviceCookie, deviceID, deviceCookieMaxAge, "/", "localhost", false, true)
+
+	var previous, known int64
+	if err := db.Model(&models.LoginAttempt{}).Where("user_id = ? AND success = ?", user.ID, true).Count(&previous).Error; err != nil {
+		return err
+	}
+	if previous > 0 {
+		err := db.Model(&models.LoginAttempt{}).
+			Where("user_id = ? AND success = ? AND device_id = ?", user.ID, true, deviceID).
+			Count(&known).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	ac.recordLoginAttempt(ctx, &user.ID, user.Email, deviceID, true)
+
+	if previous > 0 && known == 0 {
+		err := queueSecurityEmail(ctx, db, user, "newSignIn.html", time.Now())
+		if err != nil {
+			slog.ErrorContext(ctx.Request.Context(), "could not queue new sign-in email", "user_id", user.ID, "error", err)
+		}
+	}
+	return nil
+}
+
+// queueSecurityEmail queues a lockout or new device notice describing the
+// request in ctx.
+func queueSecurityEmail(ctx *gin.Context, db *gorm.DB, user *models.User, template string, at time.Time) error {
+	config, err := initializers.LoadConfig(".")
+	if err != nil {
+		return err
+	}
+
+	emailData := utils.EmailData{
+		URL:       config.ClientOrigin + "/forgotpassword",
+		FirstName: firstName(user.Name),
+		IPAddress: ctx.ClientIP(),
+		Device:    ctx.Request.UserAgent(),
+		Time:      at.UTC().Format(time.RFC1123),
+	}
+
+	return outbox.Enqueue(db, user, &emailData, template)
+}
+
+// firstName returns the first word of name to greet the user by.
+func firstName(name string) string {
+	fields := strings.Fields(name)
+	if len(fields) == 0 {
+		return name
+	}
+	return fields[0]
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
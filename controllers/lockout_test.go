@@ -0,0 +1,313 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Llane00/ramen-backend/initializers"
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/testdb"
+	"github.com/Llane00/ramen-backend/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func TestLoginSucceededAlertsOnNewDevicesOnly(t *testing.T) {
+	testdb.Config(t)
+	db := testdb.Open(t, &models.User{}, &models.LoginAttempt{}, &models.EmailOutbox{})
+	ac := NewAuthController(db)
+	user := createTestUser(t, db)
+
+	// login signs in with deviceID, empty for a browser without the cookie,
+	// and returns the cookie it was given.
+	login := func(deviceID string) string {
+		t.Helper()
+		ctx, rec := newTestContext(http.MethodPost, "/api/auth/login", nil)
+		if deviceID != "" {
+			ctx.Request.AddCookie(&http.Cookie{Name: deviceCookie, Value: deviceID})
+		}
+		if err := ac.loginSucceeded(ctx, &user); err != nil {
+			t.Fatalf("loginSucceeded: %v", err)
+		}
+		for _, cookie := range rec.Result().Cookies() {
+			if cookie.Name == deviceCookie {
+				return cookie.Value
+			}
+		}
+		t.Fatal("loginSucceeded did not set the device cookie")
+		return ""
+	}
+	alerts := func() int64 {
+		t.Helper()
+		var count int64
+		if err := db.Model(&models.EmailOutbox{}).Where("template = ?", "newSignIn.html").Count(&count).Error; err != nil {
+			t.Fatalf("count alerts: %v", err)
+		}
+		return count
+	}
+
+	// The first sign-in has no earlier device to compare against.
+	device := login("")
+	if got := alerts(); got != 0 {
+		t.Fatalf("alerts after the first sign-in = %d, want 0", got)
+	}
+
+	// A known device cookie is kept and raises no alert.
+	if got := login(device); got != device {
+		t.Errorf("device cookie = %q, want %q kept", got, device)
+	}
+	if got := alerts(); got != 0 {
+		t.Fatalf("alerts after signing in from a known device = %d, want 0", got)
+	}
+
+	// A browser without the cookie is a new device.
+	if other := login(""); other == device {
+		t.Errorf("new device was given the existing cookie %q", device)
+	}
+	if got := alerts(); got != 1 {
+		t.Fatalf("alerts after signing in from a new device = %d, want 1", got)
+	}
+}
+
+func TestFirstName(t *testing.T) {
+	tests := map[string]string{
+		"Alice Smith":      "Alice",
+		"  Taro  Yamada ":  "Taro",
+		"Cher":             "Cher",
+		"Mary Jane Watson": "Mary",
+		"":                 "",
+	}
+	for name, want := range tests {
+		if got := firstName(name); got != want {
+			t.Errorf("firstName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+// createTestLoginUser stores a verified user whose password is password.
+func createTestLoginUser(t *testing.T, db *gorm.DB, password string) models.User {
+	t.Helper()
+	user := createTestUser(t, db)
+	hash, err := utils.HashPassword(context.Background(), password)
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+	if err := db.Model(&user).Update("password", hash).Error; err != nil {
+		t.Fatalf("set password: %v", err)
+	}
+	return user
+}
+
+// signIn calls SignInUser from ip.
+func signIn(ac *AuthController, email, password, ip string) (*gin.Context, *httptest.ResponseRecorder) {
+	ctx, rec := newTestContext(http.MethodPost, "/api/auth/login", models.SignInInput{Email: email, Password: password})
+	ctx.Request.RemoteAddr = ip + ":40000"
+	ac.SignInUser(ctx)
+	return ctx, rec
+}
+
+func reloadUser(t *testing.T, db *gorm.DB, id uuid.UUID) models.User {
+	t.Helper()
+	var user models.User
+	if err := db.First(&user, "id = ?", id).Error; err != nil {
+		t.Fatalf("find user: %v", err)
+	}
+	return user
+}
+
+func TestSignInLocksAccountAfterMaxFailures(t *testing.T) {
+	testdb.Config(t)
+	db := testdb.Open(t, &models.User{}, &models.LoginAttempt{}, &models.EmailOutbox{})
+	useTestKeys(t, db)
+	ac := NewAuthController(db)
+	user := createTestLoginUser(t, db, "correct horse")
+
+	// LOGIN_MAX_FAILURES is 5 in tests.
+	for i := 1; i <= 4; i++ {
+		if ctx, _ := signIn(&ac, user.Email, "wrong", "192.0.2.1"); errorCode(ctx) != "invalid_credentials" {
+			t.Fatalf("failure %d error = %q, want invalid_credentials", i, errorCode(ctx))
+		}
+		if got := reloadUser(t, db, user.ID); got.FailedLoginCount != i || got.LockedUntil != nil {
+			t.Fatalf("after failure %d: count %d, locked until %v, want %d and unlocked", i, got.FailedLoginCount, got.LockedUntil, i)
+		}
+	}
+
+	signIn(&ac, user.Email, "wrong", "192.0.2.1")
+	locked := reloadUser(t, db, user.ID)
+	if locked.LockedUntil == nil || time.Until(*locked.LockedUntil) < 14*time.Minute || locked.FailedLoginCount != 0 {
+		t.Fatalf("after 5 failures: count %d, locked until %v, want 0 and locked for 15m", locked.FailedLoginCount, locked.LockedUntil)
+	}
+	var notices int64
+	db.Model(&models.EmailOutbox{}).Where("to_email = ? AND template = ?", user.Email, "accountLocked.html").Count(&notices)
+	if notices != 1 {
+		t.Errorf("queued %d lockout notices, want 1", notices)
+	}
+
+	// Even the right password is refused until the lock expires.
+	ctx, rec := signIn(&ac, user.Email, "correct horse", "192.0.2.1")
+	if code := errorCode(ctx); code != "account_locked" || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("sign-in while locked error = %q, Retry-After %q, want account_locked with a Retry-After", code, rec.Header().Get("Retry-After"))
+	}
+
+	db.Model(&user).Update("locked_until", time.Now().Add(-time.Second))
+	if ctx, rec := signIn(&ac, user.Email, "correct horse", "192.0.2.1"); rec.Code != http.StatusOK || len(ctx.Errors) > 0 {
+		t.Fatalf("sign-in after the lock expired = %d %v, want 200", rec.Code, ctx.Errors)
+	}
+}
+
+func TestCheckIPBlocked(t *testing.T) {
+	db := testdb.Open(t, &models.LoginAttempt{})
+	ac := NewAuthController(db)
+	policy := initializers.LoginPolicy{IPMaxFailures: 3, FailureWindow: 15 * time.Minute}
+
+	attempt := func(ip string, success bool, ago time.Duration) {
+		t.Helper()
+		err := db.Create(&models.LoginAttempt{
+			Email: "taro@ramen.example", IPAddress: ip, Success: success, CreatedAt: time.Now().Add(-ago),
+		}).Error
+		if err != nil {
+			t.Fatalf("create attempt: %v", err)
+		}
+	}
+	check := func(ip string) (string, string) {
+		t.Helper()
+		ctx, rec := newTestContext(http.MethodPost, "/api/auth/login", nil)
+		ctx.Request.RemoteAddr = ip + ":40000"
+		err := ac.checkIPBlocked(ctx, policy)
+		if err == nil {
+			return "", ""
+		}
+		ctx.Error(err)
+		return errorCode(ctx), rec.Header().Get("Retry-After")
+	}
+
+	// Successes and failures outside the window do not count.
+	attempt("192.0.2.1", false, time.Minute)
+	attempt("192.0.2.1", false, 2*time.Minute)
+	attempt("192.0.2.1", true, time.Minute)
+	attempt("192.0.2.1", false, time.Hour)
+	if code, _ := check("192.0.2.1"); code != "" {
+		t.Fatalf("IP with 2 recent failures error = %q, want none", code)
+	}
+
+	attempt("192.0.2.1", false, 3*time.Minute)
+	if code, retry := check("192.0.2.1"); code != "too_many_failed_logins" || retry != "900" {
+		t.Fatalf("IP with 3 recent failures = %q, Retry-After %q, want too_many_failed_logins and 900", code, retry)
+	}
+	if code, _ := check("192.0.2.2"); code != "" {
+		t.Errorf("another IP error = %q, want none", code)
+	}
+}
+
+func TestDelayLogin(t *testing.T) {
+	policy := initializers.LoginPolicy{DelayBase: 20 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+	elapsed := func(failures int) time.Duration {
+		ctx, _ := newTestContext(http.MethodPost, "/api/auth/login", nil)
+		start := time.Now()
+		delayLogin(ctx, policy, failures)
+		return time.Since(start)
+	}
+
+	for _, failures := range []int{0, 1} {
+		if got := elapsed(failures); got >= 10*time.Millisecond {
+			t.Errorf("delay after %d failures = %v, want none", failures, got)
+		}
+	}
+	if got := elapsed(2); got < 20*time.Millisecond {
+		t.Errorf("delay after 2 failures = %v, want at least 20ms", got)
+	}
+	if got := elapsed(3); got < 40*time.Millisecond {
+		t.Errorf("delay after 3 failures = %v, want at least 40ms", got)
+	}
+	if got := elapsed(40); got < 50*time.Millisecond || got > time.Second {
+		t.Errorf("delay after 40 failures = %v, want the 50ms cap", got)
+	}
+
+	// A client that gives up is not waited for.
+	ctx, _ := newTestContext(http.MethodPost, "/api/auth/login", nil)
+	cancelled, cancel := context.WithCancel(ctx.Request.Context())
+	cancel()
+	ctx.Request = ctx.Request.WithContext(cancelled)
+	start := time.Now()
+	delayLogin(ctx, initializers.LoginPolicy{DelayBase: time.Minute, MaxDelay: time.Minute}, 5)
+	if got := time.Since(start); got > time.Second {
+		t.Errorf("delay with a cancelled request = %v, want none", got)
+	}
+}
+
+func TestLoginSucceededResetsFailures(t *testing.T) {
+	testdb.Config(t)
+	db := testdb.Open(t, &models.User{}, &models.LoginAttempt{}, &models.EmailOutbox{})
+	ac := NewAuthController(db)
+	user := createTestUser(t, db)
+
+	expired := time.Now().Add(-time.Minute)
+	db.Model(&user).UpdateColumns(map[string]any{"failed_login_count": 3, "locked_until": expired})
+	user = reloadUser(t, db, user.ID)
+
+	ctx, _ := newTestContext(http.MethodPost, "/api/auth/login", nil)
+	if err := ac.loginSucceeded(ctx, &user); err != nil {
+		t.Fatalf("loginSucceeded: %v", err)
+	}
+	if got := reloadUser(t, db, user.ID); got.FailedLoginCount != 0 || got.LockedUntil != nil {
+		t.Fatalf("after signing in: count %d, locked until %v, want 0 and unlocked", got.FailedLoginCount, got.LockedUntil)
+	}
+}
+
+func TestResetPasswordClearsLockout(t *testing.T) {
+	db := testdb.Open(t, &models.User{}, &models.UserToken{})
+	ac := NewAuthController(db)
+	user := createTestLoginUser(t, db, "old password")
+	db.Model(&user).UpdateColumns(map[string]any{"failed_login_count": 4, "locked_until": time.Now().Add(time.Hour)})
+
+	token, err := issueUserToken(db, models.UserToken{UserID: user.ID, Purpose: models.TokenPasswordReset}, time.Hour)
+	if err != nil {
+		t.Fatalf("issueUserToken: %v", err)
+	}
+	ctx, rec := newTestContext(http.MethodPatch, "/api/auth/resetpassword/"+token,
+		models.ResetPasswordInput{Password: "new password", PasswordConfirm: "new password"})
+	ctx.Params = gin.Params{{Key: "resetToken", Value: token}}
+	ac.ResetPassword(ctx)
+	if rec.Code != http.StatusOK || len(ctx.Errors) > 0 {
+		t.Fatalf("ResetPassword = %d %v, want 200", rec.Code, ctx.Errors)
+	}
+
+	got := reloadUser(t, db, user.ID)
+	if got.FailedLoginCount != 0 || got.LockedUntil != nil {
+		t.Fatalf("after a password reset: count %d, locked until %v, want 0 and unlocked", got.FailedLoginCount, got.LockedUntil)
+	}
+	if err := utils.VerifyPassword(context.Background(), got.Password, "new password"); err != nil {
+		t.Errorf("new password does not verify: %v", err)
+	}
+}
+
+func TestUnlockUser(t *testing.T) {
+	db := testdb.Open(t, &models.User{})
+	adc := NewAdminController(db)
+	admin := createTestUser(t, db, models.RoleSuperAdmin)
+	user := createTestUser(t, db)
+	db.Model(&user).UpdateColumns(map[string]any{"failed_login_count": 2, "locked_until": time.Now().Add(time.Hour)})
+
+	unlock := func(id string) (*gin.Context, *httptest.ResponseRecorder) {
+		ctx, rec := newTestContext(http.MethodPost, "/api/admin/users/"+id+"/unlock", nil)
+		ctx.Params = gin.Params{{Key: "userId", Value: id}}
+		ctx.Set("currentUser", admin)
+		adc.UnlockUser(ctx)
+		return ctx, rec
+	}
+
+	if ctx, rec := unlock(user.ID.String()); rec.Code != http.StatusOK || len(ctx.Errors) > 0 {
+		t.Fatalf("UnlockUser = %d %v, want 200", rec.Code, ctx.Errors)
+	}
+	if got := reloadUser(t, db, user.ID); got.FailedLoginCount != 0 || got.LockedUntil != nil {
+		t.Fatalf("after unlocking: count %d, locked until %v, want 0 and unlocked", got.FailedLoginCount, got.LockedUntil)
+	}
+
+	if ctx, _ := unlock(uuid.NewString()); errorCode(ctx) != "user_not_found" {
+		t.Errorf("UnlockUser of a missing user error = %q, want user_not_found", errorCode(ctx))
+	}
+}
@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Llane00/ramen-backend/apperror"
+	"github.com/Llane00/ramen-backend/initializers"
+	"github.com/Llane00/ramen-backend/keyset"
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/outbox"
+	"github.com/Llane00/ramen-backend/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RequestMagicLink emails a passwordless sign-in link. The response is the
+// same whether or not the address has an account; the email is only queued
+// here and sent by the outbox worker, so the timing tells little either.
+func (ac *AuthController) RequestMagicLink(ctx *gin.Context) {
+	var payload *models.MagicLinkInput
+
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.Error(apperror.FromBinding(err))
+		return
+	}
+
+	config, err := initializers.LoadConfig(".")
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+
+	ac.queueMagicLink(ctx.Request.Context(), &config, strings.ToLower(payload.Email), ctx.ClientIP())
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "message": "You will receive a sign-in link if a user with that email exists"})
+}
+
+// queueMagicLink issues a link for the user with email, replacing any unused
+// one. Unverified accounts get no link: whoever registered the address may
+// not own it. Failures are only logged so the response stays the same.
+func (ac *AuthController) queueMagicLink(ctx context.Context, config *initializers.Config, email string, ip string) {
+	db := ac.DB.WithContext(ctx)
+
+	var user models.User
+	if err := db.First(&user, "email = ?", email).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			slog.ErrorContext(ctx, "could not look up magic link user", "error", err)
+		}
+		return
+	}
+	if !user.Verified {
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		token, err := issueUserToken(tx, models.UserToken{UserID: user.ID, Purpose: models.TokenMagicLink, IPAddress: ip}, config.MagicLinkTTL())
+		if err != nil {
+			return err
+		}
+
+		// The token travels in the fragment so it never reaches server logs.
+		// The client posts it to VerifyMagicLink; a plain GET link would be
+		// spent by mail scanners that prefetch links.
+		emailData := utils.EmailData{
+			URL:       config.ClientOrigin + "/login/magic#token=" + token,
+			FirstName: firstName(user.Name),
+			Time:      time.Now().Add(config.MagicLinkTTL()).UTC().Format(time.RFC1123),
+		}
+		return outbox.Enqueue(tx, &user, &emailData, "magicLink.html")
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "could not queue magic link", "user_id", user.ID, "error", err)
+	}
+}
+
+// VerifyMagicLink spends a sign-in link and issues the session. Accounts with
+// two-factor enabled get an MFA challenge as after a password.
+func (ac *AuthController) VerifyMagicLink(ctx *gin.Context) {
+	var payload *models.MagicLinkVerifyInput
+
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.Error(apperror.FromBinding(err))
+		return
+	}
+
+	config, _ := initializers.LoadConfig(".")
+	policy := config.LoginPolicy()
+
+	if err := ac.checkIPBlocked(ctx, policy); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	db := ac.DB.WithContext(ctx.Request.Context())
+
+	link, err := consumeUserToken(db, models.TokenMagicLink, payload.Token)
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+	if link == nil {
+		ac.recordLoginAttempt(ctx, nil, "", "", false)
+		ctx.Error(apperror.Unauthorized("invalid_magic_link", "The sign-in link is invalid, expired or already used"))
+		return
+	}
+
+	var user models.User
+	if err := db.First(&user, "id = ?", link.UserID).Error; err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+
+	if err := checkAccountLocked(ctx, &user); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	if user.TOTPEnabled {
+		mfa_token, err := utils.CreatePurposeToken(config.MFATokenTTL(), user.ID, utils.PurposeMFA, keyset.Access)
+		if err != nil {
+			ctx.Error(apperror.Internal(err))
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"status": "mfa_required", "mfa_token": mfa_token})
+		return
+	}
+
+	if err := ac.loginSucceeded(ctx, &user); err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+
+	access_token, err := issueSession(ctx, &config, &user)
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "access_token": access_token})
+}
@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/testdb"
+)
+
+func TestRequestMagicLinkQueuesEmail(t *testing.T) {
+	testdb.Config(t)
+	db := testdb.Open(t, &models.User{}, &models.UserToken{}, &models.EmailOutbox{})
+	ac := NewAuthController(db)
+
+	verified := createTestUser(t, db)
+	unverified := createTestUser(t, db)
+	db.Model(&unverified).Update("verified", false)
+
+	for _, email := range []string{verified.Email, unverified.Email, "nobody@ramen.example"} {
+		ctx, rec := newTestContext(http.MethodPost, "/api/auth/magic-link", models.MagicLinkInput{Email: email})
+		ac.RequestMagicLink(ctx)
+		if rec.Code != http.StatusOK || len(ctx.Errors) > 0 {
+			t.Fatalf("RequestMagicLink(%s) = %d %v, want 200", email, rec.Code, ctx.Errors)
+		}
+	}
+
+	// The email is queued before the response, and only for the verified user.
+	var queued []models.EmailOutbox
+	if err := db.Where("template = ?", "magicLink.html").Find(&queued).Error; err != nil {
+		t.Fatalf("find emails: %v", err)
+	}
+	if len(queued) != 1 || queued[0].ToEmail != verified.Email {
+		t.Fatalf("queued magic links = %+v, want one to %s", queued, verified.Email)
+	}
+}
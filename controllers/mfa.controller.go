@@ -0,0 +1,274 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Llane00/ramen-backend/apperror"
+	"github.com/Llane00/ramen-backend/initializers"
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/secret"
+	"github.com/Llane00/ramen-backend/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/thanhpk/randstr"
+	"gorm.io/gorm"
+)
+
+const recoveryCodeCount = 10
+
+type MFAController struct {
+	DB *gorm.DB
+}
+
+func NewMFAController(DB *gorm.DB) MFAController {
+	return MFAController{DB}
+}
+
+// Enroll generates a new TOTP secret for the current user. Two-factor sign-in
+// stays off until the user proves their authenticator works via Confirm.
+func (mc *MFAController) Enroll(ctx *gin.Context) {
+	currentUser := ctx.MustGet("currentUser").(models.User)
+	if currentUser.TOTPEnabled {
+		ctx.Error(apperror.Conflict("mfa_already_enabled", "Two-factor authentication is already enabled"))
+		return
+	}
+
+	config, err := initializers.LoadConfig(".")
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+
+	key, err := config.MFASecretKey()
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+
+	totpKey, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+	sealed, err := secret.Seal(key, totpKey)
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+
+	if err := mc.DB.WithContext(ctx.Request.Context()).Model(&currentUser).Update("totp_secret", sealed).Error; err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": models.MFAEnrollmentResponse{
+		Secret:     totpKey,
+		OTPAuthURI: utils.TOTPURI(config.MFAIssuerName(), currentUser.Email, totpKey),
+	}})
+}
+
+// Confirm enables two-factor sign-in once the user submits a valid code for
+// the pending secret, and returns the recovery codes. They are shown once.
+func (mc *MFAController) Confirm(ctx *gin.Context) {
+	currentUser := ctx.MustGet("currentUser").(models.User)
+	var payload *models.MFACodeInput
+
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.Error(apperror.FromBinding(err))
+		return
+	}
+
+	if currentUser.TOTPEnabled {
+		ctx.Error(apperror.Conflict("mfa_already_enabled", "Two-factor authentication is already enabled"))
+		return
+	}
+	if currentUser.TOTPSecret == "" {
+		ctx.Error(apperror.Validation("mfa_not_enrolled", "Start two-factor enrollment first"))
+		return
+	}
+
+	secret, err := totpSecret(&currentUser)
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+	counter, ok := utils.ValidateTOTP(secret, payload.Code, time.Now())
+	if !ok {
+		ctx.Error(apperror.Validation("invalid_mfa_code", "Invalid two-factor code"))
+		return
+	}
+
+	var codes []string
+	err = mc.DB.WithContext(ctx.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&currentUser).Updates(map[string]any{"totp_enabled": true, "totp_last_counter": counter}).Error
+		if err != nil {
+			return err
+		}
+		codes, err = replaceRecoveryCodes(tx, currentUser.ID)
+		return err
+	})
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": models.RecoveryCodesResponse{RecoveryCodes: codes}})
+}
+
+// Disable turns two-factor sign-in off. It asks for the password, if the user
+// has one, and a second factor so a stolen session alone cannot weaken the
+// account.
+func (mc *MFAController) Disable(ctx *gin.Context) {
+	currentUser := ctx.MustGet("currentUser").(models.User)
+	var payload *models.DisableMFAInput
+
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.Error(apperror.FromBinding(err))
+		return
+	}
+
+	if !currentUser.TOTPEnabled {
+		ctx.Error(apperror.Conflict("mfa_not_enabled", "Two-factor authentication is not enabled"))
+		return
+	}
+
+	config, err := initializers.LoadConfig(".")
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+	if currentUser.RequiresMFA(config.MFARequiredRoles) {
+		ctx.Error(apperror.Forbidden("mfa_required", "Two-factor authentication is required for your role"))
+		return
+	}
+
+	if err := reauthenticate(ctx, mc.DB, &currentUser, payload.Password, payload.Code); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	err = mc.DB.WithContext(ctx.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&currentUser).Updates(map[string]any{"totp_enabled": false, "totp_secret": "", "totp_last_counter": 0}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", currentUser.ID).Delete(&models.RecoveryCode{}).Error
+	})
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after checking a
+// second factor.
+func (mc *MFAController) RegenerateRecoveryCodes(ctx *gin.Context) {
+	currentUser := ctx.MustGet("currentUser").(models.User)
+	var payload *models.MFACodeInput
+
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.Error(apperror.FromBinding(err))
+		return
+	}
+
+	if !currentUser.TOTPEnabled {
+		ctx.Error(apperror.Conflict("mfa_not_enabled", "Two-factor authentication is not enabled"))
+		return
+	}
+
+	ok, err := verifySecondFactor(ctx.Request.Context(), mc.DB, &currentUser, payload.Code)
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+	if !ok {
+		ctx.Error(apperror.Unauthorized("invalid_mfa_code", "Invalid two-factor code"))
+		return
+	}
+
+	var codes []string
+	err = mc.DB.WithContext(ctx.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		codes, err = replaceRecoveryCodes(tx, currentUser.ID)
+		return err
+	})
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": models.RecoveryCodesResponse{RecoveryCodes: codes}})
+}
+
+// verifySecondFactor accepts either a TOTP code or an unused recovery code.
+// A TOTP code is rejected if its time step was already used, and a recovery
+// code is burned by the same statement that checks it, so neither can be
+// replayed.
+func verifySecondFactor(ctx context.Context, db *gorm.DB, user *models.User, code string) (bool, error) {
+	db = db.WithContext(ctx)
+	code = strings.TrimSpace(code)
+
+	if user.TOTPSecret != "" {
+		secret, err := totpSecret(user)
+		if err != nil {
+			return false, err
+		}
+		if counter, ok := utils.ValidateTOTP(secret, code, time.Now()); ok {
+			result := db.Model(&models.User{}).
+				Where("id = ? AND totp_last_counter < ?", user.ID, counter).
+				Update("totp_last_counter", counter)
+			return result.RowsAffected == 1, result.Error
+		}
+	}
+
+	result := db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, hashRecoveryCode(code)).
+		Update("used_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
+
+// totpSecret decrypts the TOTP secret stored for user.
+func totpSecret(user *models.User) (string, error) {
+	config, err := initializers.LoadConfig(".")
+	if err != nil {
+		return "", err
+	}
+	key, err := config.MFASecretKey()
+	if err != nil {
+		return "", err
+	}
+	return secret.Open(key, user.TOTPSecret)
+}
+
+// replaceRecoveryCodes deletes the user's recovery codes and stores a fresh
+// set, returning the plaintext codes.
+func replaceRecoveryCodes(tx *gorm.DB, userID uuid.UUID) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	rows := make([]models.RecoveryCode, recoveryCodeCount)
+	now := time.Now()
+	for i := range codes {
+		raw := randstr.Hex(10)
+		codes[i] = raw[:5] + "-" + raw[5:]
+		rows[i] = models.RecoveryCode{UserID: userID, CodeHash: hashRecoveryCode(codes[i]), CreatedAt: now}
+	}
+
+	if err := tx.Create(&rows).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(code)))
+	return hex.EncodeToString(sum[:])
+}
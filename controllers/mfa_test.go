@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Llane00/ramen-backend/initializers"
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/secret"
+	"github.com/Llane00/ramen-backend/testdb"
+	"github.com/Llane00/ramen-backend/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// enableTestMFA enrolls user with a fresh TOTP secret and recovery codes and
+// returns the plaintext secret and codes.
+func enableTestMFA(t *testing.T, db *gorm.DB, user *models.User) (string, []string) {
+	t.Helper()
+	config, err := initializers.LoadConfig(".")
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	key, err := config.MFASecretKey()
+	if err != nil {
+		t.Fatalf("MFASecretKey: %v", err)
+	}
+	totpKey, _ := utils.GenerateTOTPSecret()
+	sealed, err := secret.Seal(key, totpKey)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	user.TOTPSecret = sealed
+	user.TOTPEnabled = true
+	if err := db.Model(user).Updates(map[string]any{"totp_secret": sealed, "totp_enabled": true}).Error; err != nil {
+		t.Fatalf("enable mfa: %v", err)
+	}
+	codes, err := replaceRecoveryCodes(db, user.ID)
+	if err != nil {
+		t.Fatalf("replaceRecoveryCodes: %v", err)
+	}
+	return totpKey, codes
+}
+
+func TestVerifySecondFactorRejectsReplayedCodes(t *testing.T) {
+	testdb.Config(t)
+	db := testdb.Open(t, &models.User{}, &models.RecoveryCode{})
+	user := createTestUser(t, db)
+	secret, _ := enableTestMFA(t, db, &user)
+
+	code, err := utils.TOTPCode(secret, time.Now())
+	if err != nil {
+		t.Fatalf("TOTPCode: %v", err)
+	}
+	if ok, err := verifySecondFactor(context.Background(), db, &user, code); err != nil || !ok {
+		t.Fatalf("first use = %v, %v, want accepted", ok, err)
+	}
+	if ok, err := verifySecondFactor(context.Background(), db, &user, code); err != nil || ok {
+		t.Fatalf("replay = %v, %v, want rejected", ok, err)
+	}
+
+	// A code from an earlier step inside the skew window is a replay too.
+	earlier, _ := utils.TOTPCode(secret, time.Now().Add(-30*time.Second))
+	if ok, _ := verifySecondFactor(context.Background(), db, &user, earlier); ok {
+		t.Error("code of an earlier step accepted after a later one was used")
+	}
+}
+
+func TestVerifySecondFactorRecoveryCodesAreSingleUse(t *testing.T) {
+	testdb.Config(t)
+	db := testdb.Open(t, &models.User{}, &models.RecoveryCode{})
+	user := createTestUser(t, db)
+	_, codes := enableTestMFA(t, db, &user)
+
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("got %d recovery codes, want %d", len(codes), recoveryCodeCount)
+	}
+	if ok, err := verifySecondFactor(context.Background(), db, &user, " "+codes[0]+" "); err != nil || !ok {
+		t.Fatalf("first use = %v, %v, want accepted", ok, err)
+	}
+	if ok, err := verifySecondFactor(context.Background(), db, &user, codes[0]); err != nil || ok {
+		t.Fatalf("second use = %v, %v, want rejected", ok, err)
+	}
+	if ok, _ := verifySecondFactor(context.Background(), db, &user, codes[1]); !ok {
+		t.Error("an unused recovery code was rejected")
+	}
+
+	// Another user's codes do not work.
+	other := createTestUser(t, db)
+	if ok, _ := verifySecondFactor(context.Background(), db, &other, codes[2]); ok {
+		t.Error("recovery code accepted for another user")
+	}
+}
+
+func TestDisableMFA(t *testing.T) {
+	testdb.Config(t)
+	db := testdb.Open(t, &models.User{}, &models.RecoveryCode{})
+	mc := NewMFAController(db)
+
+	disable := func(user models.User, input models.DisableMFAInput) (*gin.Context, int) {
+		ctx, rec := newTestContext(http.MethodPost, "/api/auth/2fa/disable", input)
+		ctx.Set("currentUser", user)
+		mc.Disable(ctx)
+		return ctx, rec.Code
+	}
+
+	// A user with a password needs it as well as a code.
+	user := createTestLoginUser(t, db, "correct horse")
+	_, codes := enableTestMFA(t, db, &user)
+	if ctx, _ := disable(user, models.DisableMFAInput{Code: codes[0]}); errorCode(ctx) != "invalid_credentials" {
+		t.Fatalf("Disable without the password error = %q, want invalid_credentials", errorCode(ctx))
+	}
+	if ctx, _ := disable(user, models.DisableMFAInput{Password: "correct horse", Code: "000000"}); errorCode(ctx) != "invalid_mfa_code" {
+		t.Fatalf("Disable with a wrong code error = %q, want invalid_mfa_code", errorCode(ctx))
+	}
+	if ctx, code := disable(user, models.DisableMFAInput{Password: "correct horse", Code: codes[1]}); code != http.StatusOK || len(ctx.Errors) > 0 {
+		t.Fatalf("Disable = %d %v, want 200", code, ctx.Errors)
+	}
+
+	// A user who signs in through a provider disables it with a code alone.
+	passwordless := createTestUser(t, db)
+	db.Model(&passwordless).Update("password", "")
+	passwordless.Password = ""
+	secret, _ := enableTestMFA(t, db, &passwordless)
+	code, _ := utils.TOTPCode(secret, time.Now())
+	if ctx, status := disable(passwordless, models.DisableMFAInput{Code: code}); status != http.StatusOK || len(ctx.Errors) > 0 {
+		t.Fatalf("passwordless Disable = %d %v, want 200", status, ctx.Errors)
+	}
+
+	for _, id := range []uuid.UUID{user.ID, passwordless.ID} {
+		var stored models.User
+		db.First(&stored, "id = ?", id)
+		var remaining int64
+		db.Model(&models.RecoveryCode{}).Where("user_id = ?", id).Count(&remaining)
+		if stored.TOTPEnabled || stored.TOTPSecret != "" || remaining != 0 {
+			t.Errorf("user %s after Disable: enabled %v, secret set %v, %d recovery codes", id, stored.TOTPEnabled, stored.TOTPSecret != "", remaining)
+		}
+	}
+}
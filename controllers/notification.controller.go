@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Llane00/ramen-backend/apperror"
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/pagination"
+	"github.com/Llane00/ramen-backend/pubsub"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// streamHeartbeat is how often an idle stream sends a comment so proxies do
+// not close it.
+const streamHeartbeat = 25 * time.Second
+
+type NotificationController struct {
+	DB  *gorm.DB
+	Hub *pubsub.Hub
+}
+
+func NewNotificationController(DB *gorm.DB, hub *pubsub.Hub) NotificationController {
+	return NotificationController{DB, hub}
+}
+
+// NotificationListSpec declares the filters and sort fields accepted by
+// ListNotifications. ?unread=true is handled separately.
+var NotificationListSpec = pagination.Spec{
+	Filters: []pagination.Filter{
+		{Param: "type", Column: "type", Op: "=", Values: []string{
+			models.NotificationOrderCreated, models.NotificationOrderStatus, models.NotificationPaymentReceived,
+			models.NotificationPaymentFailed, models.NotificationPaymentRefunded,
+		}},
+		{Param: "created_after", Column: "created_at", Op: ">=", Kind: pagination.Time},
+	},
+	Sorts: []pagination.SortField{pagination.CreatedAt},
+}
+
+// NotificationPage is a page of notifications with the user's unread count.
+type NotificationPage struct {
+	pagination.Page[models.Notification]
+	Unread int64 `json:"unread"`
+}
+
+// UnreadCount is the payload of the unread stream event and the response of
+// MarkNotificationsRead.
+type UnreadCount struct {
+	Unread int64 `json:"unread"`
+}
+
+func (nc *NotificationController) unreadCount(db *gorm.DB, user *models.User) (int64, error) {
+	var unread int64
+	err := db.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", user.ID).Count(&unread).Error
+	return unread, err
+}
+
+// ListNotifications lists the current user's notifications, newest first.
+func (nc *NotificationController) ListNotifications(ctx *gin.Context) {
+	currentUser := ctx.MustGet("currentUser").(models.User)
+
+	query, err := pagination.Parse(ctx, NotificationListSpec)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	db := nc.DB.WithContext(ctx.Request.Context())
+	scope := db.Where("user_id = ?", currentUser.ID)
+	if unreadOnly, _ := strconv.ParseBool(ctx.Query("unread")); unreadOnly {
+		scope = scope.Where("read_at IS NULL")
+	}
+
+	page, err := pagination.Find[models.Notification](scope, query)
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+
+	unread, err := nc.unreadCount(db, &currentUser)
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, NotificationPage{Page: *page, Unread: unread})
+}
+
+// MarkNotificationsRead marks the listed notifications, or all of them when
+// none are listed, as read. Other open streams of the user get the new
+// unread count.
+func (nc *NotificationController) MarkNotificationsRead(ctx *gin.Context) {
+	currentUser := ctx.MustGet("currentUser").(models.User)
+	var payload models.MarkNotificationsReadInput
+
+	// An empty body marks everything read.
+	if err := ctx.ShouldBindJSON(&payload); err != nil && !errors.Is(err, io.EOF) {
+		ctx.Error(apperror.FromBinding(err))
+		return
+	}
+
+	db := nc.DB.WithContext(ctx.Request.Context())
+	update := db.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", currentUser.ID)
+	if len(payload.IDs) > 0 {
+		update = update.Where("id IN ?", payload.IDs)
+	}
+	if err := update.UpdateColumn("read_at", time.Now()).Error; err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+
+	unread, err := nc.unreadCount(db, &currentUser)
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+
+	if msg, err := pubsub.NewMessage("unread", UnreadCount{unread}); err == nil {
+		if err := pubsub.Notify(db, msg, userTopic(currentUser.ID)); err != nil {
+			slog.Warn("could not send unread count", "user_id", currentUser.ID, "error", err)
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": UnreadCount{unread}})
+}
+
+// StreamNotifications pushes the current user's new notifications as
+// Server-Sent Events. The stream opens with an "unread" event carrying the
+// unread count and sends another after every "notification" event.
+func (nc *NotificationController) StreamNotifications(ctx *gin.Context) {
+	currentUser := ctx.MustGet("currentUser").(models.User)
+	db := nc.DB.WithContext(ctx.Request.Context())
+
+	sub, err := nc.Hub.Subscribe(userTopic(currentUser.ID))
+	if errors.Is(err, pubsub.ErrTooManySubscribers) {
+		ctx.Error(apperror.TooManyRequests("too_many_streams", "Too many open notification streams"))
+		return
+	} else if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+	defer sub.Close()
+
+	unread, err := nc.unreadCount(db, &currentUser)
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.SSEvent("unread", UnreadCount{unread})
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Request.Context().Done():
+			return false
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ": heartbeat\n\n")
+			return err == nil
+		case msg, ok := <-sub.C:
+			if !ok {
+				return false
+			}
+			ctx.SSEvent(msg.Event, msg.Data)
+			if msg.Event != notificationEvent {
+				return true
+			}
+
+			unread, err := nc.unreadCount(db, &currentUser)
+			if err != nil {
+				return false
+			}
+			ctx.SSEvent("unread", UnreadCount{unread})
+			return true
+		}
+	})
+}
@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/pubsub"
+	"github.com/Llane00/ramen-backend/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// notificationEvent is the stream event carrying a new notification.
+const notificationEvent = "notification"
+
+// userTopic is the pubsub topic of a user's notification stream.
+func userTopic(userID uuid.UUID) string {
+	return "user:" + userID.String()
+}
+
+// createNotifications stores notifications in tx and sends them to the
+// recipients' streams once tx commits, so streams never show a notification
+// that was rolled back.
+func createNotifications(tx *gorm.DB, notifications []models.Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+	if err := tx.Create(&notifications).Error; err != nil {
+		return err
+	}
+	for _, notification := range notifications {
+		msg, err := pubsub.NewMessage(notificationEvent, notification)
+		if err != nil {
+			return err
+		}
+		if err := pubsub.Notify(tx, msg, userTopic(notification.UserID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// orderNotification addresses a notification about order to userID. order
+// is loaded by loadOrderDetails.
+func orderNotification(userID uuid.UUID, order *models.Order, kind string, title string, body string) models.Notification {
+	return models.Notification{
+		UserID:  userID,
+		Type:    kind,
+		Title:   title,
+		Body:    body,
+		ShopID:  &order.ShopID,
+		OrderID: &order.ID,
+	}
+}
+
+// newOrderNotifications tells the shop owner about a new order.
+func newOrderNotifications(order *models.Order, currency string) []models.Notification {
+	quantity := 0
+	for _, item := range order.Items {
+		quantity += item.Quantity
+	}
+
+	return []models.Notification{orderNotification(order.Shop.OwnerID, order, models.NotificationOrderCreated,
+		fmt.Sprintf("New order %s", orderNumber(order.ID)),
+		fmt.Sprintf("%s ordered %d items for %s at %s", firstName(order.User.Name), quantity,
+			utils.FormatCents(order.TotalPrice, currency), order.Shop.Name))}
+}
+
+// orderStatusNotifications tells the customer their order changed status.
+func orderStatusNotifications(order *models.Order) []models.Notification {
+	return []models.Notification{orderNotification(order.UserID, order, models.NotificationOrderStatus,
+		fmt.Sprintf("Order %s is %s", orderNumber(order.ID), order.Status),
+		fmt.Sprintf("Your order from %s is now %s.", order.Shop.Name, order.Status))}
+}
+
+// paymentNotifications tells the customer and the shop owner about a
+// payment that completed, failed or was refunded.
+func paymentNotifications(order *models.Order, payment *models.Payment, currency string) []models.Notification {
+	number := orderNumber(order.ID)
+	amount := utils.FormatCents(payment.Amount, currency)
+
+	switch payment.Status {
+	case models.PaymentStatusCompleted:
+		return []models.Notification{
+			orderNotification(order.UserID, order, models.NotificationPaymentReceived,
+				fmt.Sprintf("Payment confirmed for order %s", number),
+				fmt.Sprintf("We received your payment of %s to %s.", amount, order.Shop.Name)),
+			orderNotification(order.Shop.OwnerID, order, models.NotificationPaymentReceived,
+				fmt.Sprintf("Order %s was paid", number),
+				fmt.Sprintf("%s paid %s by %s.", firstName(order.User.Name), amount, payment.PaymentMethod)),
+		}
+	case models.PaymentStatusFailed:
+		return []models.Notification{orderNotification(order.UserID, order, models.NotificationPaymentFailed,
+			fmt.Sprintf("Payment failed for order %s", number),
+			fmt.Sprintf("Your payment of %s to %s did not go through. Please try again.", amount, order.Shop.Name))}
+	case models.PaymentStatusRefunded:
+		return []models.Notification{
+			orderNotification(order.UserID, order, models.NotificationPaymentRefunded,
+				fmt.Sprintf("Refund issued for order %s", number),
+				fmt.Sprintf("%s refunded %s to your %s.", order.Shop.Name, amount, payment.PaymentMethod)),
+			orderNotification(order.Shop.OwnerID, order, models.NotificationPaymentRefunded,
+				fmt.Sprintf("Order %s was refunded", number),
+				fmt.Sprintf("%s was refunded to %s.", amount, firstName(order.User.Name))),
+		}
+	}
+	return nil
+}
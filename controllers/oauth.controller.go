@@ -0,0 +1,328 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Llane00/ramen-backend/apperror"
+	"github.com/Llane00/ramen-backend/initializers"
+	"github.com/Llane00/ramen-backend/keyset"
+	"github.com/Llane00/ramen-backend/metrics"
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/oauth"
+	"github.com/Llane00/ramen-backend/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/thanhpk/randstr"
+	"gorm.io/gorm"
+)
+
+// oauthVerifierCookie holds the PKCE verifier between the redirect to the
+// provider and the callback. It is scoped to the OAuth routes.
+const (
+	oauthVerifierCookie = "oauth_verifier"
+	oauthCookiePath     = "/api/auth/oauth"
+)
+
+type OAuthController struct {
+	DB        *gorm.DB
+	Providers oauth.Registry
+}
+
+func NewOAuthController(DB *gorm.DB, providers oauth.Registry) OAuthController {
+	return OAuthController{DB, providers}
+}
+
+// oauthState is signed into the state parameter that makes the round trip
+// through the provider.
+type oauthState struct {
+	Provider string `json:"provider"`
+	Redirect string `json:"redirect"`
+	Nonce    string `json:"nonce"`
+	// Link is the ID of the signed in user the identity is being linked to,
+	// empty for a sign-in.
+	Link string `json:"link,omitempty"`
+	// Binding is the PKCE challenge of the verifier in the browser's cookie,
+	// so a state only completes in the browser that started the sign-in.
+	Binding string `json:"binding"`
+}
+
+// ListProviders returns the names of the configured sign-in providers.
+func (oc *OAuthController) ListProviders(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": gin.H{"providers": oc.Providers.Names()}})
+}
+
+// Begin redirects the browser to the provider's consent page. The optional
+// redirect query parameter is the client path to return to afterwards.
+func (oc *OAuthController) Begin(ctx *gin.Context) {
+	provider, ok := oc.Providers[ctx.Param("provider")]
+	if !ok {
+		ctx.Error(apperror.NotFound("oauth_provider_not_found", "Unknown sign-in provider"))
+		return
+	}
+
+	redirect := ctx.DefaultQuery("redirect", "/")
+	if !oauth.SafeRedirect(redirect) {
+		ctx.Error(apperror.Validation("invalid_redirect", "Invalid redirect",
+			apperror.FieldError{Field: "redirect", Message: "must be a path on the client"}))
+		return
+	}
+
+	authURL, err := startOAuth(ctx, provider, oauthState{Redirect: redirect})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.Redirect(http.StatusTemporaryRedirect, authURL)
+}
+
+// startOAuth signs state for provider, stores the PKCE verifier in the
+// browser and returns the provider's consent page URL.
+func startOAuth(ctx *gin.Context, provider oauth.Provider, state oauthState) (string, error) {
+	config, err := initializers.LoadConfig(".")
+	if err != nil {
+		return "", apperror.Internal(err)
+	}
+
+	verifier, err := oauth.NewVerifier()
+	if err != nil {
+		return "", apperror.Internal(err)
+	}
+
+	state.Provider = provider.Name()
+	state.Nonce = randstr.Hex(32)
+	state.Binding = oauth.Challenge(verifier)
+	signedState, err := utils.CreatePurposeToken(config.OAuthStateTTL(), state, utils.PurposeOAuthState, keyset.Access)
+	if err != nil {
+		return "", apperror.Internal(err)
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx.Request.Context(), signedState, oauth.Challenge(verifier), state.Nonce)
+	if err != nil {
+		return "", apperror.Upstream("oauth_provider_unavailable", "The sign-in provider is unavailable", err)
+	}
+
+	ctx.SetCookie(oauthVerifierCookie, verifier, int(config.OAuthStateTTL().Seconds()), oauthCookiePath, "localhost", false, true)
+	return authURL, nil
+}
+
+// Callback completes a sign-in started by Begin and redirects to the client.
+func (oc *OAuthController) Callback(ctx *gin.Context) {
+	provider, ok := oc.Providers[ctx.Param("provider")]
+	if !ok {
+		ctx.Error(apperror.NotFound("oauth_provider_not_found", "Unknown sign-in provider"))
+		return
+	}
+
+	if ctx.Query("error") != "" {
+		ctx.Error(apperror.Unauthorized("oauth_denied", "Sign-in was cancelled at the provider"))
+		return
+	}
+
+	code := ctx.Query("code")
+	if code == "" {
+		ctx.Error(apperror.Validation("missing_authorization_code", "Authorization code not provided!",
+			apperror.FieldError{Field: "code", Message: "is required"}))
+		return
+	}
+
+	// The verifier is single use whatever the outcome.
+	verifier, _ := ctx.Cookie(oauthVerifierCookie)
+	ctx.SetCookie(oauthVerifierCookie, "", -1, oauthCookiePath, "localhost", false, true)
+
+	state, err := parseOAuthState(ctx.Query("state"))
+	if err != nil || state.Provider != provider.Name() || verifier == "" ||
+		subtle.ConstantTimeCompare([]byte(oauth.Challenge(verifier)), []byte(state.Binding)) != 1 {
+		ctx.Error(&apperror.Error{Kind: apperror.KindUnauthorized, Code: "invalid_oauth_state", Message: "The sign-in request is invalid or has expired, please try again", Err: err})
+		return
+	}
+
+	identity, err := provider.Exchange(ctx.Request.Context(), code, verifier, state.Nonce)
+	if err != nil {
+		slog.ErrorContext(ctx.Request.Context(), "failed to complete oauth sign-in", "provider", provider.Name(), "error", err)
+		ctx.Error(apperror.Upstream("oauth_exchange_failed", fmt.Sprintf("Failed to sign in with %s", provider.Name()), err))
+		return
+	}
+
+	if state.Link != "" {
+		if err := oc.linkIdentity(ctx, state.Link, identity); err != nil {
+			ctx.Error(err)
+			return
+		}
+		config, _ := initializers.LoadConfig(".")
+		ctx.Redirect(http.StatusTemporaryRedirect, config.ClientOrigin+state.Redirect)
+		return
+	}
+
+	user, err := oc.identityUser(ctx, identity)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	config, _ := initializers.LoadConfig(".")
+
+	// The provider vouches for the password step only; accounts with
+	// two-factor enabled finish signing in on the client's MFA page. The
+	// challenge token travels in the fragment so it stays out of server logs.
+	if user.TOTPEnabled {
+		mfa_token, err := utils.CreatePurposeToken(config.MFATokenTTL(), user.ID, utils.PurposeMFA, keyset.Access)
+		if err != nil {
+			ctx.Error(apperror.Internal(err))
+			return
+		}
+		ctx.Redirect(http.StatusTemporaryRedirect, config.ClientOrigin+"/login/mfa#mfa_token="+url.QueryEscape(mfa_token))
+		return
+	}
+
+	if _, err := issueSession(ctx, &config, user); err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+
+	ctx.Redirect(http.StatusTemporaryRedirect, config.ClientOrigin+state.Redirect)
+}
+
+// parseOAuthState verifies a state parameter issued by Begin.
+func parseOAuthState(raw string) (*oauthState, error) {
+	sub, err := utils.ValidatePurposeToken(raw, utils.PurposeOAuthState, keyset.Access)
+	if err != nil {
+		return nil, err
+	}
+	claims, ok := sub.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("oauth state: unexpected subject %T", sub)
+	}
+
+	field := func(name string) string {
+		v, _ := claims[name].(string)
+		return v
+	}
+	state := &oauthState{
+		Provider: field("provider"),
+		Redirect: field("redirect"),
+		Nonce:    field("nonce"),
+		Link:     field("link"),
+		Binding:  field("binding"),
+	}
+	if !oauth.SafeRedirect(state.Redirect) || state.Binding == "" {
+		return nil, errors.New("oauth state: incomplete")
+	}
+	return state, nil
+}
+
+// identityUser resolves the user an identity signs in as, creating the user
+// on first sign-in. An identity never takes over an existing account by
+// email alone: the owner has to link it while signed in.
+func (oc *OAuthController) identityUser(ctx *gin.Context, identity *oauth.Identity) (*models.User, error) {
+	db := oc.DB.WithContext(ctx.Request.Context())
+	now := time.Now()
+
+	var linked models.UserIdentity
+	err := db.Preload("User").First(&linked, "provider = ? AND subject = ?", identity.Provider, identity.Subject).Error
+	if err == nil {
+		err := db.Model(&linked).UpdateColumns(map[string]any{
+			"email":          identity.Email,
+			"email_verified": identity.EmailVerified,
+			"last_used_at":   now,
+		}).Error
+		if err != nil {
+			return nil, apperror.Internal(err)
+		}
+		return &linked.User, nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, apperror.Internal(err)
+	}
+
+	// An unverified address proves nothing about who owns it, so it may
+	// neither claim an account nor reserve the address for a new one.
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, apperror.Forbidden("oauth_email_not_verified", fmt.Sprintf("Your %s account has no verified email address", identity.Provider))
+	}
+
+	var user models.User
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := tx.First(&user, "email = ?", identity.Email).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			user = models.User{
+				Name:     identity.Name,
+				Email:    identity.Email,
+				Password: "",
+				Roles:    models.UserRoles{models.RoleUser},
+				Verified: true,
+				Locale:   requestLocale(ctx, identity.Locale),
+				Photo:    identity.Picture,
+				Provider: identity.Provider,
+			}
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+			metrics.SignUps.WithLabelValues(user.Provider).Inc()
+		case err != nil:
+			return err
+		case !strings.EqualFold(user.Provider, identity.Provider):
+			// Users created by this provider before identities were stored
+			// are adopted by their verified email; any other account must
+			// link the identity explicitly.
+			return apperror.Conflict("oauth_account_exists", fmt.Sprintf("An account with this email already exists. Sign in and link your %s account from your profile", identity.Provider))
+		}
+
+		return tx.Create(&models.UserIdentity{
+			UserID:        user.ID,
+			Provider:      identity.Provider,
+			Subject:       identity.Subject,
+			Email:         identity.Email,
+			EmailVerified: identity.EmailVerified,
+			LastUsedAt:    &now,
+			CreatedAt:     now,
+		}).Error
+	})
+	if err != nil {
+		return nil, apperror.From(err)
+	}
+	return &user, nil
+}
+
+// linkIdentity attaches identity to the user that started the link.
+func (oc *OAuthController) linkIdentity(ctx *gin.Context, userID string, identity *oauth.Identity) error {
+	db := oc.DB.WithContext(ctx.Request.Context())
+
+	var existing models.UserIdentity
+	err := db.First(&existing, "provider = ? AND subject = ?", identity.Provider, identity.Subject).Error
+	if err == nil {
+		if existing.UserID.String() != userID {
+			return apperror.Conflict("identity_in_use", fmt.Sprintf("This %s account is already linked to another user", identity.Provider))
+		}
+		return nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperror.Internal(err)
+	}
+
+	var user models.User
+	if err := db.First(&user, "id = ?", userID).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperror.Forbidden("user_not_found", "The user this link was started for no longer exists")
+	} else if err != nil {
+		return apperror.Internal(err)
+	}
+
+	err = db.Create(&models.UserIdentity{
+		UserID:        user.ID,
+		Provider:      identity.Provider,
+		Subject:       identity.Subject,
+		Email:         identity.Email,
+		EmailVerified: identity.EmailVerified,
+		CreatedAt:     time.Now(),
+	}).Error
+	if err != nil {
+		return apperror.Internal(err)
+	}
+	slog.InfoContext(ctx.Request.Context(), "identity linked", "user_id", user.ID, "provider", identity.Provider)
+	return nil
+}
@@ -1,9 +1,15 @@
 package controllers
 
 import (
+	"fmt"
 	"net/http"
+	"time"
 
+	"github.com/Llane00/ramen-backend/apperror"
+	"github.com/Llane00/ramen-backend/initializers"
+	"github.com/Llane00/ramen-backend/metrics"
 	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/pagination"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -17,53 +23,169 @@ func NewOrderController(DB *gorm.DB) OrderController {
 	return OrderController{DB}
 }
 
+// OrderListSpec declares the filters and sort fields accepted by order listings.
+var OrderListSpec = pagination.Spec{
+	Filters: []pagination.Filter{
+		{Param: "status", Column: "status", Op: "=", Values: []string{
+			string(models.OrderStatusPending), string(models.OrderStatusPaid), string(models.OrderStatusShipping),
+			string(models.OrderStatusDelivered), string(models.OrderStatusCompleted), string(models.OrderStatusCancelled),
+		}},
+		{Param: "user_id", Column: "user_id", Op: "=", Kind: pagination.UUID},
+		{Param: "min_total", Column: "total_price", Op: ">=", Kind: pagination.Int},
+		{Param: "max_total", Column: "total_price", Op: "<=", Kind: pagination.Int},
+		{Param: "created_after", Column: "created_at", Op: ">=", Kind: pagination.Time},
+		{Param: "created_before", Column: "created_at", Op: "<=", Kind: pagination.Time},
+	},
+	Sorts: []pagination.SortField{
+		pagination.CreatedAt,
+		{Param: "total_price", Column: "total_price", Kind: pagination.Int},
+	},
+}
+
 // CreateOrder creates a new order
 func (oc *OrderController) CreateOrder(ctx *gin.Context) {
 	var input models.CreateOrderInput
 	if err := ctx.ShouldBindJSON(&input); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		ctx.Error(apperror.FromBinding(err))
 		return
 	}
 
-	user, exists := ctx.Get("currentUser")
-	if !exists {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+	currentUser, err := requireUser(ctx)
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
-	currentUser := user.(models.User)
 
-	shopId, err := uuid.Parse(ctx.Param("shopId"))
+	shopId, err := uuidParam(ctx, "shopId", "shop")
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shop ID"})
+		ctx.Error(err)
 		return
 	}
 
-	order := models.Order{
-		UserID:     currentUser.ID,
-		ShopID:     shopId,
-		TotalPrice: input.TotalPrice,
-		Status:     models.OrderStatusPending,
+	config, err := initializers.LoadConfig(".")
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
 	}
 
-	if err := oc.DB.Create(&order).Error; err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create order"})
+	var order models.Order
+	err = oc.DB.WithContext(ctx.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := checkTakingOrders(tx, shopId, time.Now()); err != nil {
+			return err
+		}
+
+		items, total, err := orderItems(tx, shopId, input.Items)
+		if err != nil {
+			return err
+		}
+		if total != input.TotalPrice {
+			return apperror.Validation("total_mismatch", "The order total does not match its items",
+				apperror.FieldError{Field: "total_price", Message: fmt.Sprintf("must be %d", total)})
+		}
+
+		order = models.Order{
+			UserID:     currentUser.ID,
+			ShopID:     shopId,
+			TotalPrice: total,
+			Status:     models.OrderStatusPending,
+			Items:      items,
+		}
+		if err := tx.Create(&order).Error; err != nil {
+			return err
+		}
+
+		details, err := loadOrderDetails(tx, order.ID)
+		if err != nil {
+			return err
+		}
+		if err := createNotifications(tx, newOrderNotifications(details, config.OrderCurrency())); err != nil {
+			return err
+		}
+		if err := notifyOrderEvent(tx, models.OrderEventCreated, &order, nil); err != nil {
+			return err
+		}
+		return queueOrderEmail(tx, &config, details, nil, "orderConfirmation.html")
+	})
+	if err != nil {
+		ctx.Error(apperror.From(err))
 		return
 	}
+	metrics.OrdersCreated.WithLabelValues(string(order.Status)).Inc()
 
 	ctx.JSON(http.StatusCreated, gin.H{"data": order})
 }
 
+// checkTakingOrders fails unless the shop takes orders at now: it is within
+// its opening hours and has not paused ordering.
+func checkTakingOrders(db *gorm.DB, shopID uuid.UUID, now time.Time) error {
+	var shop models.Shop
+	if err := withClosures(db).First(&shop, shopID).Error; err != nil {
+		return apperror.FromQuery(err, "shop_not_found", "Shop not found")
+	}
+	if shop.OrderingPaused {
+		return apperror.Conflict("ordering_paused", "The shop is not taking orders right now")
+	}
+	shop.SetOpenStatus(now)
+	if !shop.IsOpenNow {
+		message := "The shop is closed"
+		if shop.NextOpenAt != nil {
+			message = fmt.Sprintf("The shop is closed until %s", shop.NextOpenAt.Format(time.RFC3339))
+		}
+		return apperror.Conflict("shop_closed", message)
+	}
+	return nil
+}
+
+// orderItems prices the requested items from the shop's products. Names and
+// prices are copied so later product changes leave the order as placed.
+func orderItems(db *gorm.DB, shopID uuid.UUID, input []models.CreateOrderItemInput) ([]models.OrderItem, int64, error) {
+	ids := make([]uuid.UUID, len(input))
+	for i, item := range input {
+		ids[i] = item.ProductID
+	}
+
+	var products []models.Product
+	if err := db.Where("id IN ? AND shop_id = ?", ids, shopID).Find(&products).Error; err != nil {
+		return nil, 0, err
+	}
+	byID := make(map[uuid.UUID]models.Product, len(products))
+	for _, product := range products {
+		byID[product.ID] = product
+	}
+
+	items := make([]models.OrderItem, len(input))
+	var total int64
+	for i, item := range input {
+		product, ok := byID[item.ProductID]
+		if !ok {
+			return nil, 0, apperror.Validation("product_not_found", "No product with that ID in this shop",
+				apperror.FieldError{Field: fmt.Sprintf("items[%d].product_id", i), Message: "is not a product of this shop"})
+		}
+
+		items[i] = models.OrderItem{
+			ProductID:    product.ID,
+			ProductName:  product.Name,
+			ProductPrice: product.Price,
+			Quantity:     item.Quantity,
+			TotalPrice:   product.Price * int64(item.Quantity),
+			Status:       models.OrderItemStatusQueued,
+		}
+		total += items[i].TotalPrice
+	}
+	return items, total, nil
+}
+
 // GetOrder retrieves an order by its ID
 func (oc *OrderController) GetOrder(ctx *gin.Context) {
-	orderId, err := uuid.Parse(ctx.Param("orderId"))
+	orderId, err := uuidParam(ctx, "orderId", "order")
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		ctx.Error(err)
 		return
 	}
 
 	var order models.Order
-	if err := oc.DB.Preload("Items").First(&order, orderId).Error; err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+	if err := oc.DB.WithContext(ctx.Request.Context()).Preload("Items").First(&order, orderId).Error; err != nil {
+		ctx.Error(apperror.FromQuery(err, "order_not_found", "Order not found"))
 		return
 	}
 
@@ -72,63 +194,121 @@ func (oc *OrderController) GetOrder(ctx *gin.Context) {
 
 // UpdateOrderStatus updates the status of an order
 func (oc *OrderController) UpdateOrderStatus(ctx *gin.Context) {
-	orderId, err := uuid.Parse(ctx.Param("orderId"))
+	orderId, err := uuidParam(ctx, "orderId", "order")
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		ctx.Error(err)
 		return
 	}
 
 	var input models.UpdateOrderStatusInput
 	if err := ctx.ShouldBindJSON(&input); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		ctx.Error(apperror.FromBinding(err))
 		return
 	}
 
 	var order models.Order
-	if err := oc.DB.First(&order, orderId).Error; err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+	if err := oc.DB.WithContext(ctx.Request.Context()).First(&order, orderId).Error; err != nil {
+		ctx.Error(apperror.FromQuery(err, "order_not_found", "Order not found"))
 		return
 	}
 
-	order.Status = input.Status
-	if err := oc.DB.Save(&order).Error; err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order status"})
+	config, err := initializers.LoadConfig(".")
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+
+	previousStatus := order.Status
+	setOrderStatus(&order, input.Status)
+	err = oc.DB.WithContext(ctx.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&order).Error; err != nil {
+			return err
+		}
+		if order.Status == previousStatus {
+			return nil
+		}
+		return orderStatusChanged(tx, &config, &order)
+	})
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{"data": order})
 }
 
+// setOrderStatus changes the status of order, recording when it was first
+// paid.
+func setOrderStatus(order *models.Order, status models.OrderStatus) {
+	order.Status = status
+	if status == models.OrderStatusPaid && order.PaidAt == nil {
+		now := time.Now()
+		order.PaidAt = &now
+	}
+}
+
+// orderStatusChanged tells the customer and live subscribers that order,
+// saved in tx, changed status, and emails the customer about the statuses
+// in orderStatusEmails.
+func orderStatusChanged(tx *gorm.DB, config *initializers.Config, order *models.Order) error {
+	details, err := loadOrderDetails(tx, order.ID)
+	if err != nil {
+		return err
+	}
+	if err := createNotifications(tx, orderStatusNotifications(details)); err != nil {
+		return err
+	}
+	if err := notifyOrderEvent(tx, models.OrderEventStatus, order, nil); err != nil {
+		return err
+	}
+	if !orderStatusEmails[order.Status] {
+		return nil
+	}
+	return queueOrderEmail(tx, config, details, nil, "orderStatus.html")
+}
+
 // ListOrders lists all orders for a shop
 func (oc *OrderController) ListOrders(ctx *gin.Context) {
-	shopId, err := uuid.Parse(ctx.Param("shopId"))
+	shopId, err := uuidParam(ctx, "shopId", "shop")
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	query, err := pagination.Parse(ctx, OrderListSpec)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shop ID"})
+		ctx.Error(err)
 		return
 	}
 
-	var orders []models.Order
-	if err := oc.DB.Where("shop_id = ?", shopId).Find(&orders).Error; err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list orders"})
+	page, err := pagination.Find[models.Order](oc.DB.WithContext(ctx.Request.Context()).Where("shop_id = ?", shopId), query)
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"data": orders})
+	ctx.JSON(http.StatusOK, page)
 }
 
 // GetOrderPayments retrieves all payments for a specific order
 func (oc *OrderController) GetOrderPayments(ctx *gin.Context) {
-	orderId, err := uuid.Parse(ctx.Param("orderId"))
+	orderId, err := uuidParam(ctx, "orderId", "order")
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	query, err := pagination.Parse(ctx, PaymentListSpec)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		ctx.Error(err)
 		return
 	}
 
-	var payments []models.Payment
-	if err := oc.DB.Where("order_id = ?", orderId).Find(&payments).Error; err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve order payments"})
+	page, err := pagination.Find[models.Payment](oc.DB.WithContext(ctx.Request.Context()).Where("order_id = ?", orderId), query)
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"data": payments})
+	ctx.JSON(http.StatusOK, page)
 }
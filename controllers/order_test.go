@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/testdb"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// orderModels are the tables an order touches.
+var orderModels = []any{
+	&models.User{}, &models.Shop{}, &models.ShopClosure{}, &models.Product{}, &models.Order{},
+	&models.OrderItem{}, &models.Payment{}, &models.Notification{}, &models.EmailOutbox{},
+}
+
+// createTestShop stores an always open shop owned by owner with one product
+// per price.
+func createTestShop(t *testing.T, db *gorm.DB, owner models.User, prices ...int64) (models.Shop, []models.Product) {
+	t.Helper()
+	shop := models.Shop{Name: "Ramen Yamada", OwnerID: owner.ID, TimeZone: "UTC"}
+	if err := db.Create(&shop).Error; err != nil {
+		t.Fatalf("create shop: %v", err)
+	}
+	products := make([]models.Product, len(prices))
+	for i, price := range prices {
+		products[i] = models.Product{Name: "Ramen " + uuid.NewString()[:8], Price: price, Stock: 10, ShopID: shop.ID}
+		if err := db.Create(&products[i]).Error; err != nil {
+			t.Fatalf("create product: %v", err)
+		}
+	}
+	return shop, products
+}
+
+func createTestOrder(t *testing.T, oc *OrderController, user models.User, shop models.Shop, input models.CreateOrderInput) (*gin.Context, int) {
+	t.Helper()
+	ctx, rec := newTestContext(http.MethodPost, "/api/shops/"+shop.ID.String()+"/orders", input)
+	ctx.Params = gin.Params{{Key: "shopId", Value: shop.ID.String()}}
+	ctx.Set("currentUser", user)
+	oc.CreateOrder(ctx)
+	return ctx, rec.Code
+}
+
+func TestCreateOrderPricesItemsFromProducts(t *testing.T) {
+	testdb.Config(t)
+	db := testdb.Open(t, orderModels...)
+	oc := NewOrderController(db)
+
+	customer := createTestUser(t, db)
+	shop, products := createTestShop(t, db, createTestUser(t, db), 1450, 250)
+
+	input := models.CreateOrderInput{
+		TotalPrice: 2*1450 + 250,
+		Items: []models.CreateOrderItemInput{
+			{ProductID: products[0].ID, Quantity: 2},
+			{ProductID: products[1].ID, Quantity: 1},
+		},
+	}
+	if ctx, code := createTestOrder(t, &oc, customer, shop, input); code != http.StatusCreated || len(ctx.Errors) > 0 {
+		t.Fatalf("CreateOrder = %d %v, want 201", code, ctx.Errors)
+	}
+
+	var order models.Order
+	if err := db.Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("product_price DESC") }).
+		First(&order, "user_id = ?", customer.ID).Error; err != nil {
+		t.Fatalf("find order: %v", err)
+	}
+	if order.TotalPrice != 3150 || order.Status != models.OrderStatusPending || len(order.Items) != 2 {
+		t.Fatalf("order = %d %s with %d items, want a pending order of 3150 with 2 items", order.TotalPrice, order.Status, len(order.Items))
+	}
+	ramen := order.Items[0]
+	if ramen.ProductName != products[0].Name || ramen.ProductPrice != 1450 || ramen.TotalPrice != 2900 {
+		t.Errorf("item = %s %d %d, want %s 1450 2900", ramen.ProductName, ramen.ProductPrice, ramen.TotalPrice, products[0].Name)
+	}
+
+	// Later price changes leave the order as placed.
+	db.Model(&products[0]).Update("price", 1600)
+	var item models.OrderItem
+	db.First(&item, "id = ?", ramen.ID)
+	if item.ProductPrice != 1450 {
+		t.Errorf("item price after a product change = %d, want 1450", item.ProductPrice)
+	}
+}
+
+func TestCreateOrderRejectsWrongTotals(t *testing.T) {
+	testdb.Config(t)
+	db := testdb.Open(t, orderModels...)
+	oc := NewOrderController(db)
+
+	customer := createTestUser(t, db)
+	shop, products := createTestShop(t, db, createTestUser(t, db), 1450)
+	other, otherProducts := createTestShop(t, db, createTestUser(t, db), 100)
+
+	tests := []struct {
+		name  string
+		input models.CreateOrderInput
+		want  string
+	}{
+		{"client price", models.CreateOrderInput{TotalPrice: 1, Items: []models.CreateOrderItemInput{
+			{ProductID: products[0].ID, Quantity: 1},
+		}}, "total_mismatch"},
+		{"missing quantity", models.CreateOrderInput{TotalPrice: 1450, Items: []models.CreateOrderItemInput{
+			{ProductID: products[0].ID, Quantity: 2},
+		}}, "total_mismatch"},
+		{"another shop's product", models.CreateOrderInput{TotalPrice: 100, Items: []models.CreateOrderItemInput{
+			{ProductID: otherProducts[0].ID, Quantity: 1},
+		}}, "product_not_found"},
+	}
+	for _, tt := range tests {
+		ctx, _ := createTestOrder(t, &oc, customer, shop, tt.input)
+		if code := errorCode(ctx); code != tt.want {
+			t.Errorf("%s: error = %q, want %q", tt.name, code, tt.want)
+		}
+	}
+
+	var count int64
+	db.Model(&models.Order{}).Where("shop_id IN ?", []uuid.UUID{shop.ID, other.ID}).Count(&count)
+	if count != 0 {
+		t.Fatalf("%d orders created from rejected requests", count)
+	}
+}
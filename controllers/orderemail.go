@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"strings"
+
+	"github.com/Llane00/ramen-backend/initializers"
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/outbox"
+	"github.com/Llane00/ramen-backend/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// orderStatusEmails are the order statuses customers are emailed about. Paid
+// orders get a payment receipt instead.
+var orderStatusEmails = map[models.OrderStatus]bool{
+	models.OrderStatusShipping:  true,
+	models.OrderStatusDelivered: true,
+	models.OrderStatusCancelled: true,
+}
+
+// orderNumber is the short reference customers see for an order.
+func orderNumber(id uuid.UUID) string {
+	return strings.ToUpper(id.String()[:8])
+}
+
+// loadOrderDetails loads an order with what its emails and notifications
+// describe: the items, the shop and the customer.
+func loadOrderDetails(db *gorm.DB, orderID uuid.UUID) (*models.Order, error) {
+	var order models.Order
+	if err := db.Preload("Items").Preload("Shop").Preload("User").First(&order, "id = ?", orderID).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// queueOrderEmail queues template about order, loaded by loadOrderDetails, to
+// its customer unless they opted out. payment is set for receipts and
+// refunds, which follow the receipts preference; other order emails follow
+// the order updates one.
+func queueOrderEmail(db *gorm.DB, config *initializers.Config, order *models.Order, payment *models.Payment, template string) error {
+	user := &order.User
+	wanted := user.EmailOrderUpdates
+	if payment != nil {
+		wanted = user.EmailReceipts
+	}
+	if !wanted {
+		return nil
+	}
+
+	currency := config.OrderCurrency()
+
+	details := utils.OrderEmailData{
+		Number:   orderNumber(order.ID),
+		ShopName: order.Shop.Name,
+		Status:   string(order.Status),
+		Total:    utils.FormatCents(order.TotalPrice, currency),
+	}
+	for _, item := range order.Items {
+		details.Items = append(details.Items, utils.OrderLineData{
+			Name:      item.ProductName,
+			Quantity:  item.Quantity,
+			UnitPrice: utils.FormatCents(item.ProductPrice, currency),
+			Subtotal:  utils.FormatCents(item.TotalPrice, currency),
+		})
+	}
+	if payment != nil {
+		details.PaymentMethod = payment.PaymentMethod
+		details.Amount = utils.FormatCents(payment.Amount, currency)
+	}
+
+	emailData := utils.EmailData{
+		URL:       config.ClientOrigin + "/orders/" + order.ID.String(),
+		FirstName: firstName(user.Name),
+		Order:     &details,
+	}
+	return outbox.Enqueue(db, user, &emailData, template)
+}
@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"github.com/Llane00/ramen-backend/apperror"
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// uuidParam parses the named path parameter as a UUID. label is used in the
+// error message, e.g. "shop" yields "Invalid shop ID".
+func uuidParam(ctx *gin.Context, name string, label string) (uuid.UUID, error) {
+	id, err := uuid.Parse(ctx.Param(name))
+	if err != nil {
+		return uuid.Nil, apperror.Validation("invalid_"+label+"_id", "Invalid "+label+" ID",
+			apperror.FieldError{Field: name, Message: "must be a valid UUID"})
+	}
+	return id, nil
+}
+
+// requireUser returns the user stored by middleware.DeserializeUser.
+func requireUser(ctx *gin.Context) (models.User, error) {
+	user, exists := ctx.Get("currentUser")
+	if !exists {
+		return models.User{}, apperror.Unauthorized("not_logged_in", "You are not logged in")
+	}
+	return user.(models.User), nil
+}
+
+// requestLocale picks the locale of a new user: preferred, e.g. the locale of
+// an OAuth profile, if valid, otherwise the first Accept-Language of the
+// request, otherwise utils.DefaultLocale.
+func requestLocale(ctx *gin.Context, preferred string) string {
+	if locale := utils.NormalizeLocale(preferred); locale != "" {
+		return locale
+	}
+	if locale := utils.LocaleFromAcceptLanguage(ctx.GetHeader("Accept-Language")); locale != "" {
+		return locale
+	}
+	return utils.DefaultLocale
+}
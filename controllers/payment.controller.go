@@ -3,9 +3,12 @@ package controllers
 import (
 	"net/http"
 
+	"github.com/Llane00/ramen-backend/apperror"
+	"github.com/Llane00/ramen-backend/initializers"
+	"github.com/Llane00/ramen-backend/metrics"
 	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/pagination"
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -17,23 +20,40 @@ func NewPaymentController(DB *gorm.DB) PaymentController {
 	return PaymentController{DB}
 }
 
+// PaymentListSpec declares the filters and sort fields accepted by payment listings.
+var PaymentListSpec = pagination.Spec{
+	Filters: []pagination.Filter{
+		{Param: "status", Column: "status", Op: "=", Values: []string{
+			string(models.PaymentStatusPending), string(models.PaymentStatusProcessing), string(models.PaymentStatusCompleted),
+			string(models.PaymentStatusFailed), string(models.PaymentStatusRefunded), string(models.PaymentStatusCancelled),
+		}},
+		{Param: "payment_method", Column: "payment_method", Op: "="},
+		{Param: "created_after", Column: "created_at", Op: ">=", Kind: pagination.Time},
+		{Param: "created_before", Column: "created_at", Op: "<=", Kind: pagination.Time},
+	},
+	Sorts: []pagination.SortField{
+		pagination.CreatedAt,
+		{Param: "amount", Column: "amount", Kind: pagination.Int},
+	},
+}
+
 // CreatePayment creates a new payment
 func (pc *PaymentController) CreatePayment(ctx *gin.Context) {
 	var input models.CreatePaymentInput
 	if err := ctx.ShouldBindJSON(&input); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		ctx.Error(apperror.FromBinding(err))
 		return
 	}
 
-	orderId, err := uuid.Parse(ctx.Param("orderId"))
+	orderId, err := uuidParam(ctx, "orderId", "order")
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		ctx.Error(err)
 		return
 	}
 
 	var order models.Order
-	if err := pc.DB.First(&order, orderId).Error; err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+	if err := pc.DB.WithContext(ctx.Request.Context()).First(&order, orderId).Error; err != nil {
+		ctx.Error(apperror.FromQuery(err, "order_not_found", "Order not found"))
 		return
 	}
 
@@ -44,8 +64,14 @@ func (pc *PaymentController) CreatePayment(ctx *gin.Context) {
 		Status:        models.PaymentStatusPending,
 	}
 
-	if err := pc.DB.Create(&payment).Error; err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create payment"})
+	err = pc.DB.WithContext(ctx.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&payment).Error; err != nil {
+			return err
+		}
+		return notifyOrderEvent(tx, models.OrderEventPayment, &order, &payment)
+	})
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
 		return
 	}
 
@@ -54,15 +80,15 @@ func (pc *PaymentController) CreatePayment(ctx *gin.Context) {
 
 // GetPayment retrieves a payment by its ID
 func (pc *PaymentController) GetPayment(ctx *gin.Context) {
-	paymentID, err := uuid.Parse(ctx.Param("id"))
+	paymentID, err := uuidParam(ctx, "id", "payment")
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
+		ctx.Error(err)
 		return
 	}
 
 	var payment models.Payment
-	if err := pc.DB.First(&payment, paymentID).Error; err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+	if err := pc.DB.WithContext(ctx.Request.Context()).First(&payment, paymentID).Error; err != nil {
+		ctx.Error(apperror.FromQuery(err, "payment_not_found", "Payment not found"))
 		return
 	}
 
@@ -71,48 +97,90 @@ func (pc *PaymentController) GetPayment(ctx *gin.Context) {
 
 // UpdatePaymentStatus updates the status of a payment
 func (pc *PaymentController) UpdatePaymentStatus(ctx *gin.Context) {
-	paymentID, err := uuid.Parse(ctx.Param("id"))
+	paymentID, err := uuidParam(ctx, "id", "payment")
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
+		ctx.Error(err)
 		return
 	}
 
-	var input struct {
-		Status models.PaymentStatus `json:"status" binding:"required"`
-	}
+	var input models.UpdatePaymentStatusInput
 	if err := ctx.ShouldBindJSON(&input); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		ctx.Error(apperror.FromBinding(err))
 		return
 	}
 
 	var payment models.Payment
-	if err := pc.DB.First(&payment, paymentID).Error; err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+	if err := pc.DB.WithContext(ctx.Request.Context()).First(&payment, paymentID).Error; err != nil {
+		ctx.Error(apperror.FromQuery(err, "payment_not_found", "Payment not found"))
+		return
+	}
+
+	config, err := initializers.LoadConfig(".")
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
 		return
 	}
 
+	previousStatus := payment.Status
 	payment.Status = input.Status
-	if err := pc.DB.Save(&payment).Error; err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update payment status"})
+	err = pc.DB.WithContext(ctx.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&payment).Error; err != nil {
+			return err
+		}
+		if payment.Status == previousStatus {
+			return nil
+		}
+
+		order, err := loadOrderDetails(tx, payment.OrderID)
+		if err != nil {
+			return err
+		}
+		if err := createNotifications(tx, paymentNotifications(order, &payment, config.OrderCurrency())); err != nil {
+			return err
+		}
+		if err := notifyOrderEvent(tx, models.OrderEventPayment, order, &payment); err != nil {
+			return err
+		}
+
+		switch payment.Status {
+		case models.PaymentStatusCompleted:
+			return queueOrderEmail(tx, &config, order, &payment, "paymentReceipt.html")
+		case models.PaymentStatusRefunded:
+			return queueOrderEmail(tx, &config, order, &payment, "refundConfirmation.html")
+		}
+		return nil
+	})
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
 		return
 	}
+	if payment.Status != previousStatus &&
+		(payment.Status == models.PaymentStatusCompleted || payment.Status == models.PaymentStatusFailed) {
+		metrics.Payments.WithLabelValues(string(payment.Status)).Inc()
+	}
 
 	ctx.JSON(http.StatusOK, gin.H{"data": payment})
 }
 
 // ListPayments lists all payments for an order
 func (pc *PaymentController) ListPayments(ctx *gin.Context) {
-	orderId, err := uuid.Parse(ctx.Param("orderId"))
+	orderId, err := uuidParam(ctx, "orderId", "order")
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		ctx.Error(err)
 		return
 	}
 
-	var payments []models.Payment
-	if err := pc.DB.Where("order_id = ?", orderId).Find(&payments).Error; err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list payments"})
+	query, err := pagination.Parse(ctx, PaymentListSpec)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	page, err := pagination.Find[models.Payment](pc.DB.WithContext(ctx.Request.Context()).Where("order_id = ?", orderId), query)
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"data": payments})
+	ctx.JSON(http.StatusOK, page)
 }
@@ -2,11 +2,12 @@ package controllers
 
 import (
 	"net/http"
-	"strconv"
 	"strings"
 	"time"
 
+	"github.com/Llane00/ramen-backend/apperror"
 	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/pagination"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
@@ -19,12 +20,26 @@ func NewPostController(DB *gorm.DB) PostController {
 	return PostController{DB}
 }
 
+// PostListSpec declares the filters and sort fields accepted by FindPosts.
+var PostListSpec = pagination.Spec{
+	Filters: []pagination.Filter{
+		{Param: "user", Column: `"user"`, Op: "=", Kind: pagination.UUID},
+		{Param: "q", Column: "title", Op: "contains"},
+		{Param: "created_after", Column: "created_at", Op: ">=", Kind: pagination.Time},
+		{Param: "created_before", Column: "created_at", Op: "<=", Kind: pagination.Time},
+	},
+	Sorts: []pagination.SortField{
+		pagination.CreatedAt,
+		{Param: "title", Column: "title", Kind: pagination.String},
+	},
+}
+
 func (pc *PostController) CreatePost(ctx *gin.Context) {
 	currentUser := ctx.MustGet("currentUser").(models.User)
 	var payload *models.CreatePostRequest
 
 	if err := ctx.ShouldBindJSON(&payload); err != nil {
-		ctx.JSON(http.StatusBadRequest, err.Error())
+		ctx.Error(apperror.FromBinding(err))
 		return
 	}
 
@@ -38,13 +53,13 @@ func (pc *PostController) CreatePost(ctx *gin.Context) {
 		UpdatedAt: now,
 	}
 
-	result := pc.DB.Create(&newPost)
+	result := pc.DB.WithContext(ctx.Request.Context()).Create(&newPost)
 	if result.Error != nil {
 		if strings.Contains(result.Error.Error(), "duplicate key") {
-			ctx.JSON(http.StatusConflict, gin.H{"status": "fail", "message": "Post with that title already exists"})
+			ctx.Error(apperror.Conflict("post_title_taken", "Post with that title already exists"))
 			return
 		}
-		ctx.JSON(http.StatusBadGateway, gin.H{"status": "error", "message": result.Error.Error()})
+		ctx.Error(apperror.Internal(result.Error))
 		return
 	}
 
@@ -52,18 +67,22 @@ func (pc *PostController) CreatePost(ctx *gin.Context) {
 }
 
 func (pc *PostController) UpdatePost(ctx *gin.Context) {
-	postId := ctx.Param("postId")
+	postId, err := uuidParam(ctx, "postId", "post")
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
 	currentUser := ctx.MustGet("currentUser").(models.User)
 
 	var payload *models.UpdatePost
 	if err := ctx.ShouldBindJSON(&payload); err != nil {
-		ctx.JSON(http.StatusBadGateway, gin.H{"status": "fail", "message": err.Error()})
+		ctx.Error(apperror.FromBinding(err))
 		return
 	}
 	var updatedPost models.Post
-	result := pc.DB.First(&updatedPost, "id = ?", postId)
+	result := pc.DB.WithContext(ctx.Request.Context()).First(&updatedPost, "id = ?", postId)
 	if result.Error != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"status": "fail", "message": "No post with that title exists"})
+		ctx.Error(apperror.FromQuery(result.Error, "post_not_found", "No post with that title exists"))
 		return
 	}
 	now := time.Now()
@@ -76,18 +95,25 @@ func (pc *PostController) UpdatePost(ctx *gin.Context) {
 		UpdatedAt: now,
 	}
 
-	pc.DB.Model(&updatedPost).Updates(postToUpdate)
+	if err := pc.DB.WithContext(ctx.Request.Context()).Model(&updatedPost).Updates(postToUpdate).Error; err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
 
 	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": updatedPost})
 }
 
 func (pc *PostController) FindPostById(ctx *gin.Context) {
-	postId := ctx.Param("postId")
+	postId, err := uuidParam(ctx, "postId", "post")
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
 
 	var post models.Post
-	result := pc.DB.First(&post, "id = ?", postId)
+	result := pc.DB.WithContext(ctx.Request.Context()).First(&post, "id = ?", postId)
 	if result.Error != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"status": "fail", "message": "No post with that title exists"})
+		ctx.Error(apperror.FromQuery(result.Error, "post_not_found", "No post with that title exists"))
 		return
 	}
 
@@ -95,30 +121,36 @@ func (pc *PostController) FindPostById(ctx *gin.Context) {
 }
 
 func (pc *PostController) FindPosts(ctx *gin.Context) {
-	var page = ctx.DefaultQuery("page", "1")
-	var limit = ctx.DefaultQuery("limit", "10")
-
-	intPage, _ := strconv.Atoi(page)
-	intLimit, _ := strconv.Atoi(limit)
-	offset := (intPage - 1) * intLimit
+	query, err := pagination.Parse(ctx, PostListSpec)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
 
-	var posts []models.Post
-	results := pc.DB.Limit(intLimit).Offset(offset).Find(&posts)
-	if results.Error != nil {
-		ctx.JSON(http.StatusBadGateway, gin.H{"status": "error", "message": results.Error})
+	page, err := pagination.Find[models.Post](pc.DB.WithContext(ctx.Request.Context()), query)
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"status": "success", "results": len(posts), "data": posts})
+	ctx.JSON(http.StatusOK, page)
 }
 
 func (pc *PostController) DeletePost(ctx *gin.Context) {
-	postId := ctx.Param("postId")
+	postId, err := uuidParam(ctx, "postId", "post")
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
 
-	result := pc.DB.Delete(&models.Post{}, "id = ?", postId)
+	result := pc.DB.WithContext(ctx.Request.Context()).Delete(&models.Post{}, "id = ?", postId)
 
 	if result.Error != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"status": "fail", "message": "No post with that title exists"})
+		ctx.Error(apperror.Internal(result.Error))
+		return
+	}
+	if result.RowsAffected == 0 {
+		ctx.Error(apperror.NotFound("post_not_found", "No post with that title exists"))
 		return
 	}
 
@@ -3,9 +3,10 @@ package controllers
 import (
 	"net/http"
 
+	"github.com/Llane00/ramen-backend/apperror"
 	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/pagination"
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -17,17 +18,32 @@ func NewProductController(DB *gorm.DB) ProductController {
 	return ProductController{DB}
 }
 
+// ProductListSpec declares the filters and sort fields accepted by product listings.
+var ProductListSpec = pagination.Spec{
+	Filters: []pagination.Filter{
+		{Param: "min_price", Column: "price", Op: ">=", Kind: pagination.Int},
+		{Param: "max_price", Column: "price", Op: "<=", Kind: pagination.Int},
+		{Param: "min_stock", Column: "stock", Op: ">=", Kind: pagination.Int},
+		{Param: "q", Column: "name", Op: "contains"},
+	},
+	Sorts: []pagination.SortField{
+		pagination.CreatedAt,
+		{Param: "price", Column: "price", Kind: pagination.Int},
+		{Param: "name", Column: "name", Kind: pagination.String},
+	},
+}
+
 // CreateProduct creates a new product
 func (pc *ProductController) CreateProduct(ctx *gin.Context) {
 	var input models.CreateProductInput
 	if err := ctx.ShouldBindJSON(&input); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		ctx.Error(apperror.FromBinding(err))
 		return
 	}
 
-	shopUUID, err := uuid.Parse(ctx.Param("shopId"))
+	shopUUID, err := uuidParam(ctx, "shopId", "shop")
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shop ID"})
+		ctx.Error(err)
 		return
 	}
 
@@ -39,8 +55,8 @@ func (pc *ProductController) CreateProduct(ctx *gin.Context) {
 		ShopID:      shopUUID,
 	}
 
-	if err := pc.DB.Create(&product).Error; err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create product"})
+	if err := pc.DB.WithContext(ctx.Request.Context()).Create(&product).Error; err != nil {
+		ctx.Error(apperror.Internal(err))
 		return
 	}
 
@@ -49,15 +65,15 @@ func (pc *ProductController) CreateProduct(ctx *gin.Context) {
 
 // GetProduct retrieves a specific product
 func (pc *ProductController) GetProduct(ctx *gin.Context) {
-	productId, err := uuid.Parse(ctx.Param("productId"))
+	productId, err := uuidParam(ctx, "productId", "product")
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		ctx.Error(err)
 		return
 	}
 
 	var product models.Product
-	if err := pc.DB.First(&product, productId).Error; err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+	if err := pc.DB.WithContext(ctx.Request.Context()).First(&product, productId).Error; err != nil {
+		ctx.Error(apperror.FromQuery(err, "product_not_found", "Product not found"))
 		return
 	}
 
@@ -66,39 +82,47 @@ func (pc *ProductController) GetProduct(ctx *gin.Context) {
 
 // UpdateProduct updates a product
 func (pc *ProductController) UpdateProduct(ctx *gin.Context) {
-	productId, err := uuid.Parse(ctx.Param("productId"))
+	productId, err := uuidParam(ctx, "productId", "product")
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		ctx.Error(err)
 		return
 	}
 
 	var product models.Product
-	if err := pc.DB.First(&product, productId).Error; err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+	if err := pc.DB.WithContext(ctx.Request.Context()).First(&product, productId).Error; err != nil {
+		ctx.Error(apperror.FromQuery(err, "product_not_found", "Product not found"))
 		return
 	}
 
 	var input models.UpdateProductInput
 	if err := ctx.ShouldBindJSON(&input); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		ctx.Error(apperror.FromBinding(err))
 		return
 	}
 
-	pc.DB.Model(&product).Updates(input)
+	if err := pc.DB.WithContext(ctx.Request.Context()).Model(&product).Updates(input).Error; err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
 
 	ctx.JSON(http.StatusOK, gin.H{"data": product})
 }
 
 // DeleteProduct deletes a product
 func (pc *ProductController) DeleteProduct(ctx *gin.Context) {
-	productId, err := uuid.Parse(ctx.Param("productId"))
+	productId, err := uuidParam(ctx, "productId", "product")
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		ctx.Error(err)
 		return
 	}
 
-	if err := pc.DB.Delete(&models.Product{}, productId).Error; err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete product"})
+	result := pc.DB.WithContext(ctx.Request.Context()).Delete(&models.Product{}, productId)
+	if result.Error != nil {
+		ctx.Error(apperror.Internal(result.Error))
+		return
+	}
+	if result.RowsAffected == 0 {
+		ctx.Error(apperror.NotFound("product_not_found", "Product not found"))
 		return
 	}
 
@@ -107,26 +131,32 @@ func (pc *ProductController) DeleteProduct(ctx *gin.Context) {
 
 // ListProducts lists all products for a shop
 func (pc *ProductController) ListProducts(ctx *gin.Context) {
-	shopId, err := uuid.Parse(ctx.Param("shopId"))
+	shopId, err := uuidParam(ctx, "shopId", "shop")
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shop ID"})
+		ctx.Error(err)
 		return
 	}
 
-	var products []models.Product
-	if err := pc.DB.Where("shop_id = ?", shopId).Find(&products).Error; err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list products"})
+	query, err := pagination.Parse(ctx, ProductListSpec)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	page, err := pagination.Find[models.Product](pc.DB.WithContext(ctx.Request.Context()).Where("shop_id = ?", shopId), query)
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"data": products})
+	ctx.JSON(http.StatusOK, page)
 }
 
 // UpdateProductStock updates the stock of a product
 func (pc *ProductController) UpdateProductStock(ctx *gin.Context) {
-	productId, err := uuid.Parse(ctx.Param("productId"))
+	productId, err := uuidParam(ctx, "productId", "product")
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		ctx.Error(err)
 		return
 	}
 
@@ -134,19 +164,19 @@ func (pc *ProductController) UpdateProductStock(ctx *gin.Context) {
 		Stock int `json:"stock" binding:"required"`
 	}
 	if err := ctx.ShouldBindJSON(&input); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		ctx.Error(apperror.FromBinding(err))
 		return
 	}
 
 	var product models.Product
-	if err := pc.DB.First(&product, productId).Error; err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+	if err := pc.DB.WithContext(ctx.Request.Context()).First(&product, productId).Error; err != nil {
+		ctx.Error(apperror.FromQuery(err, "product_not_found", "Product not found"))
 		return
 	}
 
 	product.Stock = input.Stock
-	if err := pc.DB.Save(&product).Error; err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update product stock"})
+	if err := pc.DB.WithContext(ctx.Request.Context()).Save(&product).Error; err != nil {
+		ctx.Error(apperror.Internal(err))
 		return
 	}
 
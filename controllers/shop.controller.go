@@ -2,8 +2,11 @@ package controllers
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/Llane00/ramen-backend/apperror"
 	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/pagination"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -17,87 +20,138 @@ func NewShopController(DB *gorm.DB) ShopController {
 	return ShopController{DB}
 }
 
+// ShopListSpec declares the filters and sort fields accepted by shop listings.
+var ShopListSpec = pagination.Spec{
+	Filters: []pagination.Filter{
+		{Param: "owner_id", Column: "owner_id", Op: "=", Kind: pagination.UUID},
+		{Param: "q", Column: "name", Op: "contains"},
+		{Param: "created_after", Column: "created_at", Op: ">=", Kind: pagination.Time},
+		{Param: "created_before", Column: "created_at", Op: "<=", Kind: pagination.Time},
+	},
+	Sorts: []pagination.SortField{
+		pagination.CreatedAt,
+		{Param: "name", Column: "name", Kind: pagination.String},
+	},
+}
+
+// withClosures preloads the closures that may still affect whether a shop
+// is open, earliest first.
+func withClosures(db *gorm.DB) *gorm.DB {
+	// A day ago in UTC is still today somewhere.
+	cutoff := time.Now().AddDate(0, 0, -1).Format(time.DateOnly)
+	return db.Preload("Closures", func(db *gorm.DB) *gorm.DB {
+		return db.Where("ends_on >= ?", cutoff).Order("starts_on")
+	})
+}
+
+// ownedShop loads the shop with shopID if user owns it. Super admins may act
+// for any owner.
+func ownedShop(db *gorm.DB, shopID uuid.UUID, user *models.User) (*models.Shop, error) {
+	var shop models.Shop
+	if err := withClosures(db).First(&shop, shopID).Error; err != nil {
+		return nil, apperror.FromQuery(err, "shop_not_found", "Shop not found")
+	}
+	if shop.OwnerID != user.ID && !user.HasRole(models.RoleSuperAdmin) {
+		return nil, apperror.Forbidden("shop_forbidden", "Only the shop owner can do this")
+	}
+	return &shop, nil
+}
+
 // CreateShop creates a new shop
 func (sc *ShopController) CreateShop(ctx *gin.Context) {
 	var input models.CreateShopInput
 	if err := ctx.ShouldBindJSON(&input); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		ctx.Error(apperror.FromBinding(err))
 		return
 	}
 
-	user, exists := ctx.Get("currentUser")
-	if !exists {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+	currentUser, err := requireUser(ctx)
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
-	currentUser := user.(models.User)
 
 	shop := models.Shop{
 		Name:        input.Name,
 		Description: input.Description,
 		OwnerID:     currentUser.ID,
+		TimeZone:    input.TimeZone,
+	}
+	if shop.TimeZone == "" {
+		shop.TimeZone = "UTC"
 	}
 
-	if err := sc.DB.Create(&shop).Error; err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create shop"})
+	if err := sc.DB.WithContext(ctx.Request.Context()).Create(&shop).Error; err != nil {
+		ctx.Error(apperror.Internal(err))
 		return
 	}
+	shop.SetOpenStatus(time.Now())
 
 	ctx.JSON(http.StatusCreated, gin.H{"data": shop})
 }
 
 // GetShop retrieves a shop by its ID
 func (sc *ShopController) GetShop(ctx *gin.Context) {
-	shopId, err := uuid.Parse(ctx.Param("shopId"))
+	shopId, err := uuidParam(ctx, "shopId", "shop")
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shop ID"})
+		ctx.Error(err)
 		return
 	}
 
 	var shop models.Shop
-	if err := sc.DB.First(&shop, shopId).Error; err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Shop not found"})
+	if err := withClosures(sc.DB.WithContext(ctx.Request.Context())).First(&shop, shopId).Error; err != nil {
+		ctx.Error(apperror.FromQuery(err, "shop_not_found", "Shop not found"))
 		return
 	}
+	shop.SetOpenStatus(time.Now())
 
 	ctx.JSON(http.StatusOK, gin.H{"data": shop})
 }
 
 // UpdateShop updates a shop
 func (sc *ShopController) UpdateShop(ctx *gin.Context) {
-	shopId, err := uuid.Parse(ctx.Param("shopId"))
+	shopId, err := uuidParam(ctx, "shopId", "shop")
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shop ID"})
+		ctx.Error(err)
 		return
 	}
 
 	var shop models.Shop
-	if err := sc.DB.First(&shop, shopId).Error; err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": "Shop not found"})
+	if err := withClosures(sc.DB.WithContext(ctx.Request.Context())).First(&shop, shopId).Error; err != nil {
+		ctx.Error(apperror.FromQuery(err, "shop_not_found", "Shop not found"))
 		return
 	}
 
 	var input models.UpdateShopInput
 	if err := ctx.ShouldBindJSON(&input); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		ctx.Error(apperror.FromBinding(err))
 		return
 	}
 
-	sc.DB.Model(&shop).Updates(input)
+	if err := sc.DB.WithContext(ctx.Request.Context()).Model(&shop).Updates(input).Error; err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+	shop.SetOpenStatus(time.Now())
 
 	ctx.JSON(http.StatusOK, gin.H{"data": shop})
 }
 
 // DeleteShop deletes a shop
 func (sc *ShopController) DeleteShop(ctx *gin.Context) {
-	shopId, err := uuid.Parse(ctx.Param("shopId"))
+	shopId, err := uuidParam(ctx, "shopId", "shop")
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shop ID"})
+		ctx.Error(err)
 		return
 	}
 
-	if err := sc.DB.Delete(&models.Shop{}, shopId).Error; err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete shop"})
+	result := sc.DB.WithContext(ctx.Request.Context()).Delete(&models.Shop{}, shopId)
+	if result.Error != nil {
+		ctx.Error(apperror.Internal(result.Error))
+		return
+	}
+	if result.RowsAffected == 0 {
+		ctx.Error(apperror.NotFound("shop_not_found", "Shop not found"))
 		return
 	}
 
@@ -106,45 +160,232 @@ func (sc *ShopController) DeleteShop(ctx *gin.Context) {
 
 // ListShops lists all shops
 func (sc *ShopController) ListShops(ctx *gin.Context) {
-	var shops []models.Shop
-	if err := sc.DB.Find(&shops).Error; err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list shops"})
+	query, err := pagination.Parse(ctx, ShopListSpec)
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"data": shops})
+	page, err := pagination.Find[models.Shop](withClosures(sc.DB.WithContext(ctx.Request.Context())), query)
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+	now := time.Now()
+	for i := range page.Data {
+		page.Data[i].SetOpenStatus(now)
+	}
+
+	ctx.JSON(http.StatusOK, page)
 }
 
 // GetShopProducts retrieves all products for a specific shop
 func (sc *ShopController) GetShopProducts(ctx *gin.Context) {
-	shopId, err := uuid.Parse(ctx.Param("shopId"))
+	shopId, err := uuidParam(ctx, "shopId", "shop")
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shop ID"})
+		ctx.Error(err)
 		return
 	}
 
-	var products []models.Product
-	if err := sc.DB.Where("shop_id = ?", shopId).Find(&products).Error; err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve shop products"})
+	query, err := pagination.Parse(ctx, ProductListSpec)
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"data": products})
+	page, err := pagination.Find[models.Product](sc.DB.WithContext(ctx.Request.Context()).Where("shop_id = ?", shopId), query)
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, page)
 }
 
 // GetShopOrders retrieves all orders for a specific shop
 func (sc *ShopController) GetShopOrders(ctx *gin.Context) {
-	shopId, err := uuid.Parse(ctx.Param("shopId"))
+	shopId, err := uuidParam(ctx, "shopId", "shop")
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	query, err := pagination.Parse(ctx, OrderListSpec)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	page, err := pagination.Find[models.Order](sc.DB.WithContext(ctx.Request.Context()).Where("shop_id = ?", shopId), query)
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, page)
+}
+
+// UpdateOpeningHours replaces the shop's weekly opening hours.
+func (sc *ShopController) UpdateOpeningHours(ctx *gin.Context) {
+	currentUser, err := requireUser(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	shopId, err := uuidParam(ctx, "shopId", "shop")
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	var input models.UpdateOpeningHoursInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.Error(apperror.FromBinding(err))
+		return
+	}
+
+	db := sc.DB.WithContext(ctx.Request.Context())
+	shop, err := ownedShop(db, shopId, &currentUser)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	if err := db.Model(shop).Update("opening_hours", input.Hours).Error; err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+	shop.OpeningHours = input.Hours
+	shop.SetOpenStatus(time.Now())
+
+	ctx.JSON(http.StatusOK, gin.H{"data": shop})
+}
+
+// UpdateOrdering pauses or resumes taking orders, whatever the opening
+// hours.
+func (sc *ShopController) UpdateOrdering(ctx *gin.Context) {
+	currentUser, err := requireUser(ctx)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shop ID"})
+		ctx.Error(err)
 		return
 	}
 
-	var orders []models.Order
-	if err := sc.DB.Where("shop_id = ?", shopId).Find(&orders).Error; err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve shop orders"})
+	shopId, err := uuidParam(ctx, "shopId", "shop")
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	var input models.UpdateOrderingInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.Error(apperror.FromBinding(err))
+		return
+	}
+
+	db := sc.DB.WithContext(ctx.Request.Context())
+	shop, err := ownedShop(db, shopId, &currentUser)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	if err := db.Model(shop).Update("ordering_paused", *input.Paused).Error; err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+	shop.OrderingPaused = *input.Paused
+	shop.SetOpenStatus(time.Now())
+
+	ctx.JSON(http.StatusOK, gin.H{"data": shop})
+}
+
+// CreateClosure closes the shop for a day or a range of days.
+func (sc *ShopController) CreateClosure(ctx *gin.Context) {
+	currentUser, err := requireUser(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	shopId, err := uuidParam(ctx, "shopId", "shop")
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	var input models.CreateShopClosureInput
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.Error(apperror.FromBinding(err))
+		return
+	}
+	if input.EndsOn == "" {
+		input.EndsOn = input.StartsOn
+	}
+	// Dates in YYYY-MM-DD order as strings.
+	if input.EndsOn < input.StartsOn {
+		ctx.Error(apperror.Validation("invalid_closure", "A closure cannot end before it starts",
+			apperror.FieldError{Field: "ends_on", Message: "must not be before starts_on"}))
+		return
+	}
+
+	db := sc.DB.WithContext(ctx.Request.Context())
+	shop, err := ownedShop(db, shopId, &currentUser)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	closure := models.ShopClosure{
+		ShopID:   shop.ID,
+		StartsOn: input.StartsOn,
+		EndsOn:   input.EndsOn,
+		Reason:   input.Reason,
+	}
+	if err := db.Create(&closure).Error; err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"data": closure})
+}
+
+// DeleteClosure reopens the days of a closure.
+func (sc *ShopController) DeleteClosure(ctx *gin.Context) {
+	currentUser, err := requireUser(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	shopId, err := uuidParam(ctx, "shopId", "shop")
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	closureId, err := uuidParam(ctx, "closureId", "closure")
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	db := sc.DB.WithContext(ctx.Request.Context())
+	shop, err := ownedShop(db, shopId, &currentUser)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	result := db.Where("shop_id = ?", shop.ID).Delete(&models.ShopClosure{}, closureId)
+	if result.Error != nil {
+		ctx.Error(apperror.Internal(result.Error))
+		return
+	}
+	if result.RowsAffected == 0 {
+		ctx.Error(apperror.NotFound("closure_not_found", "Closure not found"))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"data": orders})
+	ctx.JSON(http.StatusNoContent, nil)
 }
@@ -0,0 +1,230 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Llane00/ramen-backend/apperror"
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/testdb"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var shopModels = []any{&models.User{}, &models.Shop{}, &models.ShopClosure{}}
+
+// shopRequest calls handler as user on shop with body.
+func shopRequest(handler gin.HandlerFunc, method string, user models.User, shop models.Shop, body any, params ...gin.Param) (*gin.Context, []byte) {
+	ctx, rec := newTestContext(method, "/api/shops/"+shop.ID.String(), body)
+	ctx.Params = append(gin.Params{{Key: "shopId", Value: shop.ID.String()}}, params...)
+	ctx.Set("currentUser", user)
+	handler(ctx)
+	return ctx, rec.Body.Bytes()
+}
+
+func reloadShop(t *testing.T, db *gorm.DB, id uuid.UUID) models.Shop {
+	t.Helper()
+	var shop models.Shop
+	if err := withClosures(db).First(&shop, id).Error; err != nil {
+		t.Fatalf("find shop: %v", err)
+	}
+	return shop
+}
+
+func TestCheckTakingOrders(t *testing.T) {
+	db := testdb.Open(t, shopModels...)
+	shop, _ := createTestShop(t, db, createTestUser(t, db))
+	db.Model(&shop).Updates(map[string]any{
+		"time_zone":     "Asia/Tokyo",
+		"opening_hours": models.WeeklyHours{{Day: "monday", Opens: "11:00", Closes: "14:00"}},
+	})
+
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	// 2030-01-07 is a Monday.
+	lunch := time.Date(2030, 1, 7, 12, 0, 0, 0, tokyo)
+	if err := checkTakingOrders(db, shop.ID, lunch); err != nil {
+		t.Fatalf("checkTakingOrders at lunch = %v, want nil", err)
+	}
+
+	err := checkTakingOrders(db, shop.ID, lunch.Add(3*time.Hour))
+	if appErr := apperror.From(err); appErr.Code != "shop_closed" || !strings.Contains(appErr.Message, "2030-01-14T11:00:00+09:00") {
+		t.Errorf("checkTakingOrders after closing = %v, want shop_closed until next Monday", err)
+	}
+
+	db.Create(&models.ShopClosure{ShopID: shop.ID, StartsOn: "2030-01-07", EndsOn: "2030-01-07"})
+	if err := checkTakingOrders(db, shop.ID, lunch); apperror.From(err).Code != "shop_closed" {
+		t.Errorf("checkTakingOrders on a closure = %v, want shop_closed", err)
+	}
+
+	db.Model(&shop).Update("ordering_paused", true)
+	if err := checkTakingOrders(db, shop.ID, lunch.AddDate(0, 0, 7)); apperror.From(err).Code != "ordering_paused" {
+		t.Errorf("checkTakingOrders while paused = %v, want ordering_paused", err)
+	}
+
+	if err := checkTakingOrders(db, uuid.New(), lunch); apperror.From(err).Code != "shop_not_found" {
+		t.Errorf("checkTakingOrders of a missing shop = %v, want shop_not_found", err)
+	}
+}
+
+func TestUpdateOpeningHours(t *testing.T) {
+	db := testdb.Open(t, shopModels...)
+	sc := NewShopController(db)
+	owner := createTestUser(t, db)
+	shop, _ := createTestShop(t, db, owner)
+
+	hours := models.WeeklyHours{
+		{Day: "monday", Opens: "11:00", Closes: "14:00"},
+		{Day: "friday", Opens: "18:00", Closes: "02:00"},
+	}
+	ctx, body := shopRequest(sc.UpdateOpeningHours, http.MethodPut, owner, shop, models.UpdateOpeningHoursInput{Hours: hours})
+	if ctx.Writer.Status() != http.StatusOK || len(ctx.Errors) > 0 {
+		t.Fatalf("UpdateOpeningHours = %d %v, want 200", ctx.Writer.Status(), ctx.Errors)
+	}
+	var response struct {
+		Data map[string]json.RawMessage
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	for _, key := range []string{"Name", "OwnerID", "time_zone", "opening_hours", "is_open_now", "next_open_at"} {
+		if _, ok := response.Data[key]; !ok {
+			t.Errorf("shop response has no %q: %s", key, body)
+		}
+	}
+	if got := reloadShop(t, db, shop.ID).OpeningHours; len(got) != 2 || got[1] != hours[1] {
+		t.Errorf("stored hours = %+v, want %+v", got, hours)
+	}
+
+	// An empty schedule is always open.
+	shopRequest(sc.UpdateOpeningHours, http.MethodPut, owner, shop, models.UpdateOpeningHoursInput{})
+	if stored := reloadShop(t, db, shop.ID); len(stored.OpeningHours) != 0 || !stored.IsOpenAt(time.Now()) {
+		t.Errorf("shop without hours = %+v, want always open", stored.OpeningHours)
+	}
+
+	invalid := []models.WeeklyHours{
+		{{Day: "funday", Opens: "11:00", Closes: "14:00"}},
+		{{Day: "monday", Opens: "25:00", Closes: "14:00"}},
+		{{Day: "monday", Opens: "11:00"}},
+	}
+	for _, hours := range invalid {
+		ctx, _ := shopRequest(sc.UpdateOpeningHours, http.MethodPut, owner, shop, models.UpdateOpeningHoursInput{Hours: hours})
+		if code := errorCode(ctx); code != "validation_failed" {
+			t.Errorf("UpdateOpeningHours(%+v) error = %q, want validation_failed", hours, code)
+		}
+	}
+
+	ctx, _ = shopRequest(sc.UpdateOpeningHours, http.MethodPut, createTestUser(t, db), shop, models.UpdateOpeningHoursInput{Hours: hours})
+	if code := errorCode(ctx); code != "shop_forbidden" {
+		t.Errorf("UpdateOpeningHours by another user error = %q, want shop_forbidden", code)
+	}
+}
+
+func TestUpdateOrdering(t *testing.T) {
+	db := testdb.Open(t, shopModels...)
+	sc := NewShopController(db)
+	owner := createTestUser(t, db)
+	shop, _ := createTestShop(t, db, owner)
+
+	paused := true
+	ctx, body := shopRequest(sc.UpdateOrdering, http.MethodPut, owner, shop, models.UpdateOrderingInput{Paused: &paused})
+	if ctx.Writer.Status() != http.StatusOK || !strings.Contains(string(body), `"ordering_paused":true`) {
+		t.Fatalf("UpdateOrdering = %d %s, want the paused shop", ctx.Writer.Status(), body)
+	}
+	if !reloadShop(t, db, shop.ID).OrderingPaused {
+		t.Fatal("pause was not stored")
+	}
+
+	// Pausing leaves the shop open; it only stops orders.
+	if !strings.Contains(string(body), `"is_open_now":true`) {
+		t.Errorf("paused shop is not open: %s", body)
+	}
+
+	paused = false
+	shopRequest(sc.UpdateOrdering, http.MethodPut, owner, shop, models.UpdateOrderingInput{Paused: &paused})
+	if reloadShop(t, db, shop.ID).OrderingPaused {
+		t.Error("resume was not stored")
+	}
+
+	ctx, _ = shopRequest(sc.UpdateOrdering, http.MethodPut, owner, shop, map[string]any{})
+	if code := errorCode(ctx); code != "validation_failed" {
+		t.Errorf("UpdateOrdering without paused error = %q, want validation_failed", code)
+	}
+}
+
+func TestShopClosures(t *testing.T) {
+	db := testdb.Open(t, shopModels...)
+	sc := NewShopController(db)
+	owner := createTestUser(t, db)
+	shop, _ := createTestShop(t, db, owner)
+	other, _ := createTestShop(t, db, owner)
+
+	ctx, body := shopRequest(sc.CreateClosure, http.MethodPost, owner, shop,
+		models.CreateShopClosureInput{StartsOn: "2030-12-31", Reason: "New Year's Eve"})
+	if ctx.Writer.Status() != http.StatusCreated || len(ctx.Errors) > 0 {
+		t.Fatalf("CreateClosure = %d %v, want 201", ctx.Writer.Status(), ctx.Errors)
+	}
+	var created struct{ Data models.ShopClosure }
+	if err := json.Unmarshal(body, &created); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if created.Data.EndsOn != "2030-12-31" {
+		t.Errorf("closure ends on %q, want its start day", created.Data.EndsOn)
+	}
+	if closures := reloadShop(t, db, shop.ID).Closures; len(closures) != 1 || closures[0].ID != created.Data.ID {
+		t.Errorf("shop closures = %+v, want the new closure", closures)
+	}
+
+	invalid := []struct {
+		input models.CreateShopClosureInput
+		want  string
+	}{
+		{models.CreateShopClosureInput{StartsOn: "2030-12-31", EndsOn: "2030-12-30"}, "invalid_closure"},
+		{models.CreateShopClosureInput{StartsOn: "31/12/2030"}, "validation_failed"},
+		{models.CreateShopClosureInput{StartsOn: "2030-12-31", Reason: strings.Repeat("x", 256)}, "validation_failed"},
+	}
+	for _, tt := range invalid {
+		ctx, _ := shopRequest(sc.CreateClosure, http.MethodPost, owner, shop, tt.input)
+		if code := errorCode(ctx); code != tt.want {
+			t.Errorf("CreateClosure(%s to %s) error = %q, want %q", tt.input.StartsOn, tt.input.EndsOn, code, tt.want)
+		}
+	}
+
+	closureParam := gin.Param{Key: "closureId", Value: created.Data.ID.String()}
+	ctx, _ = shopRequest(sc.DeleteClosure, http.MethodDelete, owner, other, nil, closureParam)
+	if code := errorCode(ctx); code != "closure_not_found" {
+		t.Errorf("DeleteClosure through another shop error = %q, want closure_not_found", code)
+	}
+	ctx, _ = shopRequest(sc.DeleteClosure, http.MethodDelete, owner, shop, nil, closureParam)
+	if ctx.Writer.Status() != http.StatusNoContent || len(ctx.Errors) > 0 {
+		t.Fatalf("DeleteClosure = %d %v, want 204", ctx.Writer.Status(), ctx.Errors)
+	}
+	if closures := reloadShop(t, db, shop.ID).Closures; len(closures) != 0 {
+		t.Errorf("closures after delete = %+v, want none", closures)
+	}
+}
+
+func TestUpdateShopTimeZone(t *testing.T) {
+	db := testdb.Open(t, shopModels...)
+	sc := NewShopController(db)
+	owner := createTestUser(t, db)
+	shop, _ := createTestShop(t, db, owner)
+
+	for _, zone := range []string{"Local", "Mars/Olympus"} {
+		ctx, _ := shopRequest(sc.UpdateShop, http.MethodPut, owner, shop, models.UpdateShopInput{TimeZone: zone})
+		if code := errorCode(ctx); code != "validation_failed" {
+			t.Errorf("UpdateShop(time_zone %q) error = %q, want validation_failed", zone, code)
+		}
+	}
+
+	ctx, _ := shopRequest(sc.UpdateShop, http.MethodPut, owner, shop, models.UpdateShopInput{TimeZone: "Asia/Tokyo"})
+	if len(ctx.Errors) > 0 {
+		t.Fatalf("UpdateShop(Asia/Tokyo): %v", ctx.Errors)
+	}
+	if zone := reloadShop(t, db, shop.ID).TimeZone; zone != "Asia/Tokyo" {
+		t.Errorf("time zone = %q, want Asia/Tokyo", zone)
+	}
+}
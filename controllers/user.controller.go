@@ -3,7 +3,9 @@ package controllers
 import (
 	"net/http"
 
+	"github.com/Llane00/ramen-backend/apperror"
 	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/utils"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
@@ -26,9 +28,65 @@ func (uc *UserController) GetMe(ctx *gin.Context) {
 		Photo:     currentUser.Photo,
 		Roles:     currentUser.Roles,
 		Provider:  currentUser.Provider,
+		Locale:    currentUser.Locale,
 		CreatedAt: currentUser.CreatedAt,
 		UpdatedAt: currentUser.UpdatedAt,
+
+		TwoFactorEnabled:        currentUser.TOTPEnabled,
+		NotificationPreferences: currentUser.NotificationPreferences(),
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": gin.H{"user": userResponse}})
 }
+
+// UpdateLocale sets the language the user's emails are written in.
+func (uc *UserController) UpdateLocale(ctx *gin.Context) {
+	currentUser := ctx.MustGet("currentUser").(models.User)
+	var payload *models.UpdateLocaleInput
+
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.Error(apperror.FromBinding(err))
+		return
+	}
+
+	locale := utils.NormalizeLocale(payload.Locale)
+	err := uc.DB.WithContext(ctx.Request.Context()).Model(&currentUser).UpdateColumn("locale", locale).Error
+	if err != nil {
+		ctx.Error(apperror.Internal(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": gin.H{"locale": locale}})
+}
+
+// UpdateNotificationPreferences opts the user in or out of order and payment
+// emails.
+func (uc *UserController) UpdateNotificationPreferences(ctx *gin.Context) {
+	currentUser := ctx.MustGet("currentUser").(models.User)
+	var payload *models.UpdateNotificationPreferencesInput
+
+	if err := ctx.ShouldBindJSON(&payload); err != nil {
+		ctx.Error(apperror.FromBinding(err))
+		return
+	}
+
+	updates := map[string]any{}
+	if payload.EmailOrderUpdates != nil {
+		updates["email_order_updates"] = *payload.EmailOrderUpdates
+		currentUser.EmailOrderUpdates = *payload.EmailOrderUpdates
+	}
+	if payload.EmailReceipts != nil {
+		updates["email_receipts"] = *payload.EmailReceipts
+		currentUser.EmailReceipts = *payload.EmailReceipts
+	}
+
+	if len(updates) > 0 {
+		err := uc.DB.WithContext(ctx.Request.Context()).Model(&currentUser).UpdateColumns(updates).Error
+		if err != nil {
+			ctx.Error(apperror.Internal(err))
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": currentUser.NotificationPreferences()})
+}
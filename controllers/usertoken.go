@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/utils"
+	"github.com/google/uuid"
+	"github.com/thanhpk/randstr"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Lifetimes of emailed tokens. Magic links are configured with
+// MAGIC_LINK_EXPIRED_IN.
+const (
+	emailVerificationTTL = 24 * time.Hour
+	passwordResetTTL     = 15 * time.Minute
+	emailChangeTTL       = time.Hour
+	reauthenticationTTL  = 15 * time.Minute
+)
+
+// issueUserToken stores userToken, which names the user and purpose, with a
+// fresh secret and returns the secret for emailing. Earlier unused tokens of
+// the same purpose stop working, so only the latest email is valid.
+func issueUserToken(db *gorm.DB, userToken models.UserToken, ttl time.Duration) (string, error) {
+	token := randstr.Hex(64)
+	now := time.Now()
+
+	userToken.TokenHash = utils.HashToken(token)
+	userToken.ExpiresAt = now.Add(ttl)
+	userToken.CreatedAt = now
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ? AND purpose = ? AND used_at IS NULL", userToken.UserID, userToken.Purpose).
+			Delete(&models.UserToken{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(&userToken).Error
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// recentUserToken reports whether a token of purpose was issued to the user
+// since the given time, to throttle repeated emails.
+func recentUserToken(db *gorm.DB, userID uuid.UUID, purpose string, since time.Time) (bool, error) {
+	var count int64
+	err := db.Model(&models.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND created_at > ?", userID, purpose, since).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// consumeUserToken spends an unused, unexpired token of purpose and returns
+// it, or nil if there is none. Tokens are looked up by their hash, so the
+// lookup time says nothing about stored tokens, and checking and spending
+// happen in one statement so a token works exactly once.
+func consumeUserToken(db *gorm.DB, purpose string, token string) (*models.UserToken, error) {
+	now := time.Now()
+
+	var userToken models.UserToken
+	result := db.Model(&userToken).
+		Clauses(clause.Returning{}).
+		Where("token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", utils.HashToken(token), purpose, now).
+		Update("used_at", now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &userToken, nil
+}
+
+// invalidateUserTokens spends every outstanding token of the user, e.g.
+// after the password changed.
+func invalidateUserTokens(db *gorm.DB, userID uuid.UUID) error {
+	return db.Model(&models.UserToken{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", time.Now()).Error
+}
@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/testdb"
+	"gorm.io/gorm"
+)
+
+func newTestUserTokenDB(t *testing.T) (*gorm.DB, models.User) {
+	t.Helper()
+	db := testdb.Open(t, &models.User{}, &models.UserToken{})
+	return db, createTestUser(t, db)
+}
+
+func TestUserTokenIsSingleUse(t *testing.T) {
+	db, user := newTestUserTokenDB(t)
+
+	token, err := issueUserToken(db, models.UserToken{UserID: user.ID, Purpose: models.TokenPasswordReset}, time.Hour)
+	if err != nil {
+		t.Fatalf("issueUserToken: %v", err)
+	}
+
+	// A token only works for its own purpose.
+	if got, err := consumeUserToken(db, models.TokenMagicLink, token); err != nil || got != nil {
+		t.Fatalf("consume for another purpose = %v, %v, want nil", got, err)
+	}
+
+	got, err := consumeUserToken(db, models.TokenPasswordReset, token)
+	if err != nil || got == nil || got.UserID != user.ID {
+		t.Fatalf("first consume = %v, %v, want the token of %s", got, err, user.ID)
+	}
+	if got, err := consumeUserToken(db, models.TokenPasswordReset, token); err != nil || got != nil {
+		t.Fatalf("second consume = %v, %v, want nil", got, err)
+	}
+}
+
+func TestUserTokenExpires(t *testing.T) {
+	db, user := newTestUserTokenDB(t)
+
+	token, err := issueUserToken(db, models.UserToken{UserID: user.ID, Purpose: models.TokenPasswordReset}, time.Hour)
+	if err != nil {
+		t.Fatalf("issueUserToken: %v", err)
+	}
+	if err := db.Model(&models.UserToken{}).Where("user_id = ?", user.ID).Update("expires_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatalf("expire token: %v", err)
+	}
+	if got, err := consumeUserToken(db, models.TokenPasswordReset, token); err != nil || got != nil {
+		t.Fatalf("consume expired = %v, %v, want nil", got, err)
+	}
+}
+
+func TestIssueUserTokenReplacesEarlierTokens(t *testing.T) {
+	db, user := newTestUserTokenDB(t)
+	other := createTestUser(t, db)
+
+	first, _ := issueUserToken(db, models.UserToken{UserID: user.ID, Purpose: models.TokenMagicLink}, time.Hour)
+	verification, _ := issueUserToken(db, models.UserToken{UserID: user.ID, Purpose: models.TokenEmailVerification}, time.Hour)
+	otherLink, _ := issueUserToken(db, models.UserToken{UserID: other.ID, Purpose: models.TokenMagicLink}, time.Hour)
+	second, err := issueUserToken(db, models.UserToken{UserID: user.ID, Purpose: models.TokenMagicLink}, time.Hour)
+	if err != nil {
+		t.Fatalf("issueUserToken: %v", err)
+	}
+
+	// Only the latest token of a purpose works; other purposes and users
+	// keep theirs.
+	if got, _ := consumeUserToken(db, models.TokenMagicLink, first); got != nil {
+		t.Error("an earlier magic link still works")
+	}
+	for name, tt := range map[string]struct{ purpose, token string }{
+		"latest magic link":  {models.TokenMagicLink, second},
+		"email verification": {models.TokenEmailVerification, verification},
+		"other user's link":  {models.TokenMagicLink, otherLink},
+	} {
+		if got, err := consumeUserToken(db, tt.purpose, tt.token); err != nil || got == nil {
+			t.Errorf("%s = %v, %v, want usable", name, got, err)
+		}
+	}
+}
+
+func TestInvalidateUserTokens(t *testing.T) {
+	db, user := newTestUserTokenDB(t)
+
+	reset, _ := issueUserToken(db, models.UserToken{UserID: user.ID, Purpose: models.TokenPasswordReset}, time.Hour)
+	link, _ := issueUserToken(db, models.UserToken{UserID: user.ID, Purpose: models.TokenMagicLink}, time.Hour)
+	if err := invalidateUserTokens(db, user.ID); err != nil {
+		t.Fatalf("invalidateUserTokens: %v", err)
+	}
+
+	for purpose, token := range map[string]string{models.TokenPasswordReset: reset, models.TokenMagicLink: link} {
+		if got, _ := consumeUserToken(db, purpose, token); got != nil {
+			t.Errorf("%s token works after invalidation", purpose)
+		}
+	}
+}
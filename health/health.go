@@ -0,0 +1,107 @@
+package health
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var draining atomic.Bool
+
+// SetDraining marks the process as shutting down so readiness checks fail
+// and load balancers stop routing new traffic to it.
+func SetDraining(value bool) {
+	draining.Store(value)
+}
+
+func IsDraining() bool {
+	return draining.Load()
+}
+
+// Worker tracks the liveness of a background worker. Workers call Beat on
+// every loop iteration; a worker that stops beating for longer than its
+// MaxSilence is reported as unhealthy.
+type Worker struct {
+	mu         sync.Mutex
+	name       string
+	maxSilence time.Duration
+	running    bool
+	lastBeat   time.Time
+	lastError  string
+}
+
+type WorkerStatus struct {
+	Name      string    `json:"name"`
+	Running   bool      `json:"running"`
+	Healthy   bool      `json:"healthy"`
+	LastBeat  time.Time `json:"last_beat"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+var (
+	workersMu sync.Mutex
+	workers   = map[string]*Worker{}
+)
+
+// RegisterWorker registers (or returns the already registered) worker with
+// the given name.
+func RegisterWorker(name string, maxSilence time.Duration) *Worker {
+	workersMu.Lock()
+	defer workersMu.Unlock()
+
+	if w, ok := workers[name]; ok {
+		return w
+	}
+	w := &Worker{name: name, maxSilence: maxSilence}
+	workers[name] = w
+	return w
+}
+
+// Beat records that the worker is alive. A nil err clears the last error.
+func (w *Worker) Beat(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.running = true
+	w.lastBeat = time.Now()
+	if err != nil {
+		w.lastError = err.Error()
+	} else {
+		w.lastError = ""
+	}
+}
+
+// Stopped records that the worker loop has exited.
+func (w *Worker) Stopped() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.running = false
+}
+
+func (w *Worker) status(now time.Time) WorkerStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return WorkerStatus{
+		Name:      w.name,
+		Running:   w.running,
+		Healthy:   w.running && now.Sub(w.lastBeat) <= w.maxSilence,
+		LastBeat:  w.lastBeat,
+		LastError: w.lastError,
+	}
+}
+
+// Workers returns the status of every registered worker sorted by name.
+func Workers() []WorkerStatus {
+	workersMu.Lock()
+	defer workersMu.Unlock()
+
+	now := time.Now()
+	statuses := make([]WorkerStatus, 0, len(workers))
+	for _, w := range workers {
+		statuses = append(statuses, w.status(now))
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
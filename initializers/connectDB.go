@@ -2,15 +2,16 @@ package initializers
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
 var DB *gorm.DB
 
-func ConnectDB(config *Config, env string) {
+func ConnectDB(config *Config, env string) error {
 	var err error
 	var dsn string
 
@@ -22,12 +23,16 @@ func ConnectDB(config *Config, env string) {
 		dsn = fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=Asia/Shanghai",
 			config.DBHost, config.DBUserName, config.DBUserPassword, config.DBName, config.DBPort)
 	default:
-		log.Fatal("Invalid environment specified")
+		return fmt.Errorf("invalid environment specified: %q", env)
 	}
 
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: newGormLogger(logger.Warn)})
 	if err != nil {
-		log.Fatalf("Failed to connect to the %s Database: %v", env, err)
+		return fmt.Errorf("failed to connect to the %s database: %w", env, err)
 	}
-	fmt.Printf("✅ Connected Successfully to the %s Database\n", env)
+	if err := DB.Use(newGormTracing()); err != nil {
+		return fmt.Errorf("failed to register database tracing: %w", err)
+	}
+	slog.Info("connected to database", "env", env)
+	return nil
 }
@@ -0,0 +1,9 @@
+package initializers
+
+// OrderCurrency is the ISO 4217 code of the currency prices are in.
+func (c *Config) OrderCurrency() string {
+	if c.Currency != "" {
+		return c.Currency
+	}
+	return "USD"
+}
@@ -0,0 +1,27 @@
+package initializers
+
+import (
+	"context"
+
+	"github.com/Llane00/ramen-backend/keyset"
+)
+
+// Keys signs and verifies JWTs. It must be loaded after ConnectDB.
+var Keys *keyset.KeySet
+
+func LoadKeys(ctx context.Context, config *Config) error {
+	secret, err := config.SigningKeySecret()
+	if err != nil {
+		return err
+	}
+	Keys, err = keyset.New(ctx, DB, secret,
+		config.AccessTokenPrivateKey, config.AccessTokenPublicKey,
+		config.RefreshTokenPrivateKey, config.RefreshTokenPublicKey)
+	return err
+}
+
+// SigningKeySecret decodes SIGNING_KEY_SECRET, the AES-256 key the private
+// signing keys are encrypted with in the database.
+func (c *Config) SigningKeySecret() ([]byte, error) {
+	return decodeSecretKey("SIGNING_KEY_SECRET", c.SigningKeySecretBase64)
+}
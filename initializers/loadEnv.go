@@ -14,7 +14,29 @@ type Config struct {
 	DBPort         string `mapstructure:"POSTGRES_PORT"`
 	ServerPort     string `mapstructure:"PORT"`
 
+	ShutdownDrainPeriod time.Duration `mapstructure:"SHUTDOWN_DRAIN_PERIOD"`
+	ShutdownTimeout     time.Duration `mapstructure:"SHUTDOWN_TIMEOUT"`
+
 	ClientOrigin string `mapstructure:"CLIENT_ORIGIN"`
+	// Currency is the ISO 4217 code prices are charged in, USD by default.
+	Currency string `mapstructure:"CURRENCY"`
+	// TrustedProxies lists the proxies whose X-Forwarded-For header is honoured
+	// when resolving the client IP. Empty means the peer address is used as is.
+	TrustedProxies []string `mapstructure:"TRUSTED_PROXIES"`
+
+	LogLevel string `mapstructure:"LOG_LEVEL"`
+
+	MetricsEnabled    bool     `mapstructure:"METRICS_ENABLED"`
+	MetricsToken      string   `mapstructure:"METRICS_TOKEN"`
+	MetricsAllowedIPs []string `mapstructure:"METRICS_ALLOWED_IPS"`
+
+	// RateLimitStore selects where rate limit buckets live: "memory" (default,
+	// single instance) or "postgres" (shared by every instance).
+	RateLimitStore string `mapstructure:"RATE_LIMIT_STORE"`
+
+	ServiceName    string `mapstructure:"OTEL_SERVICE_NAME"`
+	TracesExporter string `mapstructure:"OTEL_TRACES_EXPORTER"`
+	OTLPEndpoint   string `mapstructure:"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"`
 
 	AccessTokenPrivateKey  string        `mapstructure:"ACCESS_TOKEN_PRIVATE_KEY"`
 	AccessTokenPublicKey   string        `mapstructure:"ACCESS_TOKEN_PUBLIC_KEY"`
@@ -24,13 +46,77 @@ type Config struct {
 	RefreshTokenExpiresIn  time.Duration `mapstructure:"REFRESH_TOKEN_EXPIRED_IN"`
 	AccessTokenMaxAge      int           `mapstructure:"ACCESS_TOKEN_MAXAGE"`
 	RefreshTokenMaxAge     int           `mapstructure:"REFRESH_TOKEN_MAXAGE"`
+	// The private halves of the signing keys in the database are encrypted
+	// with SIGNING_KEY_SECRET, a base64 encoded 32 byte key
+	// (openssl rand -base64 32).
+	SigningKeySecretBase64 string `mapstructure:"SIGNING_KEY_SECRET"`
+
+	// Account lockout. An account is locked for LOGIN_LOCKOUT_DURATION after
+	// LOGIN_MAX_FAILURES consecutive wrong passwords, and an IP is blocked
+	// after LOGIN_IP_MAX_FAILURES failures within LOGIN_FAILURE_WINDOW. Each
+	// failure beyond the second delays the response by LOGIN_DELAY_BASE,
+	// doubling per failure.
+	LoginMaxFailures     int           `mapstructure:"LOGIN_MAX_FAILURES"`
+	LoginLockoutDuration time.Duration `mapstructure:"LOGIN_LOCKOUT_DURATION"`
+	LoginIPMaxFailures   int           `mapstructure:"LOGIN_IP_MAX_FAILURES"`
+	LoginFailureWindow   time.Duration `mapstructure:"LOGIN_FAILURE_WINDOW"`
+	LoginDelayBase       time.Duration `mapstructure:"LOGIN_DELAY_BASE"`
 
-	EmailFrom    string `mapstructure:"EMAIL_FROM"`
-	SMTPApiToken string `mapstructure:"SMTP_API_TOKEN"`
+	// Two-factor authentication. Users holding one of MFA_REQUIRED_ROLES must
+	// enroll before they can use the API. MFA_TOKEN_EXPIRED_IN bounds the time
+	// between the password and the second factor. TOTP secrets are stored
+	// encrypted with MFA_SECRET_KEY, a base64 encoded 32 byte key
+	// (openssl rand -base64 32).
+	MFAIssuer          string        `mapstructure:"MFA_ISSUER"`
+	MFARequiredRoles   []string      `mapstructure:"MFA_REQUIRED_ROLES"`
+	MFATokenExpiresIn  time.Duration `mapstructure:"MFA_TOKEN_EXPIRED_IN"`
+	MFASecretKeyBase64 string        `mapstructure:"MFA_SECRET_KEY"`
 
+	// MagicLinkExpiresIn bounds the lifetime of passwordless sign-in links.
+	MagicLinkExpiresIn time.Duration `mapstructure:"MAGIC_LINK_EXPIRED_IN"`
+
+	// Email delivery. EMAIL_TRANSPORT selects "mailtrap" (default, the
+	// Mailtrap API with SMTP_API_TOKEN), "smtp" or "maildir" (written to
+	// EMAIL_MAILDIR for local development). MAILTRAP_API_URL may point at a
+	// sandbox inbox.
+	EmailTransport string `mapstructure:"EMAIL_TRANSPORT"`
+	EmailFrom      string `mapstructure:"EMAIL_FROM"`
+	EmailFromName  string `mapstructure:"EMAIL_FROM_NAME"`
+	SMTPApiToken   string `mapstructure:"SMTP_API_TOKEN"`
+	MailtrapAPIURL string `mapstructure:"MAILTRAP_API_URL"`
+	SMTPHost       string `mapstructure:"SMTP_HOST"`
+	SMTPPort       string `mapstructure:"SMTP_PORT"`
+	SMTPUsername   string `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword   string `mapstructure:"SMTP_PASSWORD"`
+	EmailMaildir   string `mapstructure:"EMAIL_MAILDIR"`
+
+	// Emails are queued in the outbox and sent by a background worker that
+	// polls every EMAIL_OUTBOX_INTERVAL. An email is given up on after
+	// EMAIL_MAX_ATTEMPTS failed sends.
+	EmailOutboxInterval time.Duration `mapstructure:"EMAIL_OUTBOX_INTERVAL"`
+	EmailMaxAttempts    int           `mapstructure:"EMAIL_MAX_ATTEMPTS"`
+
+	// OAuth sign-in providers. Each provider is enabled when its client ID
+	// is set. The redirect URL is /api/auth/oauth/<provider> on this API;
+	// the generic OpenID Connect provider is named by OIDC_PROVIDER_NAME.
 	GoogleClientID         string `mapstructure:"GOOGLE_OAUTH_CLIENT_ID"`
 	GoogleClientSecret     string `mapstructure:"GOOGLE_OAUTH_CLIENT_SECRET"`
 	GoogleOAuthRedirectUrl string `mapstructure:"GOOGLE_OAUTH_REDIRECT_URL"`
+
+	GitHubClientID         string `mapstructure:"GITHUB_OAUTH_CLIENT_ID"`
+	GitHubClientSecret     string `mapstructure:"GITHUB_OAUTH_CLIENT_SECRET"`
+	GitHubOAuthRedirectUrl string `mapstructure:"GITHUB_OAUTH_REDIRECT_URL"`
+
+	OIDCProviderName string   `mapstructure:"OIDC_PROVIDER_NAME"`
+	OIDCIssuerURL    string   `mapstructure:"OIDC_ISSUER_URL"`
+	OIDCClientID     string   `mapstructure:"OIDC_CLIENT_ID"`
+	OIDCClientSecret string   `mapstructure:"OIDC_CLIENT_SECRET"`
+	OIDCRedirectUrl  string   `mapstructure:"OIDC_REDIRECT_URL"`
+	OIDCScopes       []string `mapstructure:"OIDC_SCOPES"`
+
+	// OAuthStateExpiresIn bounds the time a user may spend on the provider's
+	// consent page.
+	OAuthStateExpiresIn time.Duration `mapstructure:"OAUTH_STATE_EXPIRED_IN"`
 }
 
 func LoadConfig(path string) (config Config, err error) {
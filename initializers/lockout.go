@@ -0,0 +1,42 @@
+package initializers
+
+import "time"
+
+// LoginPolicy holds the account lockout settings with defaults applied.
+type LoginPolicy struct {
+	MaxFailures     int
+	LockoutDuration time.Duration
+	IPMaxFailures   int
+	FailureWindow   time.Duration
+	DelayBase       time.Duration
+	// MaxDelay caps the progressive delay so a response never stalls for long.
+	MaxDelay time.Duration
+}
+
+// LoginPolicy returns the configured lockout settings.
+func (c *Config) LoginPolicy() LoginPolicy {
+	p := LoginPolicy{
+		MaxFailures:     c.LoginMaxFailures,
+		LockoutDuration: c.LoginLockoutDuration,
+		IPMaxFailures:   c.LoginIPMaxFailures,
+		FailureWindow:   c.LoginFailureWindow,
+		DelayBase:       c.LoginDelayBase,
+		MaxDelay:        8 * time.Second,
+	}
+	if p.MaxFailures == 0 {
+		p.MaxFailures = 5
+	}
+	if p.LockoutDuration == 0 {
+		p.LockoutDuration = 15 * time.Minute
+	}
+	if p.IPMaxFailures == 0 {
+		p.IPMaxFailures = 20
+	}
+	if p.FailureWindow == 0 {
+		p.FailureWindow = 15 * time.Minute
+	}
+	if p.DelayBase == 0 {
+		p.DelayBase = 250 * time.Millisecond
+	}
+	return p
+}
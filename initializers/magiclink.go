@@ -0,0 +1,13 @@
+package initializers
+
+import "time"
+
+const defaultMagicLinkTTL = 15 * time.Minute
+
+// MagicLinkTTL is how long an emailed sign-in link stays valid.
+func (c *Config) MagicLinkTTL() time.Duration {
+	if c.MagicLinkExpiresIn > 0 {
+		return c.MagicLinkExpiresIn
+	}
+	return defaultMagicLinkTTL
+}
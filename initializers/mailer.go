@@ -0,0 +1,48 @@
+package initializers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Llane00/ramen-backend/mailer"
+)
+
+const (
+	defaultEmailFromName = "Ramen"
+	defaultSMTPPort      = "587"
+	defaultEmailMaildir  = "tmp/mail"
+)
+
+// Mailer returns the email transport selected by EMAIL_TRANSPORT. client
+// makes the requests of HTTP based transports.
+func (c *Config) Mailer(client *http.Client) (mailer.Mailer, error) {
+	switch c.EmailTransport {
+	case "", "mailtrap":
+		return &mailer.Mailtrap{URL: c.MailtrapAPIURL, Token: c.SMTPApiToken, Client: client}, nil
+	case "smtp":
+		if c.SMTPHost == "" {
+			return nil, fmt.Errorf("SMTP_HOST is required for the smtp email transport")
+		}
+		port := c.SMTPPort
+		if port == "" {
+			port = defaultSMTPPort
+		}
+		return &mailer.SMTP{Host: c.SMTPHost, Port: port, Username: c.SMTPUsername, Password: c.SMTPPassword}, nil
+	case "maildir":
+		dir := c.EmailMaildir
+		if dir == "" {
+			dir = defaultEmailMaildir
+		}
+		return &mailer.Maildir{Dir: dir}, nil
+	default:
+		return nil, fmt.Errorf("unknown email transport %q", c.EmailTransport)
+	}
+}
+
+// EmailSenderName is the display name emails are sent from.
+func (c *Config) EmailSenderName() string {
+	if c.EmailFromName != "" {
+		return c.EmailFromName
+	}
+	return defaultEmailFromName
+}
@@ -0,0 +1,44 @@
+package initializers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"time"
+)
+
+const (
+	defaultMFAIssuer   = "Ramen"
+	defaultMFATokenTTL = 5 * time.Minute
+)
+
+// MFAIssuerName is the issuer shown next to the account in authenticator apps.
+func (c *Config) MFAIssuerName() string {
+	if c.MFAIssuer != "" {
+		return c.MFAIssuer
+	}
+	return defaultMFAIssuer
+}
+
+// MFATokenTTL is how long a user has to enter the second factor after the
+// password was accepted.
+func (c *Config) MFATokenTTL() time.Duration {
+	if c.MFATokenExpiresIn > 0 {
+		return c.MFATokenExpiresIn
+	}
+	return defaultMFATokenTTL
+}
+
+// MFASecretKey decodes MFA_SECRET_KEY, the AES-256 key TOTP secrets are
+// encrypted with at rest.
+func (c *Config) MFASecretKey() ([]byte, error) {
+	return decodeSecretKey("MFA_SECRET_KEY", c.MFASecretKeyBase64)
+}
+
+// decodeSecretKey decodes the base64 encoded AES-256 key in the setting name.
+func decodeSecretKey(name, value string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("%s must be a base64 encoded 32 byte key", name)
+	}
+	return key, nil
+}
@@ -0,0 +1,61 @@
+package initializers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Llane00/ramen-backend/oauth"
+)
+
+const (
+	defaultOIDCProviderName = "oidc"
+	defaultOAuthStateTTL    = 10 * time.Minute
+)
+
+// OAuthProviders returns the sign-in providers that have credentials
+// configured. client makes the requests to the providers.
+func (c *Config) OAuthProviders(client *http.Client) oauth.Registry {
+	providers := oauth.Registry{}
+
+	if c.GoogleClientID != "" {
+		providers.Add(oauth.NewGoogle(oauth.Client{
+			ID:          c.GoogleClientID,
+			Secret:      c.GoogleClientSecret,
+			RedirectURL: c.GoogleOAuthRedirectUrl,
+			HTTPClient:  client,
+		}))
+	}
+
+	if c.GitHubClientID != "" {
+		providers.Add(oauth.NewGitHub(oauth.Client{
+			ID:          c.GitHubClientID,
+			Secret:      c.GitHubClientSecret,
+			RedirectURL: c.GitHubOAuthRedirectUrl,
+			HTTPClient:  client,
+		}))
+	}
+
+	if c.OIDCClientID != "" && c.OIDCIssuerURL != "" {
+		name := c.OIDCProviderName
+		if name == "" {
+			name = defaultOIDCProviderName
+		}
+		providers.Add(oauth.NewOIDC(name, c.OIDCIssuerURL, oauth.Client{
+			ID:          c.OIDCClientID,
+			Secret:      c.OIDCClientSecret,
+			RedirectURL: c.OIDCRedirectUrl,
+			Scopes:      c.OIDCScopes,
+			HTTPClient:  client,
+		}))
+	}
+
+	return providers
+}
+
+// OAuthStateTTL is how long a sign-in started with a provider stays valid.
+func (c *Config) OAuthStateTTL() time.Duration {
+	if c.OAuthStateExpiresIn > 0 {
+		return c.OAuthStateExpiresIn
+	}
+	return defaultOAuthStateTTL
+}
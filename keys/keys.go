@@ -0,0 +1,179 @@
+// Command keys manages the JWT signing keys.
+//
+//	go run keys/keys.go list
+//	go run keys/keys.go generate access|refresh
+//	go run keys/keys.go promote <kid>
+//	go run keys/keys.go retire <kid>
+//	go run keys/keys.go prune
+//
+// A rotation is generate, wait until every verifier has refreshed its copy
+// of /.well-known/jwks.json (at least the JWKS cache time plus the server
+// reload interval), then promote. Promoting retires the previous active key
+// of the same use; it keeps verifying until prune removes it once every token
+// it signed has expired.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Llane00/ramen-backend/initializers"
+	"github.com/Llane00/ramen-backend/keyset"
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/utils"
+	"gorm.io/gorm"
+)
+
+var config initializers.Config
+
+func init() {
+	env := os.Getenv("GO_ENV")
+	if env == "" {
+		env = "development" // default env
+	}
+
+	var err error
+	config, err = initializers.LoadConfig(".")
+	utils.InitLogger(env, config.LogLevel)
+	if err != nil {
+		slog.Error("could not load environment variables", "error", err)
+		os.Exit(1)
+	}
+
+	if err := initializers.ConnectDB(&config, env); err != nil {
+		slog.Error("could not connect to database", "error", err)
+		os.Exit(1)
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	var err error
+	switch cmd, args := os.Args[1], os.Args[2:]; {
+	case cmd == "list" && len(args) == 0:
+		err = list()
+	case cmd == "generate" && len(args) == 1:
+		err = generate(keyset.Use(args[0]))
+	case cmd == "promote" && len(args) == 1:
+		err = promote(args[0])
+	case cmd == "retire" && len(args) == 1:
+		err = retire(args[0])
+	case cmd == "prune" && len(args) == 0:
+		err = prune()
+	default:
+		usage()
+	}
+
+	if err != nil {
+		slog.Error("keys: "+os.Args[1]+" failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: keys list | generate access|refresh | promote <kid> | retire <kid> | prune")
+	os.Exit(2)
+}
+
+func list() error {
+	var keys []models.SigningKey
+	if err := initializers.DB.Order("use, created_at").Find(&keys).Error; err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KID\tUSE\tSTATUS\tCREATED\tACTIVATED\tRETIRED")
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", k.ID, k.Use, k.Status, k.CreatedAt.Format(time.RFC3339), formatTime(k.ActivatedAt), formatTime(k.RetiredAt))
+	}
+	return w.Flush()
+}
+
+func generate(use keyset.Use) error {
+	sealKey, err := config.SigningKeySecret()
+	if err != nil {
+		return err
+	}
+	key, err := keyset.Generate(use, sealKey)
+	if err != nil {
+		return err
+	}
+	if err := initializers.DB.Create(key).Error; err != nil {
+		return err
+	}
+	slog.Info("generated pending key; promote it once verifiers have picked it up", "kid", key.ID, "use", key.Use)
+	return nil
+}
+
+// promote makes kid the signing key of its use and retires the previous one.
+func promote(kid string) error {
+	return initializers.DB.Transaction(func(tx *gorm.DB) error {
+		var key models.SigningKey
+		if err := tx.First(&key, "id = ?", kid).Error; err != nil {
+			return err
+		}
+		if key.Status == models.SigningKeyActive {
+			return errors.New("key is already active")
+		}
+
+		now := time.Now()
+		err := tx.Model(&models.SigningKey{}).
+			Where("use = ? AND status = ?", key.Use, models.SigningKeyActive).
+			Updates(map[string]any{"status": models.SigningKeyRetired, "retired_at": now}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&key).Updates(map[string]any{"status": models.SigningKeyActive, "activated_at": now, "retired_at": nil}).Error
+		if err != nil {
+			return err
+		}
+		slog.Info("promoted key", "kid", key.ID, "use", key.Use)
+		return nil
+	})
+}
+
+func retire(kid string) error {
+	result := initializers.DB.Model(&models.SigningKey{}).
+		Where("id = ? AND status <> ?", kid, models.SigningKeyRetired).
+		Updates(map[string]any{"status": models.SigningKeyRetired, "retired_at": time.Now()})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("no unretired key %q", kid)
+	}
+	slog.Info("retired key", "kid", kid)
+	return nil
+}
+
+// prune deletes retired keys whose tokens have all expired.
+func prune() error {
+	lifetimes := map[keyset.Use]time.Duration{
+		keyset.Access:  config.AccessTokenExpiresIn,
+		keyset.Refresh: config.RefreshTokenExpiresIn,
+	}
+	for use, lifetime := range lifetimes {
+		result := initializers.DB.
+			Where("use = ? AND status = ? AND retired_at < ?", use, models.SigningKeyRetired, time.Now().Add(-lifetime)).
+			Delete(&models.SigningKey{})
+		if result.Error != nil {
+			return result.Error
+		}
+		slog.Info("pruned retired keys", "use", use, "deleted", result.RowsAffected)
+	}
+	return nil
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,50 @@
+package keyset
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/secret"
+	"github.com/thanhpk/randstr"
+)
+
+const keyBits = 2048
+
+// Generate creates a new pending signing key for use with its private key
+// sealed with sealKey. The key ID embeds the creation date so operators can
+// tell keys apart at a glance.
+func Generate(use Use, sealKey []byte) (*models.SigningKey, error) {
+	if use != Access && use != Refresh {
+		return nil, fmt.Errorf("keyset: unknown key use %q", use)
+	}
+
+	private, err := rsa.GenerateKey(rand.Reader, keyBits)
+	if err != nil {
+		return nil, err
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(&private.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	privatePEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(private)})
+	sealed, err := secret.Seal(sealKey, string(privatePEM))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return &models.SigningKey{
+		ID:         fmt.Sprintf("%s-%s-%s", use, now.Format("20060102"), randstr.Hex(8)),
+		Use:        string(use),
+		Status:     models.SigningKeyPending,
+		PrivateKey: sealed,
+		PublicKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})),
+		CreatedAt:  now,
+	}, nil
+}
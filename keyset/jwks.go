@@ -0,0 +1,38 @@
+package keyset
+
+import (
+	"encoding/base64"
+	"math/big"
+)
+
+// JWK is the public part of an RSA signing key as defined by RFC 7517.
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys that verify access tokens. Refresh tokens are
+// only ever verified by this service and are not published.
+func (ks *KeySet) JWKS() JWKS {
+	keys := ks.PublicKeys(Access)
+	set := JWKS{Keys: make([]JWK, len(keys))}
+	for i, key := range keys {
+		set.Keys[i] = JWK{
+			Kty: "RSA",
+			Use: "sig",
+			Alg: "RS256",
+			Kid: key.ID,
+			N:   base64.RawURLEncoding.EncodeToString(key.Public.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.Public.E)).Bytes()),
+		}
+	}
+	return set
+}
@@ -0,0 +1,197 @@
+// Package keyset holds the RSA keys that sign and verify JWTs.
+//
+// Keys live in the signing_keys table, their private halves encrypted with
+// SIGNING_KEY_SECRET, and are managed with the keys command
+// (go run keys/keys.go). Every token carries the ID of its signing key in the
+// "kid" header, so several keys can verify at once and rotating the signing
+// key does not invalidate sessions. The keys from ACCESS_TOKEN_*_KEY and
+// REFRESH_TOKEN_*_KEY remain as a fallback: they sign while no database key
+// is active and verify tokens issued before kid headers were introduced.
+package keyset
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/secret"
+	"github.com/golang-jwt/jwt"
+	"gorm.io/gorm"
+)
+
+type Use string
+
+const (
+	Access  Use = "access"
+	Refresh Use = "refresh"
+)
+
+// legacyKeyID identifies the configured fallback keys. Tokens they sign
+// carry no kid header, as before key rotation existed.
+const legacyKeyID = ""
+
+var ErrUnknownKey = errors.New("keyset: unknown key id")
+
+type Key struct {
+	ID      string
+	Use     Use
+	Status  string
+	Private *rsa.PrivateKey
+	Public  *rsa.PublicKey
+}
+
+// KeySet is safe for concurrent use; Reload swaps the keys atomically.
+type KeySet struct {
+	db      *gorm.DB
+	sealKey []byte
+	legacy  map[Use]*Key
+
+	mu      sync.RWMutex
+	signing map[Use]*Key
+	verify  map[Use]map[string]*Key
+}
+
+// New builds a key set from the database, whose private keys are sealed with
+// sealKey, and the configured fallback keys, given base64 encoded PEM like the
+// rest of the configuration. Empty fallback keys are skipped.
+func New(ctx context.Context, db *gorm.DB, sealKey []byte, accessPrivate, accessPublic, refreshPrivate, refreshPublic string) (*KeySet, error) {
+	ks := &KeySet{db: db, sealKey: sealKey, legacy: map[Use]*Key{}}
+
+	for use, pair := range map[Use][2]string{Access: {accessPrivate, accessPublic}, Refresh: {refreshPrivate, refreshPublic}} {
+		if pair[0] == "" || pair[1] == "" {
+			continue
+		}
+		key, err := parseBase64Pair(pair[0], pair[1])
+		if err != nil {
+			return nil, fmt.Errorf("keyset: %s key from config: %w", use, err)
+		}
+		key.ID, key.Use, key.Status = legacyKeyID, use, models.SigningKeyActive
+		ks.legacy[use] = key
+	}
+
+	if err := ks.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// Reload reads the keys from the database.
+func (ks *KeySet) Reload(ctx context.Context) error {
+	var rows []models.SigningKey
+	if err := ks.db.WithContext(ctx).Order("activated_at DESC NULLS LAST, created_at DESC").Find(&rows).Error; err != nil {
+		return fmt.Errorf("keyset: load keys: %w", err)
+	}
+
+	signing := map[Use]*Key{}
+	verify := map[Use]map[string]*Key{Access: {}, Refresh: {}}
+	for use, key := range ks.legacy {
+		signing[use] = key
+		verify[use][legacyKeyID] = key
+	}
+
+	dbSigning := map[Use]bool{}
+	for _, row := range rows {
+		key, err := openKeyPair(ks.sealKey, row.PrivateKey, row.PublicKey)
+		if err != nil {
+			return fmt.Errorf("keyset: key %s: %w", row.ID, err)
+		}
+		key.ID, key.Use, key.Status = row.ID, Use(row.Use), row.Status
+
+		if verify[key.Use] == nil {
+			continue
+		}
+		verify[key.Use][key.ID] = key
+		// Rows are ordered newest activation first.
+		if key.Status == models.SigningKeyActive && !dbSigning[key.Use] {
+			signing[key.Use] = key
+			dbSigning[key.Use] = true
+		}
+	}
+
+	ks.mu.Lock()
+	ks.signing, ks.verify = signing, verify
+	ks.mu.Unlock()
+	return nil
+}
+
+// SigningKey returns the key that signs new tokens of use.
+func (ks *KeySet) SigningKey(use Use) (*Key, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	key, ok := ks.signing[use]
+	if !ok {
+		return nil, fmt.Errorf("keyset: no active %s signing key", use)
+	}
+	return key, nil
+}
+
+// VerificationKey returns the public key for kid. An empty kid selects the
+// configured fallback key.
+func (ks *KeySet) VerificationKey(use Use, kid string) (*rsa.PublicKey, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	key, ok := ks.verify[use][kid]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, kid)
+	}
+	return key.Public, nil
+}
+
+// PublicKeys returns the keys of use that verify tokens, ordered by ID. The
+// fallback key is left out: it has no kid to publish it under.
+func (ks *KeySet) PublicKeys(use Use) []*Key {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	keys := make([]*Key, 0, len(ks.verify[use]))
+	for kid, key := range ks.verify[use] {
+		if kid != legacyKeyID {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys
+}
+
+func parseBase64Pair(private, public string) (*Key, error) {
+	privatePEM, err := base64.StdEncoding.DecodeString(private)
+	if err != nil {
+		return nil, fmt.Errorf("decode private key: %w", err)
+	}
+	publicPEM, err := base64.StdEncoding.DecodeString(public)
+	if err != nil {
+		return nil, fmt.Errorf("decode public key: %w", err)
+	}
+	return parsePEMPair(string(privatePEM), string(publicPEM))
+}
+
+// openKeyPair decrypts a private key sealed by Generate and parses the pair.
+func openKeyPair(sealKey []byte, sealed, public string) (*Key, error) {
+	if !secret.IsSealed(sealed) {
+		return nil, errors.New("private key is not encrypted; run the migration")
+	}
+	private, err := secret.Open(sealKey, sealed)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt private key: %w", err)
+	}
+	return parsePEMPair(private, public)
+}
+
+func parsePEMPair(private, public string) (*Key, error) {
+	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(private))
+	if err != nil {
+		return nil, fmt.Errorf("parse private key: %w", err)
+	}
+	publicKey, err := jwt.ParseRSAPublicKeyFromPEM([]byte(public))
+	if err != nil {
+		return nil, fmt.Errorf("parse public key: %w", err)
+	}
+	return &Key{Private: privateKey, Public: publicKey}, nil
+}
@@ -0,0 +1,178 @@
+package keyset
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/secret"
+	"github.com/Llane00/ramen-backend/testdb"
+	"gorm.io/gorm"
+)
+
+// testSealKey encrypts the private keys of the tests.
+var testSealKey = bytes.Repeat([]byte{7}, 32)
+
+// createTestKey stores a new key of use in status, activated at activatedAt
+// if it is not nil.
+func createTestKey(t *testing.T, db *gorm.DB, use Use, status string, activatedAt *time.Time) *models.SigningKey {
+	t.Helper()
+	row, err := Generate(use, testSealKey)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	row.Status, row.ActivatedAt = status, activatedAt
+	if err := db.Create(row).Error; err != nil {
+		t.Fatalf("create key: %v", err)
+	}
+	return row
+}
+
+func TestKeySetRotation(t *testing.T) {
+	db := testdb.Open(t, &models.SigningKey{})
+	ctx := context.Background()
+	earlier, later := time.Now().Add(-time.Hour), time.Now()
+
+	previous := createTestKey(t, db, Access, models.SigningKeyActive, &earlier)
+	current := createTestKey(t, db, Access, models.SigningKeyActive, &later)
+	pending := createTestKey(t, db, Access, models.SigningKeyPending, nil)
+	retired := createTestKey(t, db, Access, models.SigningKeyRetired, &earlier)
+	refresh := createTestKey(t, db, Refresh, models.SigningKeyActive, &later)
+
+	ks, err := New(ctx, db, testSealKey, "", "", "", "")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	// The most recently activated key signs.
+	if key, err := ks.SigningKey(Access); err != nil || key.ID != current.ID {
+		t.Fatalf("SigningKey(access) = %v, %v, want %s", key, err, current.ID)
+	}
+
+	// Every key that is not pruned verifies, including retired ones.
+	for _, row := range []*models.SigningKey{previous, current, pending, retired} {
+		if _, err := ks.VerificationKey(Access, row.ID); err != nil {
+			t.Errorf("VerificationKey(%s, %s): %v", row.Status, row.ID, err)
+		}
+	}
+
+	// Keys only verify tokens of their own use.
+	if _, err := ks.VerificationKey(Access, refresh.ID); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("VerificationKey(access, refresh key) = %v, want ErrUnknownKey", err)
+	}
+
+	// Pruning a retired key stops it from verifying after the next reload.
+	if err := db.Delete(retired).Error; err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	if err := ks.Reload(ctx); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if _, err := ks.VerificationKey(Access, retired.ID); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("VerificationKey(pruned) = %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestGenerateSealsPrivateKey(t *testing.T) {
+	row, err := Generate(Access, testSealKey)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if !secret.IsSealed(row.PrivateKey) || strings.Contains(row.PrivateKey, "PRIVATE KEY") {
+		t.Fatalf("private key is stored in plaintext: %.40s...", row.PrivateKey)
+	}
+	if _, err := openKeyPair(testSealKey, row.PrivateKey, row.PublicKey); err != nil {
+		t.Fatalf("openKeyPair: %v", err)
+	}
+	if _, err := openKeyPair(bytes.Repeat([]byte{8}, 32), row.PrivateKey, row.PublicKey); err == nil {
+		t.Error("openKeyPair opened the key with another secret")
+	}
+
+	// Keys stored before encryption must be migrated, not trusted.
+	plain, err := secret.Open(testSealKey, row.PrivateKey)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if _, err := openKeyPair(testSealKey, plain, row.PublicKey); err == nil {
+		t.Error("openKeyPair accepted a plaintext private key")
+	}
+}
+
+func TestKeySetRejectsWrongSealKey(t *testing.T) {
+	db := testdb.Open(t, &models.SigningKey{})
+	now := time.Now()
+	createTestKey(t, db, Access, models.SigningKeyActive, &now)
+
+	if _, err := New(context.Background(), db, bytes.Repeat([]byte{8}, 32), "", "", "", ""); err == nil {
+		t.Fatal("New loaded keys sealed with another secret")
+	}
+}
+
+func TestKeySetWithoutKeys(t *testing.T) {
+	db := testdb.Open(t, &models.SigningKey{})
+	ks, err := New(context.Background(), db, testSealKey, "", "", "", "")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if _, err := ks.SigningKey(Access); err == nil {
+		t.Error("SigningKey succeeded without any key")
+	}
+	// Without a fallback key, a token without kid is rejected like any
+	// unknown kid.
+	if _, err := ks.VerificationKey(Access, legacyKeyID); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("VerificationKey(no kid) = %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestJWKSOmitsPrivateMaterial(t *testing.T) {
+	access := parseTestKey(t, Access)
+	refresh := parseTestKey(t, Refresh)
+	legacy := parseTestKey(t, Access)
+	legacy.ID = legacyKeyID
+	ks := &KeySet{verify: map[Use]map[string]*Key{
+		Access:  {access.ID: access, legacyKeyID: legacy},
+		Refresh: {refresh.ID: refresh},
+	}}
+
+	raw, err := json.Marshal(ks.JWKS())
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var set struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	if err := json.Unmarshal(raw, &set); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	// Only the access key with a kid is published, and only its public part.
+	if len(set.Keys) != 1 || set.Keys[0]["kid"] != access.ID {
+		t.Fatalf("JWKS = %s, want only %s", raw, access.ID)
+	}
+	for field := range set.Keys[0] {
+		switch field {
+		case "kty", "use", "alg", "kid", "n", "e":
+		default:
+			t.Errorf("JWKS publishes %q", field)
+		}
+	}
+}
+
+func parseTestKey(t *testing.T, use Use) *Key {
+	t.Helper()
+	row, err := Generate(use, testSealKey)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	key, err := openKeyPair(testSealKey, row.PrivateKey, row.PublicKey)
+	if err != nil {
+		t.Fatalf("openKeyPair: %v", err)
+	}
+	key.ID, key.Use, key.Status = row.ID, use, row.Status
+	return key
+}
@@ -0,0 +1,33 @@
+package keyset
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Llane00/ramen-backend/health"
+)
+
+// Watch reloads the keys every interval until ctx is done, so a key promoted
+// with the keys command reaches every instance without a restart.
+func (ks *KeySet) Watch(ctx context.Context, interval time.Duration) {
+	worker := health.RegisterWorker("keyset", 3*interval)
+	defer worker.Stopped()
+	worker.Beat(nil)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := ks.Reload(ctx)
+			if err != nil {
+				slog.ErrorContext(ctx, "could not reload signing keys", "error", err)
+			}
+			worker.Beat(err)
+		}
+	}
+}
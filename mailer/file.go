@@ -0,0 +1,44 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Maildir writes messages into a Maildir (tmp, new and cur directories
+// under Dir) instead of sending them, for local development. Any mail client
+// that reads Maildir can open it, and every file is a plain .eml message.
+type Maildir struct {
+	Dir string
+}
+
+func (m *Maildir) Send(ctx context.Context, msg *Message) error {
+	now := time.Now()
+	data, err := msg.Bytes(now)
+	if err != nil {
+		return fmt.Errorf("maildir: encode message: %w", err)
+	}
+
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(m.Dir, sub), 0o755); err != nil {
+			return fmt.Errorf("maildir: %w", err)
+		}
+	}
+
+	// Messages are written to tmp and renamed into new, so readers never see
+	// a partial file.
+	name := fmt.Sprintf("%d.%s.ramen.eml", now.UnixNano(), uuid.NewString())
+	tmp := filepath.Join(m.Dir, "tmp", name)
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("maildir: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(m.Dir, "new", name)); err != nil {
+		return fmt.Errorf("maildir: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package mailer
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testMessage = Message{
+	From:     "noreply@ramen.example",
+	FromName: "Ramen",
+	To:       "customer@example.com",
+	ToName:   "Ramen Lover",
+	Subject:  "Your order is ready 🍜",
+	Text:     "Hi Ramen,\n\nYour order is ready.",
+	HTML:     "<p>Hi Ramen,</p><p>Your order is ready.</p>",
+}
+
+func TestMaildirWritesMessage(t *testing.T) {
+	dir := t.TempDir()
+	m := &Maildir{Dir: dir}
+
+	if err := m.Send(context.Background(), &testMessage); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "new", "*.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("new messages = %v, %v; want one", files, err)
+	}
+	if tmp, _ := filepath.Glob(filepath.Join(dir, "tmp", "*")); len(tmp) != 0 {
+		t.Errorf("tmp not empty: %v", tmp)
+	}
+
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	assertMessage(t, f)
+}
+
+// assertMessage parses r as an email and checks it carries testMessage.
+func assertMessage(t *testing.T, r io.Reader) {
+	t.Helper()
+
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+
+	if got := msg.Header.Get("To"); got != `"Ramen Lover" <customer@example.com>` {
+		t.Errorf("To = %q", got)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != testMessage.Subject {
+		t.Errorf("Subject = %q, %v; want %q", subject, err, testMessage.Subject)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, %v", mediaType, err)
+	}
+
+	bodies := map[string]string{}
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := parts.NextRawPart()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("NextRawPart: %v", err)
+		}
+		body, err := io.ReadAll(quotedprintable.NewReader(part))
+		if err != nil {
+			t.Fatal(err)
+		}
+		contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		bodies[contentType] = strings.ReplaceAll(string(body), "\r\n", "\n")
+	}
+
+	if bodies["text/plain"] != testMessage.Text {
+		t.Errorf("text part = %q, want %q", bodies["text/plain"], testMessage.Text)
+	}
+	if bodies["text/html"] != testMessage.HTML {
+		t.Errorf("html part = %q, want %q", bodies["text/html"], testMessage.HTML)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	r := &Recorder{}
+	other := testMessage
+	other.To = "someone@example.com"
+
+	r.Send(context.Background(), &testMessage)
+	r.Send(context.Background(), &other)
+
+	if got := r.To("customer@example.com"); len(got) != 1 || got[0].Subject != testMessage.Subject {
+		t.Errorf("To(customer) = %+v", got)
+	}
+	if len(r.Messages()) != 2 {
+		t.Errorf("Messages = %d, want 2", len(r.Messages()))
+	}
+
+	r.Reset()
+	r.Err = io.ErrUnexpectedEOF
+	if err := r.Send(context.Background(), &testMessage); err == nil || len(r.Messages()) != 0 {
+		t.Errorf("Send with Err = %v, recorded %d", err, len(r.Messages()))
+	}
+}
+
+func TestMessageBytesHeaderInjection(t *testing.T) {
+	msg := testMessage
+	msg.Subject = "Hello\r\nBcc: victim@example.com"
+
+	data, err := msg.Bytes(testTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := mail.ReadMessage(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bcc := parsed.Header.Get("Bcc"); bcc != "" {
+		t.Errorf("subject injected a Bcc header: %q", bcc)
+	}
+}
+
+var testTime = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
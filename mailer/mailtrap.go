@@ -0,0 +1,74 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Mailtrap API endpoints. Sandbox URLs, which deliver to a test inbox, are
+// per inbox: https://sandbox.api.mailtrap.io/api/send/<inbox id>.
+const MailtrapAPIURL = "https://send.api.mailtrap.io/api/send"
+
+// Mailtrap sends through the Mailtrap email API.
+type Mailtrap struct {
+	URL    string
+	Token  string
+	Client *http.Client
+}
+
+type mailtrapAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+type mailtrapRequest struct {
+	From    mailtrapAddress   `json:"from"`
+	To      []mailtrapAddress `json:"to"`
+	Subject string            `json:"subject"`
+	Text    string            `json:"text"`
+	HTML    string            `json:"html"`
+}
+
+func (m *Mailtrap) Send(ctx context.Context, msg *Message) error {
+	payload, err := json.Marshal(mailtrapRequest{
+		From:    mailtrapAddress{Email: msg.From, Name: msg.FromName},
+		To:      []mailtrapAddress{{Email: msg.To, Name: msg.ToName}},
+		Subject: msg.Subject,
+		Text:    msg.Text,
+		HTML:    msg.HTML,
+	})
+	if err != nil {
+		return fmt.Errorf("mailtrap: encode request: %w", err)
+	}
+
+	url := m.URL
+	if url == "" {
+		url = MailtrapAPIURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("mailtrap: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+m.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := m.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("mailtrap: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("mailtrap: status %s: %s", resp.Status, body)
+	}
+	return nil
+}
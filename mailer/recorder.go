@@ -0,0 +1,50 @@
+package mailer
+
+import (
+	"context"
+	"sync"
+)
+
+// Recorder keeps the messages it is asked to send so tests can inspect them.
+type Recorder struct {
+	mu       sync.Mutex
+	messages []Message
+	// Err, when set, is returned by Send and nothing is recorded.
+	Err error
+}
+
+func (r *Recorder) Send(ctx context.Context, msg *Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.Err != nil {
+		return r.Err
+	}
+	r.messages = append(r.messages, *msg)
+	return nil
+}
+
+// Messages returns the recorded messages in the order they were sent.
+func (r *Recorder) Messages() []Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Message(nil), r.messages...)
+}
+
+// To returns the recorded messages sent to address.
+func (r *Recorder) To(address string) []Message {
+	var sent []Message
+	for _, msg := range r.Messages() {
+		if msg.To == address {
+			sent = append(sent, msg)
+		}
+	}
+	return sent
+}
+
+// Reset forgets the recorded messages.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messages = nil
+}
@@ -1,25 +1,69 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/Llane00/ramen-backend/controllers"
+	"github.com/Llane00/ramen-backend/health"
 	"github.com/Llane00/ramen-backend/initializers"
+	"github.com/Llane00/ramen-backend/metrics"
+	"github.com/Llane00/ramen-backend/middleware"
+	"github.com/Llane00/ramen-backend/outbox"
+	"github.com/Llane00/ramen-backend/pubsub"
+	"github.com/Llane00/ramen-backend/ratelimit"
 	"github.com/Llane00/ramen-backend/routes"
+	"github.com/Llane00/ramen-backend/utils"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 var (
-	server              *gin.Engine
-	AuthController      controllers.AuthController
-	AuthRouteController routes.AuthRouteController
+	server         *gin.Engine
+	shutdownTracer func(context.Context) error
+	emailWorker    *outbox.Worker
+	hub            *pubsub.Hub
+	liveHub        *pubsub.Hub
+
+	HealthController      controllers.HealthController
+	HealthRouteController routes.HealthRouteController
+
+	JWKSController      controllers.JWKSController
+	JWKSRouteController routes.JWKSRouteController
+
+	DocsController         controllers.DocsController
+	OpenAPIRouteController routes.OpenAPIRouteController
+	AuthController         controllers.AuthController
+	AuthRouteController    routes.AuthRouteController
+
+	OAuthController      controllers.OAuthController
+	OAuthRouteController routes.OAuthRouteController
+
+	MFAController      controllers.MFAController
+	MFARouteController routes.MFARouteController
 
 	UserController      controllers.UserController
 	UserRouteController routes.UserRouteController
 
+	IdentityController      controllers.IdentityController
+	IdentityRouteController routes.IdentityRouteController
+
+	NotificationController      controllers.NotificationController
+	NotificationRouteController routes.NotificationRouteController
+
+	LiveController      controllers.LiveController
+	LiveRouteController routes.LiveRouteController
+
+	APIKeyController      controllers.APIKeyController
+	APIKeyRouteController routes.APIKeyRouteController
+
 	PostController      controllers.PostController
 	PostRouteController routes.PostRouteController
 
@@ -32,27 +76,116 @@ var (
 	OrderController      controllers.OrderController
 	OrderRouteController routes.OrderRouteController
 
+	KitchenController      controllers.KitchenController
+	KitchenRouteController routes.KitchenRouteController
+
 	PaymentController      controllers.PaymentController
 	PaymentRouteController routes.PaymentRouteController
+
+	AdminController      controllers.AdminController
+	AdminRouteController routes.AdminRouteController
 )
 
 func init() {
+	env := os.Getenv("GO_ENV")
+	if env == "" {
+		env = "development" // default env
+	}
+
 	config, err := initializers.LoadConfig(".")
+	utils.InitLogger(env, config.LogLevel)
 	if err != nil {
-		log.Fatal("? Could not load environment variables", err)
+		slog.Error("could not load environment variables", "error", err)
+		os.Exit(1)
 	}
 
-	env := os.Getenv("GO_ENV")
-	if env == "" {
-		env = "development" // default env
+	shutdownTracer, err = initializers.InitTracer(context.Background(), &config, env)
+	if err != nil {
+		slog.Error("could not initialize tracing", "error", err)
+		os.Exit(1)
+	}
+
+	if err := initializers.ConnectDB(&config, env); err != nil {
+		slog.Error("could not connect to database", "error", err)
+		os.Exit(1)
+	}
+
+	if err := metrics.InstrumentDB(initializers.DB); err != nil {
+		slog.Error("could not instrument database", "error", err)
+		os.Exit(1)
+	}
+
+	if err := initializers.LoadKeys(context.Background(), &config); err != nil {
+		slog.Error("could not load signing keys", "error", err)
+		os.Exit(1)
+	}
+
+	mail, err := config.Mailer(utils.NewHTTPClient(30 * time.Second))
+	if err != nil {
+		slog.Error("could not configure email", "error", err)
+		os.Exit(1)
+	}
+	if err := utils.InitEmail(mail, "templates", config.EmailFrom, config.EmailSenderName()); err != nil {
+		slog.Error("could not load email templates", "error", err)
+		os.Exit(1)
+	}
+	emailWorker = outbox.NewWorker(initializers.DB, config.EmailOutboxInterval, config.EmailMaxAttempts)
+
+	HealthController = controllers.NewHealthController(initializers.DB)
+	HealthRouteController = routes.NewHealthRouteController(HealthController)
+
+	JWKSController = controllers.NewJWKSController(initializers.Keys)
+	JWKSRouteController = routes.NewJWKSRouteController(JWKSController)
+
+	spec, err := routes.BuildOpenAPI()
+	if err != nil {
+		slog.Error("could not build OpenAPI document", "error", err)
+		os.Exit(1)
+	}
+	DocsController = controllers.NewDocsController(spec)
+	OpenAPIRouteController = routes.NewOpenAPIRouteController(DocsController)
+
+	var limiter ratelimit.Store
+	switch config.RateLimitStore {
+	case "", "memory":
+		limiter = ratelimit.NewMemoryStore()
+	case "postgres":
+		limiter = ratelimit.NewPostgresStore(initializers.DB)
+	default:
+		slog.Error("unknown rate limit store", "store", config.RateLimitStore)
+		os.Exit(1)
 	}
-	initializers.ConnectDB(&config, env)
+
+	// Each user may have a few notification streams open, e.g. browser tabs.
+	hub = pubsub.NewHub(5)
+	// Live order connections are per order or per shop, where a shop may
+	// have several kitchen screens.
+	liveHub = pubsub.NewHub(20)
 
 	AuthController = controllers.NewAuthController(initializers.DB)
-	AuthRouteController = routes.NewAuthRouteController(AuthController)
+	AuthRouteController = routes.NewAuthRouteController(AuthController, limiter)
+
+	oauthProviders := config.OAuthProviders(utils.NewHTTPClient(30 * time.Second))
+	OAuthController = controllers.NewOAuthController(initializers.DB, oauthProviders)
+	OAuthRouteController = routes.NewOAuthRouteController(OAuthController, limiter)
+
+	MFAController = controllers.NewMFAController(initializers.DB)
+	MFARouteController = routes.NewMFARouteController(MFAController)
 
 	UserController = controllers.NewUserController(initializers.DB)
-	UserRouteController = routes.NewRouteUserController(UserController)
+	UserRouteController = routes.NewRouteUserController(UserController, limiter)
+
+	IdentityController = controllers.NewIdentityController(initializers.DB, oauthProviders)
+	IdentityRouteController = routes.NewIdentityRouteController(IdentityController, limiter)
+
+	NotificationController = controllers.NewNotificationController(initializers.DB, hub)
+	NotificationRouteController = routes.NewNotificationRouteController(NotificationController)
+
+	LiveController = controllers.NewLiveController(initializers.DB, liveHub)
+	LiveRouteController = routes.NewLiveRouteController(LiveController)
+
+	APIKeyController = controllers.NewAPIKeyController(initializers.DB)
+	APIKeyRouteController = routes.NewAPIKeyRouteController(APIKeyController)
 
 	PostController = controllers.NewPostController(initializers.DB)
 	PostRouteController = routes.NewRoutePostController(PostController)
@@ -64,38 +197,151 @@ func init() {
 	ProductRouteController = routes.NewProductRouteController(ProductController)
 
 	OrderController = controllers.NewOrderController(initializers.DB)
-	OrderRouteController = routes.NewOrderRouteController(OrderController)
+	OrderRouteController = routes.NewOrderRouteController(OrderController, limiter)
+
+	KitchenController = controllers.NewKitchenController(initializers.DB)
+	KitchenRouteController = routes.NewKitchenRouteController(KitchenController)
 
 	PaymentController = controllers.NewPaymentController(initializers.DB)
 	PaymentRouteController = routes.NewPaymentRouteController(PaymentController)
 
-	server = gin.Default()
+	AdminController = controllers.NewAdminController(initializers.DB)
+	AdminRouteController = routes.NewAdminRouteController(AdminController)
+
+	if env == "production" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	server = gin.New()
+	if err := server.SetTrustedProxies(config.TrustedProxies); err != nil {
+		slog.Error("invalid TRUSTED_PROXIES", "error", err)
+		os.Exit(1)
+	}
+	server.Use(otelgin.Middleware(config.TracingServiceName(), otelgin.WithFilter(func(r *http.Request) bool {
+		return r.URL.Path != "/metrics"
+	})))
+	server.Use(middleware.RequestID(), middleware.RequestLogger(), middleware.Metrics(), middleware.Recovery(), middleware.ErrorHandler())
 }
 
 func main() {
 	config, err := initializers.LoadConfig(".")
 	if err != nil {
-		log.Fatal("Could not load environment variables", err)
+		slog.Error("could not load environment variables", "error", err)
+		os.Exit(1)
 	}
 
 	corsConfig := cors.DefaultConfig()
 	corsConfig.AllowOrigins = []string{"http://localhost:8000", config.ClientOrigin}
 	corsConfig.AllowCredentials = true
+	corsConfig.AddAllowHeaders(middleware.RequestIDHeader, "Authorization")
+	corsConfig.AddExposeHeaders(middleware.RequestIDHeader, "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After")
 
 	server.Use(cors.New(corsConfig))
 
+	routes.MetricsRoute(&server.RouterGroup, &config)
+	HealthRouteController.HealthRoute(&server.RouterGroup)
+	JWKSRouteController.JWKSRoute(&server.RouterGroup)
+
 	router := server.Group("/api")
-	router.GET("/healthchecker", func(ctx *gin.Context) {
-		message := "Welcome to Golang with Gorm and Postgres"
-		ctx.JSON(http.StatusOK, gin.H{"status": "success", "message": message})
-	})
+	OpenAPIRouteController.OpenAPIRoute(router)
 
 	AuthRouteController.AuthRoute(router)
+	OAuthRouteController.OAuthRoute(router)
+	MFARouteController.MFARoute(router)
 	UserRouteController.UserRoute(router)
+	IdentityRouteController.IdentityRoute(router)
+	NotificationRouteController.NotificationRoute(router)
+	LiveRouteController.LiveRoute(router)
+	APIKeyRouteController.APIKeyRoute(router)
 	PostRouteController.PostRoute(router)
 	ShopRouteController.ShopRoute(router)
 	ProductRouteController.ProductRoute(router)
 	OrderRouteController.OrderRoute(router)
+	KitchenRouteController.KitchenRoute(router)
 	PaymentRouteController.PaymentRoute(router)
-	log.Fatal(server.Run(":" + config.ServerPort))
+	AdminRouteController.AdminRoute(router)
+
+	if err := run(&config); err != nil {
+		slog.Error("server stopped", "error", err)
+		os.Exit(1)
+	}
+}
+
+// keyReloadInterval is how quickly a signing key promoted with the keys
+// command takes effect.
+const keyReloadInterval = time.Minute
+
+// run serves HTTP until SIGINT or SIGTERM. On a signal the server first
+// reports not-ready for the drain period so load balancers stop routing to
+// it, then finishes in-flight requests and emails and flushes traces.
+func run(config *initializers.Config) error {
+	srv := &http.Server{
+		Addr:    ":" + config.ServerPort,
+		Handler: server,
+	}
+	// Shutdown waits for open requests, so end the event streams first.
+	srv.RegisterOnShutdown(hub.Close)
+	srv.RegisterOnShutdown(liveHub.Close)
+
+	sqlDB, err := initializers.DB.DB()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	go initializers.Keys.Watch(ctx, keyReloadInterval)
+
+	workerDone := make(chan struct{})
+	go func() {
+		emailWorker.Run(ctx)
+		close(workerDone)
+	}()
+
+	// Events from every instance reach this instance's streams through
+	// Postgres.
+	go pubsub.Listen(ctx, sqlDB, hub, liveHub)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("server listening", "addr", srv.Addr)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	drainPeriod := config.ShutdownDrainPeriod
+	if drainPeriod == 0 {
+		drainPeriod = 5 * time.Second
+	}
+	shutdownTimeout := config.ShutdownTimeout
+	if shutdownTimeout == 0 {
+		shutdownTimeout = 15 * time.Second
+	}
+
+	slog.Info("shutting down", "drain_period", drainPeriod)
+	health.SetDraining(true)
+	time.Sleep(drainPeriod)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	err = srv.Shutdown(shutdownCtx)
+	select {
+	case <-workerDone:
+	case <-shutdownCtx.Done():
+		slog.Warn("email worker did not stop in time")
+	}
+	if flushErr := shutdownTracer(shutdownCtx); flushErr != nil {
+		slog.Error("could not flush traces", "error", flushErr)
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	slog.Info("server stopped")
+	return nil
 }
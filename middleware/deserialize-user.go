@@ -1,17 +1,55 @@
 package middleware
 
 import (
+	"crypto/subtle"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/Llane00/ramen-backend/apperror"
 	"github.com/Llane00/ramen-backend/initializers"
+	"github.com/Llane00/ramen-backend/keyset"
 	"github.com/Llane00/ramen-backend/models"
 	"github.com/Llane00/ramen-backend/utils"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+// apiKeyTouchInterval limits how often last-used tracking writes to the
+// database for a busy key.
+const apiKeyTouchInterval = time.Minute
+
+type authOptions struct {
+	allowPendingMFA bool
+	// apiKeyResource enables "Authorization: ApiKey ..." for the route. The
+	// key needs "<resource>:read" for GET and HEAD requests and
+	// "<resource>:write" for everything else.
+	apiKeyResource string
+}
+
+// DeserializeUser authenticates the request and stores the user as
+// "currentUser". Users whose role requires two-factor authentication are
+// rejected until they have enrolled. API keys are not accepted.
 func DeserializeUser() gin.HandlerFunc {
+	return deserializeUser(authOptions{})
+}
+
+// DeserializeUserPendingMFA is DeserializeUser for the few endpoints a user
+// must reach to complete a required two-factor enrollment.
+func DeserializeUserPendingMFA() gin.HandlerFunc {
+	return deserializeUser(authOptions{allowPendingMFA: true})
+}
+
+// DeserializeUserOrAPIKey is DeserializeUser that also accepts API keys
+// scoped to resource. The key is stored as "apiKey".
+func DeserializeUserOrAPIKey(resource string) gin.HandlerFunc {
+	return deserializeUser(authOptions{apiKeyResource: resource})
+}
+
+func deserializeUser(opts authOptions) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var access_token string
 		cookie, err := ctx.Cookie("access_token")
@@ -19,28 +57,53 @@ func DeserializeUser() gin.HandlerFunc {
 		authorizationHeader := ctx.Request.Header.Get("Authorization")
 		fields := strings.Fields(authorizationHeader)
 
-		if len(fields) != 0 && fields[0] == "Bearer" {
+		if len(fields) == 2 && fields[0] == "ApiKey" {
+			user, err := authenticateAPIKey(ctx, fields[1], opts.apiKeyResource)
+			if err != nil {
+				ctx.Error(err)
+				ctx.Abort()
+				return
+			}
+			ctx.Set("currentUser", *user)
+			ctx.Next()
+			return
+		}
+
+		if len(fields) == 2 && fields[0] == "Bearer" {
 			access_token = fields[1]
 		} else if err == nil {
 			access_token = cookie
 		}
 
 		if access_token == "" {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"status": "fail", "message": "You are not logged in"})
+			ctx.Error(apperror.Unauthorized("not_logged_in", "You are not logged in"))
+			ctx.Abort()
 			return
 		}
 
 		config, _ := initializers.LoadConfig(".")
-		sub, err := utils.ValidateToken(access_token, config.AccessTokenPublicKey)
+		sub, err := utils.ValidateToken(access_token, keyset.Access)
 		if err != nil {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"status": "fail", "message": err.Error()})
+			ctx.Error(&apperror.Error{Kind: apperror.KindUnauthorized, Code: "invalid_token", Message: "Your token is invalid or has expired", Err: err})
+			ctx.Abort()
 			return
 		}
 
 		var user models.User
-		result := initializers.DB.First(&user, "id = ?", fmt.Sprint(sub))
-		if result.Error != nil {
-			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"status": "fail", "message": "the user belonging to this token no logger exists"})
+		result := initializers.DB.WithContext(ctx.Request.Context()).First(&user, "id = ?", fmt.Sprint(sub))
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			ctx.Error(apperror.Forbidden("user_not_found", "The user belonging to this token no longer exists"))
+			ctx.Abort()
+			return
+		} else if result.Error != nil {
+			ctx.Error(apperror.Internal(result.Error))
+			ctx.Abort()
+			return
+		}
+
+		if !opts.allowPendingMFA && !user.TOTPEnabled && user.RequiresMFA(config.MFARequiredRoles) {
+			ctx.Error(apperror.Forbidden("mfa_enrollment_required", "Two-factor authentication must be enabled for your account"))
+			ctx.Abort()
 			return
 		}
 
@@ -48,3 +111,62 @@ func DeserializeUser() gin.HandlerFunc {
 		ctx.Next()
 	}
 }
+
+// authenticateAPIKey resolves an API key to its user, checking that the key
+// is live, that its owner meets the two-factor requirement of their role and
+// that the key carries the scope the request needs.
+func authenticateAPIKey(ctx *gin.Context, key string, resource string) (*models.User, error) {
+	if resource == "" {
+		return nil, apperror.Forbidden("api_key_not_allowed", "This endpoint cannot be used with an API key")
+	}
+
+	invalid := apperror.Unauthorized("invalid_api_key", "The API key is invalid, expired or revoked")
+	prefix, ok := utils.ParseAPIKey(key)
+	if !ok {
+		return nil, invalid
+	}
+
+	db := initializers.DB.WithContext(ctx.Request.Context())
+
+	var apiKey models.APIKey
+	result := db.Preload("User").First(&apiKey, "prefix = ?", prefix)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, invalid
+	} else if result.Error != nil {
+		return nil, apperror.Internal(result.Error)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(utils.HashToken(key)), []byte(apiKey.KeyHash)) != 1 {
+		return nil, invalid
+	}
+	now := time.Now()
+	if apiKey.RevokedAt != nil || !now.Before(apiKey.ExpiresAt) {
+		return nil, invalid
+	}
+
+	// A key must not outlive the owner's obligation to use two factors, e.g.
+	// after being given a role that requires them.
+	config, _ := initializers.LoadConfig(".")
+	if !apiKey.User.TOTPEnabled && apiKey.User.RequiresMFA(config.MFARequiredRoles) {
+		return nil, apperror.Forbidden("mfa_enrollment_required", "Two-factor authentication must be enabled for the owner of this API key")
+	}
+
+	access := "write"
+	if ctx.Request.Method == http.MethodGet || ctx.Request.Method == http.MethodHead {
+		access = "read"
+	}
+	scope := resource + ":" + access
+	if !apiKey.Scopes.Has(scope) {
+		return nil, apperror.Forbidden("insufficient_scope", "The API key lacks the "+scope+" scope")
+	}
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > apiKeyTouchInterval {
+		err := db.Model(&apiKey).UpdateColumns(map[string]any{"last_used_at": now, "last_used_ip": ctx.ClientIP()}).Error
+		if err != nil {
+			slog.WarnContext(ctx.Request.Context(), "could not record api key use", "key_prefix", prefix, "error", err)
+		}
+	}
+
+	ctx.Set("apiKey", apiKey)
+	return &apiKey.User, nil
+}
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Llane00/ramen-backend/initializers"
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/testdb"
+	"github.com/Llane00/ramen-backend/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// useTestDB points initializers.DB at db for the duration of the test.
+func useTestDB(t *testing.T, db *gorm.DB) {
+	previous := initializers.DB
+	initializers.DB = db
+	t.Cleanup(func() { initializers.DB = previous })
+}
+
+// createTestAPIKey stores a key for a new user and returns the raw key.
+func createTestAPIKey(t *testing.T, db *gorm.DB, roles models.UserRoles, scopes ...string) (string, models.APIKey) {
+	t.Helper()
+	user := models.User{Name: "Taro", Email: uuid.NewString() + "@ramen.example", Password: "unused", Roles: roles, Provider: "local", Verified: true}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	key, prefix := utils.GenerateAPIKey()
+	apiKey := models.APIKey{
+		UserID:    user.ID,
+		Name:      "test",
+		Prefix:    prefix,
+		KeyHash:   utils.HashToken(key),
+		Scopes:    scopes,
+		ExpiresAt: time.Now().Add(time.Hour),
+		CreatedAt: time.Now(),
+	}
+	if err := db.Create(&apiKey).Error; err != nil {
+		t.Fatalf("create api key: %v", err)
+	}
+	return key, apiKey
+}
+
+func TestDeserializeUserOrAPIKey(t *testing.T) {
+	testdb.Config(t)
+	t.Setenv("MFA_REQUIRED_ROLES", string(models.RoleSuperAdmin))
+	db := testdb.Open(t, &models.User{}, &models.APIKey{})
+	useTestDB(t, db)
+
+	gin.SetMode(gin.TestMode)
+	server := gin.New()
+	server.Use(ErrorHandler())
+	ok := func(ctx *gin.Context) { ctx.Status(http.StatusNoContent) }
+	server.GET("/orders", DeserializeUserOrAPIKey("orders"), ok)
+	server.POST("/orders", DeserializeUserOrAPIKey("orders"), ok)
+	server.GET("/me", DeserializeUser(), ok)
+
+	readKey, _ := createTestAPIKey(t, db, nil, models.ScopeOrdersRead)
+	revokedKey, revoked := createTestAPIKey(t, db, nil, models.ScopeOrdersRead)
+	expiredKey, expired := createTestAPIKey(t, db, nil, models.ScopeOrdersRead)
+	adminKey, _ := createTestAPIKey(t, db, models.UserRoles{models.RoleSuperAdmin}, models.ScopeOrdersRead)
+	db.Model(&revoked).Update("revoked_at", time.Now())
+	db.Model(&expired).Update("expires_at", time.Now().Add(-time.Second))
+	_, prefix := utils.GenerateAPIKey()
+	guessed := "rk_" + readKey[3:15] + "_wrongsecret"
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		key    string
+		want   int
+	}{
+		{"read scope", http.MethodGet, "/orders", readKey, http.StatusNoContent},
+		{"missing write scope", http.MethodPost, "/orders", readKey, http.StatusForbidden},
+		{"route without api keys", http.MethodGet, "/me", readKey, http.StatusForbidden},
+		{"revoked", http.MethodGet, "/orders", revokedKey, http.StatusUnauthorized},
+		{"expired", http.MethodGet, "/orders", expiredKey, http.StatusUnauthorized},
+		{"wrong secret", http.MethodGet, "/orders", guessed, http.StatusUnauthorized},
+		{"unknown prefix", http.MethodGet, "/orders", "rk_" + prefix + "_secret", http.StatusUnauthorized},
+		{"owner without required mfa", http.MethodGet, "/orders", adminKey, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.Header.Set("Authorization", "ApiKey "+tt.key)
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d: %s", tt.name, rec.Code, tt.want, rec.Body)
+		}
+	}
+}
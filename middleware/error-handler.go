@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"log/slog"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/Llane00/ramen-backend/apperror"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+type errorBody struct {
+	Code    string                `json:"code"`
+	Message string                `json:"message"`
+	Details []apperror.FieldError `json:"details,omitempty"`
+}
+
+// ErrorHandler renders the last error attached with ctx.Error as the shared
+// JSON envelope:
+//
+//	{"status": "fail", "error": {"code": "...", "message": "...", "details": [...]}, "request_id": "..."}
+//
+// status is "fail" for client errors and "error" for server errors.
+func ErrorHandler() gin.HandlerFunc {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(jsonFieldName)
+		v.RegisterValidation("timezone", isTimeZone)
+	}
+
+	return func(ctx *gin.Context) {
+		ctx.Next()
+
+		if len(ctx.Errors) == 0 || ctx.Writer.Written() {
+			return
+		}
+
+		RenderError(ctx, ctx.Errors.Last().Err)
+	}
+}
+
+// RenderError writes err as the error envelope and aborts the chain.
+func RenderError(ctx *gin.Context, err error) {
+	appErr := apperror.From(err)
+	status := appErr.Status()
+
+	if status >= 500 {
+		slog.ErrorContext(ctx.Request.Context(), "request failed", "error_code", appErr.Code, "error", appErr)
+	}
+
+	envelopeStatus := "fail"
+	if status >= 500 {
+		envelopeStatus = "error"
+	}
+
+	ctx.AbortWithStatusJSON(status, gin.H{
+		"status":     envelopeStatus,
+		"error":      errorBody{Code: appErr.Code, Message: appErr.Message, Details: appErr.Fields},
+		"request_id": ctx.GetString("requestID"),
+	})
+}
+
+// jsonFieldName makes validation errors report the JSON name of a field.
+func jsonFieldName(field reflect.StructField) string {
+	name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+	if name == "-" || name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// isTimeZone replaces the built-in timezone rule, which accepts "Local", the
+// host's time zone, and so stores a zone that depends on the server.
+func isTimeZone(fl validator.FieldLevel) bool {
+	name := fl.Field().String()
+	if name == "" || name == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
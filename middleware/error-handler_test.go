@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Llane00/ramen-backend/apperror"
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestTimeZoneValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := gin.New()
+	server.Use(ErrorHandler())
+	server.POST("/", func(ctx *gin.Context) {
+		var input models.CreateShopInput
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			ctx.Error(apperror.FromBinding(err))
+			return
+		}
+		ctx.Status(http.StatusNoContent)
+	})
+
+	tests := map[string]int{
+		"":                 http.StatusNoContent,
+		"UTC":              http.StatusNoContent,
+		"Asia/Tokyo":       http.StatusNoContent,
+		"Local":            http.StatusBadRequest,
+		"Mars/Olympus":     http.StatusBadRequest,
+		"../../etc/passwd": http.StatusBadRequest,
+	}
+	for zone, want := range tests {
+		body := `{"name": "Ramen Yamada", "time_zone": "` + zone + `"}`
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		server.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("time_zone %q: status = %d, want %d: %s", zone, rec.Code, want, rec.Body)
+		}
+		if want == http.StatusBadRequest && !strings.Contains(rec.Body.String(), `"field":"time_zone"`) {
+			t.Errorf("time_zone %q: error does not name the field: %s", zone, rec.Body)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Llane00/ramen-backend/initializers"
+	"github.com/Llane00/ramen-backend/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics records a request counter and a latency histogram per route.
+// Routes are labelled by their registered pattern to keep cardinality bounded.
+// It must run outside Recovery so requests that panic are counted as 500s.
+func Metrics() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+
+		ctx.Next()
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := ctx.Request.Method
+
+		metrics.HTTPRequestsTotal.WithLabelValues(method, route, strconv.Itoa(ctx.Writer.Status())).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// MetricsAccess guards the /metrics endpoint. The endpoint is hidden unless
+// METRICS_ENABLED is set; callers must then present METRICS_TOKEN as a bearer
+// token or connect from one of METRICS_ALLOWED_IPS. With neither configured
+// only loopback connections are accepted.
+func MetricsAccess(config *initializers.Config) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !config.MetricsEnabled {
+			ctx.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+
+		if config.MetricsToken != "" {
+			fields := strings.Fields(ctx.GetHeader("Authorization"))
+			if len(fields) == 2 && fields[0] == "Bearer" &&
+				subtle.ConstantTimeCompare([]byte(fields[1]), []byte(config.MetricsToken)) == 1 {
+				ctx.Next()
+				return
+			}
+		}
+
+		remote := net.ParseIP(ctx.RemoteIP())
+		if remote != nil && ipAllowed(remote, config.MetricsAllowedIPs, config.MetricsToken == "") {
+			ctx.Next()
+			return
+		}
+
+		ctx.AbortWithStatus(http.StatusForbidden)
+	}
+}
+
+func ipAllowed(ip net.IP, allowed []string, allowLoopback bool) bool {
+	if len(allowed) == 0 {
+		return allowLoopback && ip.IsLoopback()
+	}
+
+	for _, entry := range allowed {
+		entry = strings.TrimSpace(entry)
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if allowedIP := net.ParseIP(entry); allowedIP != nil && allowedIP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
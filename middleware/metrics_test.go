@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Llane00/ramen-backend/metrics"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsCountsPanics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := gin.New()
+	server.Use(Metrics(), Recovery(), ErrorHandler())
+	server.GET("/panic", func(ctx *gin.Context) { panic("boom") })
+
+	counter := metrics.HTTPRequestsTotal.WithLabelValues(http.MethodGet, "/panic", "500")
+	before := testutil.ToFloat64(counter)
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", rec.Code)
+	}
+	if got := testutil.ToFloat64(counter) - before; got != 1 {
+		t.Errorf("500 responses counted = %v, want 1", got)
+	}
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Llane00/ramen-backend/apperror"
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	policy := ratelimit.Policy{Name: "test", Burst: 2, Period: time.Minute}
+	server := gin.New()
+	server.Use(ErrorHandler())
+	server.GET("/", RateLimit(ratelimit.NewMemoryStore(), policy, KeyByIP), func(ctx *gin.Context) {
+		ctx.Status(http.StatusNoContent)
+	})
+
+	for i, want := range []int{http.StatusNoContent, http.StatusNoContent, http.StatusTooManyRequests} {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		if rec.Code != want {
+			t.Fatalf("request %d: status = %d, want %d", i, rec.Code, want)
+		}
+		if got := rec.Header().Get("RateLimit-Policy"); got != "2;w=60" {
+			t.Errorf("request %d: RateLimit-Policy = %q", i, got)
+		}
+		if want != http.StatusTooManyRequests {
+			continue
+		}
+		if got := rec.Header().Get("Retry-After"); got != "30" {
+			t.Errorf("Retry-After = %q, want 30", got)
+		}
+		if got := rec.Header().Get("RateLimit-Remaining"); got != "0" {
+			t.Errorf("RateLimit-Remaining = %q, want 0", got)
+		}
+	}
+}
+
+func TestRateLimitSkipsEmptyKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	policy := ratelimit.Policy{Name: "test", Burst: 1, Period: time.Minute}
+	server := gin.New()
+	server.POST("/", RateLimit(ratelimit.NewMemoryStore(), policy, KeyByEmail), func(ctx *gin.Context) {
+		ctx.Status(http.StatusNoContent)
+	})
+
+	for i := 0; i < 3; i++ {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("request %d without an email: status = %d, want 204", i, rec.Code)
+		}
+	}
+}
+
+func TestRateLimitKeysUnreadableBodies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	policy := ratelimit.Policy{Name: "test", Burst: 1, Period: time.Minute}
+	server := gin.New()
+	server.Use(ErrorHandler())
+	server.POST("/", RateLimit(ratelimit.NewMemoryStore(), policy, KeyByEmail), func(ctx *gin.Context) {
+		var input models.MagicLinkInput
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			ctx.Error(apperror.FromBinding(err))
+			return
+		}
+		ctx.Status(http.StatusNoContent)
+	})
+
+	post := func(body string) int {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		server.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Leading whitespace past the size KeyByEmail reads hides the email
+	// from it, but not from the handler.
+	padded := strings.Repeat(" ", maxKeyBodySize) + `{"email": "victim@ramen.example"}`
+	for i, want := range []int{http.StatusNoContent, http.StatusTooManyRequests} {
+		if got := post(padded); got != want {
+			t.Fatalf("padded request %d: status = %d, want %d", i, got, want)
+		}
+	}
+
+	// Malformed bodies share the same bucket.
+	if got := post(`{"email": `); got != http.StatusTooManyRequests {
+		t.Errorf("malformed request: status = %d, want 429", got)
+	}
+
+	// The victim's own bucket is untouched.
+	if got := post(`{"email": "victim@ramen.example"}`); got != http.StatusNoContent {
+		t.Errorf("request with a readable email: status = %d, want 204", got)
+	}
+}
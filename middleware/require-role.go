@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"github.com/Llane00/ramen-backend/apperror"
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole allows the request through if the current user has any of the
+// given roles. It must run after DeserializeUser.
+func RequireRole(roles ...models.UserRole) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, ok := ctx.Get("currentUser")
+		if !ok {
+			ctx.Error(apperror.Unauthorized("not_logged_in", "You are not logged in"))
+			ctx.Abort()
+			return
+		}
+
+		currentUser := user.(models.User)
+		for _, role := range roles {
+			if currentUser.HasRole(role) {
+				ctx.Next()
+				return
+			}
+		}
+
+		ctx.Error(apperror.Forbidden("insufficient_role", "You do not have permission to perform this action"))
+		ctx.Abort()
+	}
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// AccessTokenProtocol is the WebSocket subprotocol that carries an access
+// token from browsers, which cannot set headers on a WebSocket handshake:
+//
+//	new WebSocket(url, ["access_token", token])
+const AccessTokenProtocol = "access_token"
+
+// WebSocketToken lets DeserializeUser authenticate a WebSocket handshake
+// that offers its access token as the subprotocol after AccessTokenProtocol.
+// A token in the Authorization header or cookie works as for any request.
+func WebSocketToken() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		protocols := websocket.Subprotocols(ctx.Request)
+		for i, protocol := range protocols {
+			if protocol == AccessTokenProtocol && i+1 < len(protocols) && ctx.GetHeader("Authorization") == "" {
+				ctx.Request.Header.Set("Authorization", "Bearer "+protocols[i+1])
+				break
+			}
+		}
+		ctx.Next()
+	}
+}
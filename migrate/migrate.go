@@ -1,38 +1,163 @@
 package main
 
 import (
-	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"time"
 
 	"github.com/Llane00/ramen-backend/initializers"
 	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/secret"
+	"github.com/Llane00/ramen-backend/utils"
+	"gorm.io/gorm"
 )
 
 func init() {
-	config, err := initializers.LoadConfig(".")
-	if err != nil {
-		log.Fatal("❌ Could not load environment variables", err)
-	}
-
 	env := os.Getenv("GO_ENV")
 	if env == "" {
 		env = "development" // default env
 	}
 
-	initializers.ConnectDB(&config, env)
+	config, err := initializers.LoadConfig(".")
+	utils.InitLogger(env, config.LogLevel)
+	if err != nil {
+		slog.Error("could not load environment variables", "error", err)
+		os.Exit(1)
+	}
+
+	if err := initializers.ConnectDB(&config, env); err != nil {
+		slog.Error("could not connect to database", "error", err)
+		os.Exit(1)
+	}
 }
 
 func main() {
-	fmt.Println("GO_ENV:", os.Getenv("GO_ENV"))
-	initializers.DB.AutoMigrate(
+	slog.Info("running migrations", "env", os.Getenv("GO_ENV"))
+	err := initializers.DB.AutoMigrate(
 		&models.User{},
 		&models.Post{},
 		&models.Shop{},
+		&models.ShopClosure{},
 		&models.Product{},
 		&models.Order{},
 		&models.OrderItem{},
 		&models.Payment{},
+		&models.RateLimitBucket{},
+		&models.LoginAttempt{},
+		&models.RecoveryCode{},
+		&models.APIKey{},
+		&models.SigningKey{},
+		&models.UserIdentity{},
+		&models.UserToken{},
+		&models.EmailOutbox{},
+		&models.Notification{},
+		&models.SchemaMigration{},
 	)
-	fmt.Println("✅ Migration complete")
+	if err != nil {
+		slog.Error("migration failed", "error", err)
+		os.Exit(1)
+	}
+
+	// Verification and reset tokens used to be stored base64 encoded on the
+	// user. They now live hashed in user_tokens; outstanding old tokens are
+	// dropped with their columns.
+	migrator := initializers.DB.Migrator()
+	for _, column := range []string{"verification_code", "password_reset_token", "password_reset_at"} {
+		if migrator.HasColumn(&models.User{}, column) {
+			if err := migrator.DropColumn(&models.User{}, column); err != nil {
+				slog.Error("migration failed", "error", err)
+				os.Exit(1)
+			}
+		}
+	}
+
+	// Orders paid before PaidAt existed join the kitchen queue by their
+	// last update.
+	err = initializers.DB.Model(&models.Order{}).
+		Where("paid_at IS NULL AND status IN ?", []models.OrderStatus{models.OrderStatusPaid, models.OrderStatusShipping}).
+		UpdateColumn("paid_at", gorm.Expr("updated_at")).Error
+	if err != nil {
+		slog.Error("migration failed", "error", err)
+		os.Exit(1)
+	}
+
+	if err := sealTOTPSecrets(); err != nil {
+		slog.Error("migration failed", "error", err)
+		os.Exit(1)
+	}
+	if err := sealSigningKeys(); err != nil {
+		slog.Error("migration failed", "error", err)
+		os.Exit(1)
+	}
+
+	migration := models.SchemaMigration{Version: models.SchemaVersion, AppliedAt: time.Now()}
+	if err := initializers.DB.FirstOrCreate(&migration, models.SchemaMigration{Version: models.SchemaVersion}).Error; err != nil {
+		slog.Error("could not record schema version", "error", err)
+		os.Exit(1)
+	}
+	slog.Info("migration complete", "schema_version", models.SchemaVersion)
+}
+
+// sealTOTPSecrets encrypts TOTP secrets stored in plaintext before
+// MFA_SECRET_KEY was introduced.
+func sealTOTPSecrets() error {
+	var users []models.User
+	err := initializers.DB.Select("id", "totp_secret").
+		Where("totp_secret <> '' AND totp_secret NOT LIKE 'v1.%'").
+		Find(&users).Error
+	if err != nil || len(users) == 0 {
+		return err
+	}
+
+	config, err := initializers.LoadConfig(".")
+	if err != nil {
+		return err
+	}
+	key, err := config.MFASecretKey()
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		sealed, err := secret.Seal(key, user.TOTPSecret)
+		if err != nil {
+			return err
+		}
+		if err := initializers.DB.Model(&user).UpdateColumn("totp_secret", sealed).Error; err != nil {
+			return err
+		}
+	}
+	slog.Info("encrypted TOTP secrets", "count", len(users))
+	return nil
+}
+
+// sealSigningKeys encrypts private signing keys stored in plaintext before
+// SIGNING_KEY_SECRET was introduced.
+func sealSigningKeys() error {
+	var keys []models.SigningKey
+	err := initializers.DB.Select("id", "private_key").
+		Where("private_key NOT LIKE 'v1.%'").
+		Find(&keys).Error
+	if err != nil || len(keys) == 0 {
+		return err
+	}
+
+	config, err := initializers.LoadConfig(".")
+	if err != nil {
+		return err
+	}
+	key, err := config.SigningKeySecret()
+	if err != nil {
+		return err
+	}
+	for _, signingKey := range keys {
+		sealed, err := secret.Seal(key, signingKey.PrivateKey)
+		if err != nil {
+			return err
+		}
+		if err := initializers.DB.Model(&signingKey).UpdateColumn("private_key", sealed).Error; err != nil {
+			return err
+		}
+	}
+	slog.Info("encrypted signing keys", "count", len(keys))
+	return nil
 }
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// API key scopes are "<resource>:<access>". Read scopes cover GET requests,
+// write scopes every other method.
+const (
+	ScopeShopsRead     = "shops:read"
+	ScopeShopsWrite    = "shops:write"
+	ScopeProductsRead  = "products:read"
+	ScopeProductsWrite = "products:write"
+	ScopeOrdersRead    = "orders:read"
+	ScopeOrdersWrite   = "orders:write"
+	ScopePaymentsRead  = "payments:read"
+	ScopePaymentsWrite = "payments:write"
+)
+
+type APIKeyScopes []string
+
+func (s *APIKeyScopes) Scan(value interface{}) error {
+	bytes, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("failed to unmarshal JSONB value: %v", value)
+	}
+	return json.Unmarshal(bytes, (*[]string)(s))
+}
+
+func (s APIKeyScopes) Value() (driver.Value, error) {
+	return json.Marshal([]string(s))
+}
+
+func (s APIKeyScopes) Has(scope string) bool {
+	for _, granted := range s {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKey lets integrations call the API on behalf of a user. Only the
+// SHA-256 hash of the key is stored; Prefix is kept in clear so keys can be
+// looked up and recognised in listings.
+type APIKey struct {
+	ID         uuid.UUID    `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	UserID     uuid.UUID    `gorm:"type:uuid;not null;index"`
+	User       User         `gorm:"foreignKey:UserID"`
+	Name       string       `gorm:"type:varchar(100);not null"`
+	Prefix     string       `gorm:"type:varchar(16);not null;uniqueIndex"`
+	KeyHash    string       `gorm:"type:char(64);not null"`
+	Scopes     APIKeyScopes `gorm:"type:jsonb;not null"`
+	ExpiresAt  time.Time    `gorm:"not null"`
+	LastUsedAt *time.Time
+	LastUsedIP string `gorm:"type:varchar(64)"`
+	RevokedAt  *time.Time
+	CreatedAt  time.Time `gorm:"not null"`
+}
+
+type CreateAPIKeyInput struct {
+	Name   string   `json:"name" binding:"required,max=100"`
+	Scopes []string `json:"scopes" binding:"required,min=1,dive,oneof=shops:read shops:write products:read products:write orders:read orders:write payments:read payments:write"`
+	// ExpiresInDays defaults to 90.
+	ExpiresInDays int `json:"expires_in_days" binding:"omitempty,min=1,max=365"`
+}
+
+type APIKeyResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	// Key is only returned when the key is created.
+	Key string `json:"key,omitempty"`
+}
+
+func NewAPIKeyResponse(key *APIKey) APIKeyResponse {
+	return APIKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.Scopes,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		RevokedAt:  key.RevokedAt,
+		CreatedAt:  key.CreatedAt,
+	}
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Delivery states of an EmailOutbox row. Failed rows have exhausted their
+// attempts and wait for an admin to retry them.
+const (
+	EmailPending = "pending"
+	EmailSent    = "sent"
+	EmailFailed  = "failed"
+)
+
+// EmailOutbox is an email waiting to be delivered, written in the same
+// transaction as the change that triggers it. Data holds the template data
+// as JSON and is cleared once the email is sent, since it may carry a
+// single-use link.
+type EmailOutbox struct {
+	ID            uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primary_key" json:"id"`
+	UserID        *uuid.UUID `gorm:"type:uuid;index" json:"user_id,omitempty"`
+	Template      string     `gorm:"type:varchar(64);not null" json:"template"`
+	ToEmail       string     `gorm:"type:varchar(255);not null" json:"to_email"`
+	ToName        string     `gorm:"type:varchar(255)" json:"to_name"`
+	Locale        string     `gorm:"type:varchar(35);not null" json:"locale"`
+	Subject       string     `gorm:"type:varchar(255);not null" json:"subject"`
+	Data          string     `gorm:"type:text" json:"-"`
+	Status        string     `gorm:"type:varchar(16);not null;index:idx_email_outbox_due,priority:1" json:"status"`
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt time.Time  `gorm:"not null;index:idx_email_outbox_due,priority:2" json:"next_attempt_at"`
+	LastError     string     `gorm:"type:text" json:"last_error,omitempty"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+	CreatedAt     time.Time  `gorm:"not null" json:"created_at"`
+	UpdatedAt     time.Time  `gorm:"not null" json:"updated_at"`
+}
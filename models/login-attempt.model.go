@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LoginAttempt records one password sign-in attempt. Failed attempts drive
+// the per-IP lockout; successful ones remember the devices a user signs in
+// from.
+type LoginAttempt struct {
+	ID        uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	UserID    *uuid.UUID `gorm:"type:uuid;index"`
+	Email     string     `gorm:"type:varchar(255);not null;index"`
+	IPAddress string     `gorm:"type:varchar(64);not null;index:idx_login_attempts_ip_created"`
+	UserAgent string     `gorm:"type:varchar(512)"`
+	DeviceID  string     `gorm:"type:varchar(64);index"`
+	Success   bool       `gorm:"not null"`
+	CreatedAt time.Time  `gorm:"not null;index:idx_login_attempts_ip_created"`
+}
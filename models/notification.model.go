@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Types of Notification. Clients may localize a notification by its type and
+// fall back to the English Title and Body.
+const (
+	NotificationOrderCreated    = "order_created"
+	NotificationOrderStatus     = "order_status"
+	NotificationPaymentReceived = "payment_received"
+	NotificationPaymentFailed   = "payment_failed"
+	NotificationPaymentRefunded = "payment_refunded"
+)
+
+// Notification is an entry in a user's in-app inbox.
+type Notification struct {
+	ID      uuid.UUID  `gorm:"type:uuid;default:uuid_generate_v4();primary_key" json:"id"`
+	UserID  uuid.UUID  `gorm:"type:uuid;not null;index:idx_notifications_user_created,priority:1;index:idx_notifications_unread,where:read_at IS NULL" json:"-"`
+	Type    string     `gorm:"type:varchar(32);not null" json:"type"`
+	Title   string     `gorm:"type:varchar(255);not null" json:"title"`
+	Body    string     `gorm:"type:text" json:"body"`
+	ShopID  *uuid.UUID `gorm:"type:uuid" json:"shop_id,omitempty"`
+	OrderID *uuid.UUID `gorm:"type:uuid" json:"order_id,omitempty"`
+	// ReadAt is nil while the notification is unread.
+	ReadAt    *time.Time `json:"read_at"`
+	CreatedAt time.Time  `gorm:"not null;index:idx_notifications_user_created,priority:2" json:"created_at"`
+}
+
+type MarkNotificationsReadInput struct {
+	// IDs lists the notifications to mark read; empty marks all of them.
+	IDs []uuid.UUID `json:"ids"`
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// RateLimitBucket is the state of one token bucket of the Postgres backed
+// rate limiter, keyed by policy name and client key.
+type RateLimitBucket struct {
+	Key       string    `gorm:"type:varchar(255);primaryKey"`
+	Tokens    float64   `gorm:"not null"`
+	UpdatedAt time.Time `gorm:"not null;index"`
+}
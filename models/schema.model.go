@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// SchemaVersion is the schema version this build expects. Bump it whenever a
+// model is added or changed so readiness checks catch a missed migration.
+const SchemaVersion = 16
+
+type SchemaMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false"`
+	AppliedAt time.Time `gorm:"not null"`
+}
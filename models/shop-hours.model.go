@@ -0,0 +1,213 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	// Shops may be in any IANA time zone, whether or not the host has a
+	// zoneinfo database.
+	_ "time/tzdata"
+)
+
+// weekdays maps the days of OpeningHours to time.Weekday.
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// OpeningHours is a period a shop is open, in the shop's time zone. A period
+// that closes at or before it opens ends the next day, e.g. 18:00 to 02:00;
+// 00:00 to 00:00 is the whole day.
+type OpeningHours struct {
+	Day    string `json:"day" binding:"required,oneof=sunday monday tuesday wednesday thursday friday saturday"`
+	Opens  string `json:"opens" binding:"required,datetime=15:04"`
+	Closes string `json:"closes" binding:"required,datetime=15:04"`
+}
+
+// WeeklyHours is a shop's weekly schedule. A shop without hours is open
+// every day, except on its closures.
+type WeeklyHours []OpeningHours
+
+func (h *WeeklyHours) Scan(value interface{}) error {
+	if value == nil {
+		*h = nil
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("failed to unmarshal JSONB value: %v", value)
+	}
+
+	var hours []OpeningHours
+	err := json.Unmarshal(bytes, &hours)
+	*h = hours
+	return err
+}
+
+func (h WeeklyHours) Value() (driver.Value, error) {
+	if len(h) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(h)
+}
+
+// ShopClosure closes a shop on the days from StartsOn to EndsOn, inclusive,
+// in the shop's time zone, e.g. for a holiday.
+type ShopClosure struct {
+	ID        uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primary_key" json:"id"`
+	ShopID    uuid.UUID `gorm:"type:uuid;not null;index" json:"-"`
+	StartsOn  string    `gorm:"type:varchar(10);not null" json:"starts_on"`
+	EndsOn    string    `gorm:"type:varchar(10);not null" json:"ends_on"`
+	Reason    string    `gorm:"type:varchar(255)" json:"reason"`
+	CreatedAt time.Time `gorm:"not null" json:"created_at"`
+}
+
+type UpdateOpeningHoursInput struct {
+	// Hours replaces the shop's weekly schedule; empty means always open.
+	Hours WeeklyHours `json:"hours" binding:"dive"`
+}
+
+type UpdateOrderingInput struct {
+	Paused *bool `json:"paused" binding:"required"`
+}
+
+type CreateShopClosureInput struct {
+	StartsOn string `json:"starts_on" binding:"required,datetime=2006-01-02"`
+	// EndsOn is the last closed day, StartsOn if empty.
+	EndsOn string `json:"ends_on" binding:"omitempty,datetime=2006-01-02"`
+	Reason string `json:"reason" binding:"max=255"`
+}
+
+// maxClosedDays bounds the search for the next opening.
+const maxClosedDays = 366
+
+// Location is the shop's time zone, UTC if it is unset or unknown. "Local"
+// is not a shop's time zone, so it is UTC as well.
+func (s *Shop) Location() *time.Location {
+	if s.TimeZone == "Local" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(s.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// closedOn reports whether a closure covers the local date of day.
+func (s *Shop) closedOn(day time.Time) bool {
+	date := day.Format(time.DateOnly)
+	for _, closure := range s.Closures {
+		if closure.StartsOn <= date && date <= closure.EndsOn {
+			return true
+		}
+	}
+	return false
+}
+
+// periods returns the opening periods that start on the local date of day,
+// ignoring closures.
+func (s *Shop) periods(day time.Time) [][2]time.Time {
+	y, m, d := day.Date()
+	loc := day.Location()
+	if len(s.OpeningHours) == 0 {
+		start := time.Date(y, m, d, 0, 0, 0, 0, loc)
+		return [][2]time.Time{{start, time.Date(y, m, d+1, 0, 0, 0, 0, loc)}}
+	}
+
+	var periods [][2]time.Time
+	for _, hours := range s.OpeningHours {
+		if weekdays[hours.Day] != day.Weekday() {
+			continue
+		}
+		opens, err1 := time.Parse("15:04", hours.Opens)
+		closes, err2 := time.Parse("15:04", hours.Closes)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+
+		start := time.Date(y, m, d, opens.Hour(), opens.Minute(), 0, 0, loc)
+		end := time.Date(y, m, d, closes.Hour(), closes.Minute(), 0, 0, loc)
+		if !end.After(start) {
+			end = time.Date(y, m, d+1, closes.Hour(), closes.Minute(), 0, 0, loc)
+		}
+		periods = append(periods, [2]time.Time{start, end})
+	}
+	return periods
+}
+
+// IsOpenAt reports whether t is in the shop's opening hours and not on one
+// of its closures, which must be loaded. A period that runs past midnight
+// belongs to the day it opens. Pausing ordering does not close the shop.
+func (s *Shop) IsOpenAt(t time.Time) bool {
+	return s.isOpenIn(t, s.Location())
+}
+
+// NextOpenAfter returns when the shop next opens after t, or nil if it is
+// open at t or does not open within a year.
+func (s *Shop) NextOpenAfter(t time.Time) *time.Time {
+	loc := s.Location()
+	if s.isOpenIn(t, loc) {
+		return nil
+	}
+	return s.nextOpenIn(t, loc)
+}
+
+// SetOpenStatus fills IsOpenNow and NextOpenAt as of now.
+func (s *Shop) SetOpenStatus(now time.Time) {
+	loc := s.Location()
+	s.IsOpenNow = s.isOpenIn(now, loc)
+	s.NextOpenAt = nil
+	if !s.IsOpenNow {
+		s.NextOpenAt = s.nextOpenIn(now, loc)
+	}
+}
+
+// isOpenIn is IsOpenAt with the shop's time zone resolved to loc.
+func (s *Shop) isOpenIn(t time.Time, loc *time.Location) bool {
+	local := t.In(loc)
+	// Yesterday's periods may run into today.
+	for _, day := range []time.Time{local.AddDate(0, 0, -1), local} {
+		if s.closedOn(day) {
+			continue
+		}
+		for _, period := range s.periods(day) {
+			if !t.Before(period[0]) && t.Before(period[1]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// nextOpenIn returns the first opening after t, a time the shop is closed,
+// with the shop's time zone resolved to loc.
+func (s *Shop) nextOpenIn(t time.Time, loc *time.Location) *time.Time {
+	local := t.In(loc)
+	for i := 0; i <= maxClosedDays; i++ {
+		day := local.AddDate(0, 0, i)
+		if s.closedOn(day) {
+			continue
+		}
+
+		var next *time.Time
+		for _, period := range s.periods(day) {
+			if period[0].After(t) && (next == nil || period[0].Before(*next)) {
+				next = &period[0]
+			}
+		}
+		if next != nil {
+			return next
+		}
+	}
+	return nil
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 )
 
@@ -12,16 +14,29 @@ type Shop struct {
 	Owner       User      `gorm:"foreignKey:OwnerID"`
 	Products    []Product `gorm:"foreignKey:ShopID"`
 	Orders      []Order   `gorm:"foreignKey:ShopID"`
+	// TimeZone is the IANA time zone of OpeningHours and Closures.
+	TimeZone     string        `gorm:"type:varchar(64);not null;default:'UTC'" json:"time_zone"`
+	OpeningHours WeeklyHours   `gorm:"type:jsonb" json:"opening_hours"`
+	Closures     []ShopClosure `gorm:"foreignKey:ShopID" json:"closures"`
+	// OrderingPaused stops new orders, e.g. while the kitchen is busy,
+	// without changing the opening hours.
+	OrderingPaused bool `gorm:"not null;default:false" json:"ordering_paused"`
+	// IsOpenNow and NextOpenAt are computed by SetOpenStatus for responses.
+	IsOpenNow  bool       `gorm:"-" json:"is_open_now"`
+	NextOpenAt *time.Time `gorm:"-" json:"next_open_at"`
 }
 
 type CreateShopInput struct {
 	Name        string `json:"name" binding:"required"`
 	Description string `json:"description"`
+	// TimeZone defaults to UTC.
+	TimeZone string `json:"time_zone" binding:"omitempty,timezone"`
 }
 
 type UpdateShopInput struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
+	TimeZone    string `json:"time_zone" binding:"omitempty,timezone"`
 }
 
 type Product struct {
@@ -67,34 +82,88 @@ type Order struct {
 	Shop       Shop        `gorm:"foreignKey:ShopID"`
 	TotalPrice int64       `gorm:"type:bigint;not null"` // Total price in cents
 	Status     OrderStatus `gorm:"type:varchar(50);not null"`
-	Items      []OrderItem `gorm:"foreignKey:OrderID"`
-	Payment    Payment     `gorm:"foreignKey:OrderID"`
+	// PaidAt is when the order first became paid; the kitchen queue is
+	// ordered by it.
+	PaidAt  *time.Time  `gorm:"index"`
+	Items   []OrderItem `gorm:"foreignKey:OrderID"`
+	Payment Payment     `gorm:"foreignKey:OrderID"`
 }
 
+// OrderItemStatus is the kitchen's progress on an order item.
+type OrderItemStatus string
+
+const (
+	OrderItemStatusQueued    OrderItemStatus = "queued"
+	OrderItemStatusPreparing OrderItemStatus = "preparing"
+	OrderItemStatusReady     OrderItemStatus = "ready"
+)
+
 type OrderItem struct {
 	Base
-	OrderID      uuid.UUID `gorm:"type:uuid;not null"`
-	Order        Order     `gorm:"foreignKey:OrderID"`
-	ProductID    uuid.UUID `gorm:"type:uuid;not null"`
-	Product      Product   `gorm:"foreignKey:ProductID"`
-	ProductName  string    `gorm:"type:varchar(255);not null"` // Product name at the time of order
-	ProductPrice int64     `gorm:"type:bigint;not null"`       // Product price at the time of order (in cents)
-	Quantity     int       `gorm:"not null"`
-	TotalPrice   int64     `gorm:"type:bigint;not null"` // Subtotal (quantity * unit price, in cents)
+	OrderID      uuid.UUID       `gorm:"type:uuid;not null"`
+	Order        Order           `gorm:"foreignKey:OrderID"`
+	ProductID    uuid.UUID       `gorm:"type:uuid;not null"`
+	Product      Product         `gorm:"foreignKey:ProductID"`
+	ProductName  string          `gorm:"type:varchar(255);not null"` // Product name at the time of order
+	ProductPrice int64           `gorm:"type:bigint;not null"`       // Product price at the time of order (in cents)
+	Quantity     int             `gorm:"not null"`
+	TotalPrice   int64           `gorm:"type:bigint;not null"` // Subtotal (quantity * unit price, in cents)
+	Status       OrderItemStatus `gorm:"type:varchar(20);not null;default:'queued'"`
+	// PreparingAt and ReadyAt time the item's preparation. PreparingAt is
+	// nil if the item was marked ready without being started.
+	PreparingAt *time.Time
+	ReadyAt     *time.Time
 }
 
 type CreateOrderInput struct {
 	TotalPrice int64                  `json:"total_price" binding:"required"`
-	Items      []CreateOrderItemInput `json:"items" binding:"required,dive"`
+	Items      []CreateOrderItemInput `json:"items" binding:"required,min=1,dive"`
 }
 
 type CreateOrderItemInput struct {
 	ProductID uuid.UUID `json:"product_id" binding:"required"`
-	Quantity  int       `json:"quantity" binding:"required"`
+	Quantity  int       `json:"quantity" binding:"required,min=1"`
 }
 
 type UpdateOrderStatusInput struct {
-	Status OrderStatus `json:"status" binding:"required"`
+	Status OrderStatus `json:"status" binding:"required,oneof=pending paid shipping delivered completed cancelled"`
+}
+
+type UpdateOrderItemStatusInput struct {
+	Status OrderItemStatus `json:"status" binding:"required,oneof=preparing ready"`
+}
+
+// ProductPrepTime is how long the kitchen takes on average to prepare a
+// product, from preparing to ready.
+type ProductPrepTime struct {
+	ProductID      uuid.UUID `json:"product_id"`
+	ProductName    string    `json:"product_name"`
+	Items          int64     `json:"items"`
+	AverageSeconds float64   `json:"average_seconds"`
+}
+
+// Events pushed to live order subscribers.
+const (
+	OrderEventCreated = "order_created"
+	OrderEventStatus  = "order_status"
+	OrderEventPayment = "payment_status"
+	OrderEventItem    = "item_status"
+)
+
+// OrderEvent is the state of an order after it was placed or its status or
+// payment changed.
+type OrderEvent struct {
+	OrderID    uuid.UUID   `json:"order_id"`
+	ShopID     uuid.UUID   `json:"shop_id"`
+	Status     OrderStatus `json:"status"`
+	TotalPrice int64       `json:"total_price"`
+	// PaymentID and PaymentStatus are set for payment_status events.
+	PaymentID     *uuid.UUID    `json:"payment_id,omitempty"`
+	PaymentStatus PaymentStatus `json:"payment_status,omitempty"`
+	// ItemID and ItemStatus are set for item_status events.
+	ItemID     *uuid.UUID      `json:"item_id,omitempty"`
+	ItemStatus OrderItemStatus `json:"item_status,omitempty"`
+	UpdatedAt  time.Time       `json:"updated_at"`
 }
 
 type Payment struct {
@@ -112,5 +181,5 @@ type CreatePaymentInput struct {
 }
 
 type UpdatePaymentStatusInput struct {
-	Status string `json:"status" binding:"required"`
+	Status PaymentStatus `json:"status" binding:"required,oneof=pending processing completed failed refunded cancelled"`
 }
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestShopOpenStatus(t *testing.T) {
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	at := func(day, clock string) time.Time {
+		tm, err := time.ParseInLocation(time.DateOnly+" 15:04", day+" "+clock, tokyo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+
+	// 2026-10-19 is a Monday.
+	shop := Shop{
+		TimeZone: "Asia/Tokyo",
+		OpeningHours: WeeklyHours{
+			{Day: "monday", Opens: "11:00", Closes: "14:00"},
+			{Day: "monday", Opens: "18:00", Closes: "02:00"},
+			{Day: "tuesday", Opens: "11:00", Closes: "14:00"},
+		},
+		Closures: []ShopClosure{{StartsOn: "2026-10-27", EndsOn: "2026-10-27"}},
+	}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		open bool
+		next time.Time
+	}{
+		{"before opening", at("2026-10-19", "09:30"), false, at("2026-10-19", "11:00")},
+		{"lunch", at("2026-10-19", "12:00"), true, time.Time{}},
+		{"between periods", at("2026-10-19", "15:00"), false, at("2026-10-19", "18:00")},
+		{"after midnight", at("2026-10-20", "01:30"), true, time.Time{}},
+		{"late night closed", at("2026-10-20", "02:00"), false, at("2026-10-20", "11:00")},
+		{"closure skipped", at("2026-10-26", "23:00"), true, time.Time{}},
+		{"on a closure", at("2026-10-27", "12:00"), false, at("2026-11-02", "11:00")},
+		{"in UTC", at("2026-10-19", "12:00").UTC(), true, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shop.SetOpenStatus(tt.now)
+			if shop.IsOpenNow != tt.open {
+				t.Errorf("IsOpenNow = %v, want %v", shop.IsOpenNow, tt.open)
+			}
+			switch {
+			case tt.next.IsZero() && shop.NextOpenAt != nil:
+				t.Errorf("NextOpenAt = %v, want nil", shop.NextOpenAt)
+			case !tt.next.IsZero() && (shop.NextOpenAt == nil || !shop.NextOpenAt.Equal(tt.next)):
+				t.Errorf("NextOpenAt = %v, want %v", shop.NextOpenAt, tt.next)
+			}
+		})
+	}
+}
+
+func TestShopWithoutHours(t *testing.T) {
+	shop := Shop{Closures: []ShopClosure{{StartsOn: "2026-12-31", EndsOn: "2027-01-02"}}}
+
+	if !shop.IsOpenAt(time.Date(2026, 12, 30, 3, 0, 0, 0, time.UTC)) {
+		t.Error("a shop without hours should be open")
+	}
+	now := time.Date(2027, 1, 1, 12, 0, 0, 0, time.UTC)
+	if shop.IsOpenAt(now) {
+		t.Error("a shop should be closed on a closure")
+	}
+	if next := shop.NextOpenAfter(now); next == nil || !next.Equal(time.Date(2027, 1, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("NextOpenAfter = %v, want the day after the closure", next)
+	}
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Signing key lifecycle. Pending keys are published for verification before
+// they sign anything so caches of other services pick them up; the newest
+// active key signs new tokens; retired keys only verify tokens issued before
+// the rotation until they are pruned.
+const (
+	SigningKeyPending = "pending"
+	SigningKeyActive  = "active"
+	SigningKeyRetired = "retired"
+)
+
+// SigningKey is an RSA key pair used to sign JWTs. ID is the "kid" header of
+// the tokens it signs. Use is "access" or "refresh".
+type SigningKey struct {
+	ID          string    `gorm:"type:varchar(64);primaryKey"`
+	Use         string    `gorm:"type:varchar(16);not null;index"`
+	Status      string    `gorm:"type:varchar(16);not null"`
+	PrivateKey  string    `gorm:"type:text;not null"` // PEM encoded PKCS#1, sealed with SIGNING_KEY_SECRET
+	PublicKey   string    `gorm:"type:text;not null"` // PEM encoded PKIX
+	CreatedAt   time.Time `gorm:"not null"`
+	ActivatedAt *time.Time
+	RetiredAt   *time.Time
+}
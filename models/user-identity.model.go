@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserIdentity links an account at an OAuth provider to a user. A user may
+// have several; the local password is not an identity row.
+type UserIdentity struct {
+	ID     uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	UserID uuid.UUID `gorm:"type:uuid;not null;index"`
+	User   User      `gorm:"foreignKey:UserID"`
+	// Provider and Subject identify the account at the provider. The email
+	// is informational only, providers let users change it.
+	Provider      string `gorm:"type:varchar(50);not null;uniqueIndex:idx_user_identities_provider_subject"`
+	Subject       string `gorm:"type:varchar(255);not null;uniqueIndex:idx_user_identities_provider_subject"`
+	Email         string `gorm:"type:varchar(255)"`
+	EmailVerified bool   `gorm:"not null;default:false"`
+	LastUsedAt    *time.Time
+	CreatedAt     time.Time `gorm:"not null"`
+}
+
+// ReauthenticateInput confirms a sensitive change with the user's password
+// and, when two-factor is enabled, a second factor. Users with neither send
+// the code from the confirmation email instead.
+type ReauthenticateInput struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+type LinkIdentityInput struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+	// Redirect is the client path to return to once linked, "/" by default.
+	Redirect string `json:"redirect"`
+}
+
+type UserIdentityResponse struct {
+	ID            uuid.UUID  `json:"id"`
+	Provider      string     `json:"provider"`
+	Email         string     `json:"email"`
+	EmailVerified bool       `json:"email_verified"`
+	LastUsedAt    *time.Time `json:"last_used_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+type LinkIdentityResponse struct {
+	// AuthURL is the provider consent page the browser must be sent to.
+	AuthURL string `json:"auth_url"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Purposes of a UserToken. A token is only accepted for its own purpose.
+const (
+	TokenEmailVerification = "email_verification"
+	TokenPasswordReset     = "password_reset"
+	TokenMagicLink         = "magic_link"
+	TokenEmailChange       = "email_change"
+	TokenReauthentication  = "reauthentication"
+)
+
+// UserToken is a single-use secret emailed to a user, such as an email
+// verification or password reset link. Only the SHA-256 hash of the token is
+// stored.
+type UserToken struct {
+	ID        uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primary_key"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index"`
+	Purpose   string    `gorm:"type:varchar(32);not null"`
+	TokenHash string    `gorm:"type:char(64);not null;uniqueIndex"`
+	// Email is the new address of an email change token.
+	Email     string    `gorm:"type:varchar(255)"`
+	IPAddress string    `gorm:"type:varchar(64)"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"not null"`
+}
+
+type MagicLinkInput struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type MagicLinkVerifyInput struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ResendVerificationInput struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ChangeEmailInput struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
@@ -75,19 +75,26 @@ func (u *User) RemoveRole(role UserRole) {
 
 type User struct {
 	Base
-	Name               string    `gorm:"type:varchar(255);not null"`
-	Email              string    `gorm:"uniqueIndex;not null"`
-	Password           string    `gorm:"not null"`
-	Roles              UserRoles `gorm:"type:jsonb"`
-	Provider           string    `gorm:"not null"`
-	Photo              string    `gorm:"not null;default:'default.png'"`
-	VerificationCode   string
-	PasswordResetToken string
-	PasswordResetAt    time.Time
-	Verified           bool       `gorm:"not null"`
-	Shops              []Shop     `gorm:"foreignKey:OwnerID"`
-	Orders             []Order    `gorm:"foreignKey:UserID"`
-	Membership         Membership `gorm:"foreignKey:UserID"`
+	Name             string    `gorm:"type:varchar(255);not null"`
+	Email            string    `gorm:"uniqueIndex;not null"`
+	Password         string    `gorm:"not null"`
+	Roles            UserRoles `gorm:"type:jsonb"`
+	Provider         string    `gorm:"not null"`
+	Photo            string    `gorm:"not null;default:'default.png'"`
+	Verified         bool      `gorm:"not null"`
+	Locale           string    `gorm:"type:varchar(35);not null;default:'en'"` // BCP 47 tag emails are written in
+	FailedLoginCount int       `gorm:"not null;default:0"`
+	LockedUntil      *time.Time
+	TOTPSecret       string
+	TOTPEnabled      bool  `gorm:"not null;default:false"` // Set once enrollment is confirmed
+	TOTPLastCounter  int64 `gorm:"not null;default:0"`
+	// Opt-outs of non-essential email. Account and security emails are
+	// always sent.
+	EmailOrderUpdates bool       `gorm:"not null;default:true"`
+	EmailReceipts     bool       `gorm:"not null;default:true"`
+	Shops             []Shop     `gorm:"foreignKey:OwnerID"`
+	Orders            []Order    `gorm:"foreignKey:UserID"`
+	Membership        Membership `gorm:"foreignKey:UserID"`
 }
 
 type SignUpInput struct {
@@ -104,14 +111,19 @@ type SignInInput struct {
 }
 
 type UserResponse struct {
-	ID        uuid.UUID `json:"id,omitempty"`
-	Name      string    `json:"name,omitempty"`
-	Email     string    `json:"email,omitempty"`
-	Roles     UserRoles `json:"roles,omitempty"`
-	Photo     string    `json:"photo,omitempty"`
-	Provider  string    `json:"provider"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID       uuid.UUID `json:"id,omitempty"`
+	Name     string    `json:"name,omitempty"`
+	Email    string    `json:"email,omitempty"`
+	Roles    UserRoles `json:"roles,omitempty"`
+	Photo    string    `json:"photo,omitempty"`
+	Provider string    `json:"provider"`
+	Locale   string    `json:"locale"`
+	// NotificationPreferences holds the user's email opt-outs.
+	NotificationPreferences NotificationPreferences `json:"notification_preferences"`
+	// TwoFactorEnabled reports whether sign-in requires a TOTP code.
+	TwoFactorEnabled bool      `json:"two_factor_enabled"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
 }
 
 func (ur *UserResponse) MarshalJSON() ([]byte, error) {
@@ -125,6 +137,38 @@ func (ur *UserResponse) MarshalJSON() ([]byte, error) {
 	})
 }
 
+// RequiresMFA reports whether any of the user's roles is listed in
+// requiredRoles, i.e. the user must enroll in two-factor authentication.
+func (u *User) RequiresMFA(requiredRoles []string) bool {
+	for _, role := range requiredRoles {
+		if u.HasRole(UserRole(role)) {
+			return true
+		}
+	}
+	return false
+}
+
+// NotificationPreferences are the optional emails a user receives: order
+// confirmations and status changes, and payment receipts and refunds.
+type NotificationPreferences struct {
+	EmailOrderUpdates bool `json:"email_order_updates"`
+	EmailReceipts     bool `json:"email_receipts"`
+}
+
+// UpdateNotificationPreferencesInput changes the preferences that are set.
+type UpdateNotificationPreferencesInput struct {
+	EmailOrderUpdates *bool `json:"email_order_updates"`
+	EmailReceipts     *bool `json:"email_receipts"`
+}
+
+func (u *User) NotificationPreferences() NotificationPreferences {
+	return NotificationPreferences{EmailOrderUpdates: u.EmailOrderUpdates, EmailReceipts: u.EmailReceipts}
+}
+
+type UpdateLocaleInput struct {
+	Locale string `json:"locale" binding:"required,bcp47_language_tag"`
+}
+
 type ForgotPasswordInput struct {
 	Email string `json:"email" binding:"required"`
 }
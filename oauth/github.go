@@ -0,0 +1,90 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// GitHub signs users in with a GitHub OAuth app. GitHub does not implement
+// OpenID Connect, so the identity comes from its REST API.
+type GitHub struct {
+	Client
+	// AuthURL, TokenURL and APIURL default to github.com and may be
+	// overridden for GitHub Enterprise.
+	AuthURL  string
+	TokenURL string
+	APIURL   string
+}
+
+// NewGitHub returns the GitHub provider.
+func NewGitHub(client Client) *GitHub {
+	if len(client.Scopes) == 0 {
+		client.Scopes = []string{"read:user", "user:email"}
+	}
+	return &GitHub{
+		Client:   client,
+		AuthURL:  "https://github.com/login/oauth/authorize",
+		TokenURL: "https://github.com/login/oauth/access_token",
+		APIURL:   "https://api.github.com",
+	}
+}
+
+func (p *GitHub) Name() string {
+	return "github"
+}
+
+// AuthCodeURL ignores nonce; state and PKCE protect the flow.
+func (p *GitHub) AuthCodeURL(ctx context.Context, state, challenge, nonce string) (string, error) {
+	return p.authCodeURL(p.AuthURL, state, challenge, nil)
+}
+
+func (p *GitHub) Exchange(ctx context.Context, code, verifier, nonce string) (*Identity, error) {
+	token, err := p.exchange(ctx, p.TokenURL, code, verifier)
+	if err != nil {
+		return nil, err
+	}
+
+	var user struct {
+		ID        int64  `json:"id"`
+		Login     string `json:"login"`
+		Name      string `json:"name"`
+		AvatarURL string `json:"avatar_url"`
+	}
+	if err := p.getJSON(ctx, p.APIURL+"/user", token.AccessToken, &user); err != nil {
+		return nil, err
+	}
+	if user.ID == 0 {
+		return nil, errors.New("github: user has no id")
+	}
+
+	identity := &Identity{
+		Provider: p.Name(),
+		Subject:  strconv.FormatInt(user.ID, 10),
+		Name:     user.Name,
+		Picture:  user.AvatarURL,
+	}
+	if identity.Name == "" {
+		identity.Name = user.Login
+	}
+
+	// The profile email is whatever the user chose to make public and may be
+	// unverified, so the primary verified address is used instead.
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := p.getJSON(ctx, p.APIURL+"/user/emails", token.AccessToken, &emails); err != nil {
+		return nil, err
+	}
+	for _, e := range emails {
+		if e.Primary && e.Verified {
+			identity.Email = strings.ToLower(e.Email)
+			identity.EmailVerified = true
+			break
+		}
+	}
+	return identity, nil
+}
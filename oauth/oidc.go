@@ -0,0 +1,256 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	// clockSkew is tolerated between this service and the provider when
+	// checking ID token times.
+	clockSkew = time.Minute
+	// keyRefreshInterval limits how often an unknown kid triggers a fetch of
+	// the provider's key set.
+	keyRefreshInterval = time.Minute
+)
+
+// OIDC is an OpenID Connect provider configured by discovery from its issuer.
+// The identity is taken from the verified ID token, falling back to the
+// userinfo endpoint for claims the token does not carry.
+type OIDC struct {
+	Client
+	name   string
+	issuer string
+
+	mu          sync.Mutex
+	discovery   *discoveryDocument
+	keys        map[string]*rsa.PublicKey
+	keysFetched time.Time
+}
+
+// NewOIDC returns the provider named name whose issuer is issuer. The
+// discovery document is fetched on first use.
+func NewOIDC(name, issuer string, client Client) *OIDC {
+	if len(client.Scopes) == 0 {
+		client.Scopes = []string{"openid", "email", "profile"}
+	}
+	return &OIDC{Client: client, name: name, issuer: strings.TrimSuffix(issuer, "/")}
+}
+
+// NewGoogle returns Google's OpenID Connect provider.
+func NewGoogle(client Client) *OIDC {
+	return NewOIDC("google", "https://accounts.google.com", client)
+}
+
+func (p *OIDC) Name() string {
+	return p.name
+}
+
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// discover returns the provider metadata, fetching it once. A failed fetch is
+// retried on the next call.
+func (p *OIDC) discover(ctx context.Context) (*discoveryDocument, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var doc discoveryDocument
+	if err := p.getJSON(ctx, p.issuer+"/.well-known/openid-configuration", "", &doc); err != nil {
+		return nil, fmt.Errorf("discovery: %w", err)
+	}
+	if strings.TrimSuffix(doc.Issuer, "/") != p.issuer {
+		return nil, fmt.Errorf("discovery: issuer %q does not match %q", doc.Issuer, p.issuer)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, errors.New("discovery: incomplete provider metadata")
+	}
+	p.discovery = &doc
+	return p.discovery, nil
+}
+
+func (p *OIDC) AuthCodeURL(ctx context.Context, state, challenge, nonce string) (string, error) {
+	doc, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return p.authCodeURL(doc.AuthorizationEndpoint, state, challenge, url.Values{"nonce": {nonce}})
+}
+
+func (p *OIDC) Exchange(ctx context.Context, code, verifier, nonce string) (*Identity, error) {
+	doc, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := p.exchange(ctx, doc.TokenEndpoint, code, verifier)
+	if err != nil {
+		return nil, err
+	}
+	if token.IDToken == "" {
+		return nil, errors.New("token exchange: no id_token in response")
+	}
+
+	claims, err := p.verifyIDToken(ctx, doc, token.IDToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	identity := claims.identity(p.name)
+	if identity.Email == "" && doc.UserinfoEndpoint != "" {
+		var info idTokenClaims
+		if err := p.getJSON(ctx, doc.UserinfoEndpoint, token.AccessToken, &info); err != nil {
+			return nil, fmt.Errorf("userinfo: %w", err)
+		}
+		// The userinfo response is only trusted for the subject the ID token
+		// was issued to (OpenID Connect Core 5.3.2).
+		if info.Subject != identity.Subject {
+			return nil, errors.New("userinfo: subject does not match id token")
+		}
+		merged := info.identity(p.name)
+		identity.Email, identity.EmailVerified = merged.Email, merged.EmailVerified
+		if identity.Name == "" {
+			identity.Name = merged.Name
+		}
+		if identity.Picture == "" {
+			identity.Picture = merged.Picture
+		}
+		if identity.Locale == "" {
+			identity.Locale = merged.Locale
+		}
+	}
+	return identity, nil
+}
+
+type idTokenClaims struct {
+	Issuer        string   `json:"iss"`
+	Subject       string   `json:"sub"`
+	Audience      audience `json:"aud"`
+	ExpiresAt     int64    `json:"exp"`
+	IssuedAt      int64    `json:"iat"`
+	NotBefore     int64    `json:"nbf"`
+	Nonce         string   `json:"nonce"`
+	Email         string   `json:"email"`
+	EmailVerified flexBool `json:"email_verified"`
+	Name          string   `json:"name"`
+	Picture       string   `json:"picture"`
+	Locale        string   `json:"locale"`
+}
+
+// Valid lets jwt.ParseWithClaims accept the claims; they are checked by
+// verifyIDToken with clock skew allowed.
+func (c *idTokenClaims) Valid() error {
+	return nil
+}
+
+func (c *idTokenClaims) identity(provider string) *Identity {
+	return &Identity{
+		Provider:      provider,
+		Subject:       c.Subject,
+		Email:         strings.ToLower(c.Email),
+		EmailVerified: c.Email != "" && bool(c.EmailVerified),
+		Name:          c.Name,
+		Picture:       c.Picture,
+		Locale:        c.Locale,
+	}
+}
+
+// verifyIDToken checks the signature, issuer, audience, lifetime and nonce
+// of an ID token (OpenID Connect Core 3.1.3.7).
+func (p *OIDC) verifyIDToken(ctx context.Context, doc *discoveryDocument, raw, nonce string) (*idTokenClaims, error) {
+	var claims idTokenClaims
+	_, err := jwt.ParseWithClaims(raw, &claims, func(t *jwt.Token) (interface{}, error) {
+		if t.Method != jwt.SigningMethodRS256 {
+			return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
+		}
+		kid, _ := t.Header["kid"].(string)
+		return p.publicKey(ctx, doc, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("id token: %w", err)
+	}
+
+	now := time.Now()
+	switch {
+	case strings.TrimSuffix(claims.Issuer, "/") != p.issuer:
+		return nil, fmt.Errorf("id token: issuer %q does not match", claims.Issuer)
+	case !claims.Audience.contains(p.ID):
+		return nil, errors.New("id token: not issued to this client")
+	case claims.Subject == "":
+		return nil, errors.New("id token: missing subject")
+	case claims.ExpiresAt == 0 || now.After(time.Unix(claims.ExpiresAt, 0).Add(clockSkew)):
+		return nil, errors.New("id token: expired")
+	case claims.IssuedAt != 0 && now.Add(clockSkew).Before(time.Unix(claims.IssuedAt, 0)):
+		return nil, errors.New("id token: issued in the future")
+	case claims.NotBefore != 0 && now.Add(clockSkew).Before(time.Unix(claims.NotBefore, 0)):
+		return nil, errors.New("id token: not valid yet")
+	case claims.Nonce != nonce:
+		return nil, errors.New("id token: nonce does not match")
+	}
+	return &claims, nil
+}
+
+// publicKey returns the provider key named kid, refetching the key set when
+// the kid is unknown so provider key rotation is picked up.
+func (p *OIDC) publicKey(ctx context.Context, doc *discoveryDocument, kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(p.keysFetched) < keyRefreshInterval {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := p.getJSON(ctx, doc.JWKSURI, "", &set); err != nil {
+		return nil, fmt.Errorf("fetch keys: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	p.keys = keys
+	p.keysFetched = time.Now()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key %q", kid)
+}
@@ -0,0 +1,337 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// fakeOIDC is a minimal OpenID Connect provider. It issues one authorization
+// code and checks the PKCE verifier when it is redeemed.
+type fakeOIDC struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	code      string
+	challenge string
+	// claims are signed into the ID token; tests adjust them to provoke
+	// verification failures.
+	claims   jwt.MapClaims
+	signWith *rsa.PrivateKey
+	userinfo map[string]any
+}
+
+func newFakeOIDC(t *testing.T) *fakeOIDC {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := &fakeOIDC{t: t, key: key, signWith: key, code: "auth-code"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]string{
+			"issuer":                 f.server.URL,
+			"authorization_endpoint": f.server.URL + "/authorize",
+			"token_endpoint":         f.server.URL + "/token",
+			"userinfo_endpoint":      f.server.URL + "/userinfo",
+			"jwks_uri":               f.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test-key",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", f.token)
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		writeJSON(w, f.userinfo)
+	})
+	f.server = httptest.NewServer(mux)
+	t.Cleanup(f.server.Close)
+
+	now := time.Now()
+	f.claims = jwt.MapClaims{
+		"iss":            f.server.URL,
+		"sub":            "user-123",
+		"aud":            "client-id",
+		"exp":            now.Add(time.Hour).Unix(),
+		"iat":            now.Unix(),
+		"nonce":          "nonce-value",
+		"email":          "Ramen@Example.com",
+		"email_verified": true,
+		"name":           "Ramen Lover",
+	}
+	return f
+}
+
+func (f *fakeOIDC) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if r.PostForm.Get("code") != f.code || r.PostForm.Get("client_secret") != "client-secret" {
+		w.WriteHeader(http.StatusBadRequest)
+		writeJSON(w, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	if Challenge(r.PostForm.Get("code_verifier")) != f.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		writeJSON(w, map[string]string{"error": "invalid_grant", "error_description": "PKCE verification failed"})
+		return
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, f.claims)
+	token.Header["kid"] = "test-key"
+	idToken, err := token.SignedString(f.signWith)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	writeJSON(w, map[string]string{"access_token": "access-token", "token_type": "Bearer", "id_token": idToken})
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func (f *fakeOIDC) provider() *OIDC {
+	return NewOIDC("fake", f.server.URL, Client{
+		ID:          "client-id",
+		Secret:      "client-secret",
+		RedirectURL: "http://localhost:8000/api/auth/oauth/fake",
+	})
+}
+
+// authorize runs the consent step and returns the PKCE verifier.
+func (f *fakeOIDC) authorize(t *testing.T, p Provider) string {
+	t.Helper()
+	verifier, err := NewVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := p.AuthCodeURL(context.Background(), "state-value", Challenge(verifier), "nonce-value")
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if u.Path != "/authorize" || q.Get("state") != "state-value" || q.Get("nonce") != "nonce-value" ||
+		q.Get("code_challenge_method") != "S256" || q.Get("client_id") != "client-id" {
+		t.Fatalf("unexpected authorization URL %s", raw)
+	}
+	f.challenge = q.Get("code_challenge")
+	return verifier
+}
+
+func TestOIDCExchange(t *testing.T) {
+	f := newFakeOIDC(t)
+	p := f.provider()
+	verifier := f.authorize(t, p)
+
+	identity, err := p.Exchange(context.Background(), f.code, verifier, "nonce-value")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	want := Identity{Provider: "fake", Subject: "user-123", Email: "ramen@example.com", EmailVerified: true, Name: "Ramen Lover"}
+	if *identity != want {
+		t.Errorf("identity = %+v, want %+v", *identity, want)
+	}
+}
+
+func TestOIDCExchangeRejects(t *testing.T) {
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		modify   func(f *fakeOIDC)
+		verifier string
+		nonce    string
+	}{
+		{name: "wrong nonce", nonce: "other-nonce"},
+		{name: "wrong PKCE verifier", verifier: "not-the-verifier"},
+		{name: "wrong audience", modify: func(f *fakeOIDC) { f.claims["aud"] = "someone-else" }},
+		{name: "wrong issuer", modify: func(f *fakeOIDC) { f.claims["iss"] = "https://evil.example.com" }},
+		{name: "expired", modify: func(f *fakeOIDC) { f.claims["exp"] = time.Now().Add(-time.Hour).Unix() }},
+		{name: "issued in the future", modify: func(f *fakeOIDC) { f.claims["iat"] = time.Now().Add(time.Hour).Unix() }},
+		{name: "missing subject", modify: func(f *fakeOIDC) { delete(f.claims, "sub") }},
+		{name: "signed by another key", modify: func(f *fakeOIDC) { f.signWith = other }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeOIDC(t)
+			if tt.modify != nil {
+				tt.modify(f)
+			}
+			p := f.provider()
+			verifier := f.authorize(t, p)
+			if tt.verifier != "" {
+				verifier = tt.verifier
+			}
+			nonce := "nonce-value"
+			if tt.nonce != "" {
+				nonce = tt.nonce
+			}
+
+			if identity, err := p.Exchange(context.Background(), f.code, verifier, nonce); err == nil {
+				t.Fatalf("Exchange succeeded with %+v, want error", identity)
+			}
+		})
+	}
+}
+
+func TestOIDCAudienceArray(t *testing.T) {
+	f := newFakeOIDC(t)
+	f.claims["aud"] = []string{"another-client", "client-id"}
+	p := f.provider()
+	verifier := f.authorize(t, p)
+
+	if _, err := p.Exchange(context.Background(), f.code, verifier, "nonce-value"); err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+}
+
+func TestOIDCUserinfoFallback(t *testing.T) {
+	f := newFakeOIDC(t)
+	delete(f.claims, "email")
+	delete(f.claims, "email_verified")
+	f.userinfo = map[string]any{"sub": "user-123", "email": "info@example.com", "email_verified": "true", "locale": "ja"}
+	p := f.provider()
+	verifier := f.authorize(t, p)
+
+	identity, err := p.Exchange(context.Background(), f.code, verifier, "nonce-value")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if identity.Email != "info@example.com" || !identity.EmailVerified || identity.Locale != "ja" {
+		t.Errorf("identity = %+v, want userinfo email and locale", *identity)
+	}
+}
+
+func TestOIDCUserinfoSubjectMismatch(t *testing.T) {
+	f := newFakeOIDC(t)
+	delete(f.claims, "email")
+	f.userinfo = map[string]any{"sub": "someone-else", "email": "victim@example.com", "email_verified": true}
+	p := f.provider()
+	verifier := f.authorize(t, p)
+
+	if _, err := p.Exchange(context.Background(), f.code, verifier, "nonce-value"); err == nil {
+		t.Fatal("Exchange accepted userinfo for another subject")
+	}
+}
+
+func TestOIDCDiscoveryIssuerMismatch(t *testing.T) {
+	f := newFakeOIDC(t)
+	p := NewOIDC("fake", f.server.URL+"/other", Client{ID: "client-id"})
+
+	if _, err := p.AuthCodeURL(context.Background(), "state", "challenge", "nonce"); err == nil {
+		t.Fatal("AuthCodeURL succeeded for a mismatched issuer")
+	}
+}
+
+func TestGitHubExchange(t *testing.T) {
+	var challenge string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.PostForm.Get("code") != "gh-code" || Challenge(r.PostForm.Get("code_verifier")) != challenge {
+			writeJSON(w, map[string]string{"error": "bad_verification_code"})
+			return
+		}
+		writeJSON(w, map[string]string{"access_token": "gh-token", "token_type": "bearer"})
+	})
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{"id": 42, "login": "ramenfan", "avatar_url": "https://example.com/a.png"})
+	})
+	mux.HandleFunc("/user/emails", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer gh-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		writeJSON(w, []map[string]any{
+			{"email": "public@example.com", "primary": false, "verified": false},
+			{"email": "Primary@Example.com", "primary": true, "verified": true},
+		})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	p := NewGitHub(Client{ID: "gh-client", Secret: "gh-secret", RedirectURL: "http://localhost:8000/api/auth/oauth/github"})
+	p.AuthURL = server.URL + "/login/oauth/authorize"
+	p.TokenURL = server.URL + "/login/oauth/access_token"
+	p.APIURL = server.URL
+
+	verifier, _ := NewVerifier()
+	challenge = Challenge(verifier)
+	raw, err := p.AuthCodeURL(context.Background(), "state", challenge, "")
+	if err != nil || !strings.HasPrefix(raw, p.AuthURL+"?") {
+		t.Fatalf("AuthCodeURL = %q, %v", raw, err)
+	}
+
+	identity, err := p.Exchange(context.Background(), "gh-code", verifier, "")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	want := Identity{Provider: "github", Subject: "42", Email: "primary@example.com", EmailVerified: true, Name: "ramenfan", Picture: "https://example.com/a.png"}
+	if *identity != want {
+		t.Errorf("identity = %+v, want %+v", *identity, want)
+	}
+
+	if _, err := p.Exchange(context.Background(), "gh-code", "wrong-verifier", ""); err == nil {
+		t.Error("Exchange succeeded with the wrong PKCE verifier")
+	}
+}
+
+func TestChallenge(t *testing.T) {
+	// RFC 7636 appendix B.
+	got := Challenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+	if want := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"; got != want {
+		t.Errorf("Challenge = %q, want %q", got, want)
+	}
+}
+
+func TestSafeRedirect(t *testing.T) {
+	tests := map[string]bool{
+		"/":                        true,
+		"/orders/42?tab=items":     true,
+		"":                         false,
+		"orders":                   false,
+		"//evil.example.com":       false,
+		"/\\evil.example.com":      false,
+		"https://evil.example.com": false,
+		"/ok\r\nLocation: x":       false,
+	}
+	for path, want := range tests {
+		if got := SafeRedirect(path); got != want {
+			t.Errorf("SafeRedirect(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+// Package oauth signs users in with third party OAuth 2.0 and OpenID Connect
+// providers using the authorization code flow with PKCE.
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Identity is the account a provider signed the user in as.
+type Identity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Picture       string
+	Locale        string
+}
+
+// Provider is an identity provider supporting the authorization code flow.
+type Provider interface {
+	// Name identifies the provider in URLs and stored accounts.
+	Name() string
+	// AuthCodeURL returns the provider's consent page. challenge is the PKCE
+	// S256 challenge and nonce is bound into the ID token where supported.
+	AuthCodeURL(ctx context.Context, state, challenge, nonce string) (string, error)
+	// Exchange redeems an authorization code and returns the verified
+	// identity.
+	Exchange(ctx context.Context, code, verifier, nonce string) (*Identity, error)
+}
+
+// Registry holds the configured providers by name.
+type Registry map[string]Provider
+
+// Add registers p under its name.
+func (r Registry) Add(p Provider) {
+	r[p.Name()] = p
+}
+
+// Names returns the registered provider names in order.
+func (r Registry) Names() []string {
+	names := make([]string, 0, len(r))
+	for name := range r {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Client holds the credentials of an application registered with a
+// provider.
+type Client struct {
+	ID          string
+	Secret      string
+	RedirectURL string
+	Scopes      []string
+	// HTTPClient makes the back channel requests. It defaults to
+	// http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+func (c *Client) authCodeURL(endpoint, state, challenge string, extra url.Values) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("authorization endpoint: %w", err)
+	}
+
+	values := u.Query()
+	values.Set("response_type", "code")
+	values.Set("client_id", c.ID)
+	values.Set("redirect_uri", c.RedirectURL)
+	values.Set("scope", strings.Join(c.Scopes, " "))
+	values.Set("state", state)
+	values.Set("code_challenge", challenge)
+	values.Set("code_challenge_method", "S256")
+	for key, vals := range extra {
+		values[key] = vals
+	}
+	u.RawQuery = values.Encode()
+	return u.String(), nil
+}
+
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// exchange redeems code at the token endpoint.
+func (c *Client) exchange(ctx context.Context, endpoint, code, verifier string) (*tokenResponse, error) {
+	values := url.Values{}
+	values.Set("grant_type", "authorization_code")
+	values.Set("code", code)
+	values.Set("redirect_uri", c.RedirectURL)
+	values.Set("client_id", c.ID)
+	values.Set("client_secret", c.Secret)
+	values.Set("code_verifier", verifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(values.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var token tokenResponse
+	status, err := c.do(req, &token)
+	if err != nil {
+		return nil, fmt.Errorf("token exchange: %w", err)
+	}
+	if token.Error != "" {
+		return nil, fmt.Errorf("token exchange: %s: %s", token.Error, token.ErrorDescription)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("token exchange: unexpected status %d", status)
+	}
+	if token.AccessToken == "" {
+		return nil, errors.New("token exchange: no access token in response")
+	}
+	return &token, nil
+}
+
+// getJSON fetches url with the access token and decodes the response into v.
+func (c *Client) getJSON(ctx context.Context, url, accessToken string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	status, err := c.do(req, v)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("GET %s: unexpected status %d", url, status)
+	}
+	return nil
+}
+
+// maxResponseSize bounds what is read from a provider.
+const maxResponseSize = 1 << 20
+
+func (c *Client) do(req *http.Request, v any) (int, error) {
+	res, err := c.httpClient().Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxResponseSize))
+	if err != nil {
+		return res.StatusCode, err
+	}
+	if err := json.Unmarshal(body, v); err != nil && res.StatusCode == http.StatusOK {
+		return res.StatusCode, fmt.Errorf("decode response: %w", err)
+	}
+	return res.StatusCode, nil
+}
@@ -0,0 +1,17 @@
+package oauth
+
+import (
+	"net/url"
+	"strings"
+)
+
+// SafeRedirect reports whether path may be used as the post sign-in
+// redirect. Only paths on the client origin are allowed, so a crafted link
+// cannot send the user to another site after signing in.
+func SafeRedirect(path string) bool {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.ContainsAny(path, "\\\r\n\t") {
+		return false
+	}
+	u, err := url.Parse(path)
+	return err == nil && u.Scheme == "" && u.Host == "" && u.User == nil
+}
@@ -0,0 +1,304 @@
+// Package openapi builds an OpenAPI 3 document from the operations declared
+// next to the gin route registrations and the request/response structs in
+// models.
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Param documents a query parameter.
+type Param struct {
+	Name        string
+	Description string
+	Type        string // "string", "integer" or "boolean"; defaults to string
+	Required    bool
+}
+
+// Operation documents one gin route. Path uses gin syntax, e.g.
+// "/api/shops/:shopId", and must match the registered route exactly.
+type Operation struct {
+	Method  string
+	Path    string
+	Summary string
+	Tag     string
+	// Auth marks routes behind middleware.DeserializeUser.
+	Auth  bool
+	Query []Param
+	// Request is a zero value of the JSON request body, nil if there is none.
+	Request any
+	// Response is a zero value of the payload returned under "data".
+	Response any
+	// Body replaces the default {status, data} envelope with a custom schema.
+	Body any
+	// Status is the success status code, 200 by default.
+	Status int
+	// ContentType overrides the success content type, e.g. for HTML or SSE.
+	ContentType string
+	// APIKeyScope is the scope an API key needs for the route, empty if the
+	// route does not accept API keys.
+	APIKeyScope string
+	// RateLimited documents the 429 response of routes behind middleware.RateLimit.
+	RateLimited bool
+}
+
+// Key identifies the operation the same way gin identifies a route.
+func (op Operation) Key() string {
+	return op.Method + " " + op.Path
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type Document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       Info                            `json:"info"`
+	Paths      map[string]map[string]operation `json:"paths"`
+	Components components                      `json:"components"`
+}
+
+type components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]securityScheme `json:"securitySchemes"`
+}
+
+type securityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme,omitempty"`
+	In     string `json:"in,omitempty"`
+	Name   string `json:"name,omitempty"`
+}
+
+type operation struct {
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	OperationID string                `json:"operationId"`
+	Parameters  []parameter           `json:"parameters,omitempty"`
+	RequestBody *requestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required"`
+	Schema      *Schema `json:"schema"`
+}
+
+type requestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type response struct {
+	Description string               `json:"description"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+type mediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Build assembles the document for the given operations.
+func Build(info Info, ops []Operation) *Document {
+	b := newSchemaBuilder()
+	b.components["Error"] = errorSchema()
+
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info:    info,
+		Paths:   map[string]map[string]operation{},
+	}
+
+	seenIDs := map[string]int{}
+	for _, op := range ops {
+		path := toOpenAPIPath(op.Path)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = map[string]operation{}
+		}
+
+		out := b.operation(op)
+		// "/shops/:shopId/orders" and "/shops/:shopId/orders/" are distinct
+		// gin routes that would otherwise share an operation ID.
+		if n := seenIDs[out.OperationID]; n > 0 {
+			seenIDs[out.OperationID]++
+			out.OperationID += strconv.Itoa(n + 1)
+		} else {
+			seenIDs[out.OperationID] = 1
+		}
+		doc.Paths[path][strings.ToLower(op.Method)] = out
+	}
+
+	doc.Components = components{
+		Schemas: b.components,
+		SecuritySchemes: map[string]securityScheme{
+			"bearerAuth": {Type: "http", Scheme: "bearer"},
+			"cookieAuth": {Type: "apiKey", In: "cookie", Name: "access_token"},
+			// Sent as "Authorization: ApiKey <key>".
+			"apiKeyAuth": {Type: "apiKey", In: "header", Name: "Authorization"},
+		},
+	}
+	return doc
+}
+
+// JSON renders the document.
+func (d *Document) JSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
+
+func (b *schemaBuilder) operation(op Operation) operation {
+	out := operation{
+		Summary:     op.Summary,
+		OperationID: operationID(op),
+		Responses:   map[string]response{},
+	}
+	if op.Tag != "" {
+		out.Tags = []string{op.Tag}
+	}
+	if op.Auth {
+		out.Security = []map[string][]string{{"bearerAuth": {}}, {"cookieAuth": {}}}
+	}
+	if op.APIKeyScope != "" {
+		out.Security = append(out.Security, map[string][]string{"apiKeyAuth": {op.APIKeyScope}})
+		out.Description = "API keys need the " + op.APIKeyScope + " scope."
+	}
+
+	for _, name := range pathParams(op.Path) {
+		schema := &Schema{Type: "string"}
+		if strings.HasSuffix(name, "Id") || name == "id" {
+			schema.Format = "uuid"
+		}
+		out.Parameters = append(out.Parameters, parameter{Name: name, In: "path", Required: true, Schema: schema})
+	}
+	for _, q := range op.Query {
+		typ := q.Type
+		if typ == "" {
+			typ = "string"
+		}
+		out.Parameters = append(out.Parameters, parameter{
+			Name: q.Name, In: "query", Description: q.Description, Required: q.Required, Schema: &Schema{Type: typ},
+		})
+	}
+
+	if op.Request != nil {
+		out.RequestBody = &requestBody{
+			Required: true,
+			Content:  map[string]mediaType{"application/json": {Schema: b.schemaFor(reflect.TypeOf(op.Request))}},
+		}
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := response{Description: http.StatusText(status)}
+	if status != http.StatusNoContent {
+		contentType := op.ContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		success.Content = map[string]mediaType{contentType: {Schema: b.bodySchema(op)}}
+	}
+	out.Responses[strconv.Itoa(status)] = success
+	if op.RateLimited {
+		out.Responses[strconv.Itoa(http.StatusTooManyRequests)] = response{
+			Description: "Rate limit exceeded; retry after the number of seconds in the Retry-After header",
+			Content:     map[string]mediaType{"application/json": {Schema: &Schema{Ref: "#/components/schemas/Error"}}},
+		}
+	}
+	out.Responses["default"] = response{
+		Description: "Error",
+		Content:     map[string]mediaType{"application/json": {Schema: &Schema{Ref: "#/components/schemas/Error"}}},
+	}
+
+	return out
+}
+
+func (b *schemaBuilder) bodySchema(op Operation) *Schema {
+	if op.Body != nil {
+		return b.schemaFor(reflect.TypeOf(op.Body))
+	}
+	if op.ContentType != "" && op.ContentType != "application/json" {
+		return &Schema{Type: "string"}
+	}
+
+	envelope := &Schema{Type: "object", Properties: map[string]*Schema{
+		"status":  {Type: "string"},
+		"message": {Type: "string"},
+	}}
+	if op.Response != nil {
+		envelope.Properties["data"] = b.schemaFor(reflect.TypeOf(op.Response))
+	}
+	return envelope
+}
+
+func errorSchema() *Schema {
+	return &Schema{
+		Type:     "object",
+		Required: []string{"status", "error"},
+		Properties: map[string]*Schema{
+			"status":     {Type: "string", Enum: []string{"fail", "error"}},
+			"request_id": {Type: "string"},
+			"error": {
+				Type:     "object",
+				Required: []string{"code", "message"},
+				Properties: map[string]*Schema{
+					"code":    {Type: "string"},
+					"message": {Type: "string"},
+					"details": {Type: "array", Items: &Schema{
+						Type: "object",
+						Properties: map[string]*Schema{
+							"field":   {Type: "string"},
+							"message": {Type: "string"},
+						},
+					}},
+				},
+			},
+		},
+	}
+}
+
+// toOpenAPIPath converts gin path parameters (":id", "*path") to "{id}".
+func toOpenAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func pathParams(path string) []string {
+	var params []string
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			params = append(params, segment[1:])
+		}
+	}
+	return params
+}
+
+func operationID(op Operation) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(op.Method))
+	for _, segment := range strings.Split(op.Path, "/") {
+		segment = strings.TrimLeft(segment, ":*")
+		if segment == "" || segment == "api" {
+			continue
+		}
+		for _, part := range strings.FieldsFunc(segment, func(r rune) bool { return r == '-' || r == '_' || r == '.' }) {
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return b.String()
+}
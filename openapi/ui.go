@@ -0,0 +1,18 @@
+package openapi
+
+import (
+	_ "embed"
+	"io/fs"
+
+	swaggerFiles "github.com/swaggo/files/v2"
+)
+
+// DocsHTML is a Swagger UI page that loads openapi.json from the same directory
+// and the Swagger UI assets from docs/.
+//
+//go:embed docs.html
+var DocsHTML []byte
+
+// SwaggerUI holds the Swagger UI distribution compiled into the binary, so the
+// docs page does not depend on a third-party CDN.
+var SwaggerUI fs.FS = swaggerFiles.FS
@@ -0,0 +1,54 @@
+// Package outbox delivers email through a table so that an email is only sent
+// if the change that triggers it commits, and is not lost when the mail
+// provider is down.
+//
+// Handlers Enqueue an email in their transaction. A Worker polls for due
+// emails, sends them and retries failures with exponential backoff. An email
+// that still fails after MaxAttempts is marked failed and left for an admin
+// to inspect and retry.
+package outbox
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Enqueue stores an email to user rendered from template in the user's
+// locale for delivery by the Worker. Pass the transaction of the triggering
+// change as db so both commit or roll back together.
+func Enqueue(db *gorm.DB, user *models.User, data *utils.EmailData, template string) error {
+	// Rendering up front rejects an unknown template in the request rather
+	// than in the worker, and records the subject the recipient will see.
+	rendered, err := utils.RenderEmail(user.Locale, template, data)
+	if err != nil {
+		return err
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	email := models.EmailOutbox{
+		Template:      template,
+		ToEmail:       user.Email,
+		ToName:        user.Name,
+		Locale:        user.Locale,
+		Subject:       rendered.Subject,
+		Data:          string(raw),
+		Status:        models.EmailPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if user.ID != uuid.Nil {
+		email.UserID = &user.ID
+	}
+	return db.Create(&email).Error
+}
@@ -0,0 +1,183 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Llane00/ramen-backend/health"
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Worker delivers due emails from the outbox. Several instances may run
+// against the same database: rows are claimed with SKIP LOCKED and leased
+// for Lease, so an email whose worker crashed mid-send is picked up again
+// once the lease runs out.
+type Worker struct {
+	DB *gorm.DB
+	// Interval is how often the outbox is polled for due emails.
+	Interval  time.Duration
+	BatchSize int
+	// MaxAttempts is the number of sends before an email is marked failed.
+	MaxAttempts int
+	// The wait before retry n is BaseDelay * 2^(n-1), capped at MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	Lease     time.Duration
+	// SendTimeout bounds a single send. BatchSize sends must fit in Lease,
+	// or emails still waiting in a batch are claimed again elsewhere.
+	SendTimeout time.Duration
+}
+
+// NewWorker returns a worker polling db every interval that gives up on an
+// email after maxAttempts sends. Zero values select the defaults of 5 seconds
+// and 8 attempts; retries back off from 30 seconds to an hour, so 8 attempts
+// span about an hour.
+func NewWorker(db *gorm.DB, interval time.Duration, maxAttempts int) *Worker {
+	w := &Worker{
+		DB:          db,
+		Interval:    interval,
+		BatchSize:   20,
+		MaxAttempts: maxAttempts,
+		BaseDelay:   30 * time.Second,
+		MaxDelay:    time.Hour,
+		Lease:       5 * time.Minute,
+		SendTimeout: 10 * time.Second,
+	}
+	if w.Interval == 0 {
+		w.Interval = 5 * time.Second
+	}
+	if w.MaxAttempts == 0 {
+		w.MaxAttempts = 8
+	}
+	return w
+}
+
+// Run delivers due emails every Interval until ctx is done. A batch already
+// being sent is finished first.
+func (w *Worker) Run(ctx context.Context) {
+	worker := health.RegisterWorker("outbox", 3*w.Interval+w.Lease)
+	defer worker.Stopped()
+
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		err := w.Drain(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "could not deliver outbox emails", "error", err)
+		}
+		worker.Beat(err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Drain delivers due emails in batches until none are left.
+func (w *Worker) Drain(ctx context.Context) error {
+	for ctx.Err() == nil {
+		batch, err := w.claim(ctx)
+		if err != nil {
+			return err
+		}
+
+		for i := range batch {
+			if err := w.deliver(context.WithoutCancel(ctx), &batch[i]); err != nil {
+				return err
+			}
+		}
+
+		if len(batch) < w.BatchSize {
+			return nil
+		}
+	}
+	return nil
+}
+
+// claim leases a batch of due emails and counts the attempt about to be made.
+func (w *Worker) claim(ctx context.Context) ([]models.EmailOutbox, error) {
+	var batch []models.EmailOutbox
+	now := time.Now()
+
+	err := w.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.EmailPending, now).
+			Order("next_attempt_at").
+			Limit(w.BatchSize).
+			Find(&batch).Error
+		if err != nil || len(batch) == 0 {
+			return err
+		}
+
+		ids := make([]uuid.UUID, len(batch))
+		for i := range batch {
+			ids[i] = batch[i].ID
+			batch[i].Attempts++
+		}
+		return tx.Model(&models.EmailOutbox{}).Where("id IN ?", ids).UpdateColumns(map[string]any{
+			"attempts":        gorm.Expr("attempts + 1"),
+			"next_attempt_at": now.Add(w.Lease),
+			"updated_at":      now,
+		}).Error
+	})
+	return batch, err
+}
+
+// deliver sends one claimed email and records the outcome. Only a failure to
+// record it is returned; send errors are stored on the row.
+func (w *Worker) deliver(ctx context.Context, email *models.EmailOutbox) error {
+	sendCtx, cancel := context.WithTimeout(ctx, w.SendTimeout)
+	sendErr := w.send(sendCtx, email)
+	cancel()
+	now := time.Now()
+
+	update := map[string]any{"updated_at": now}
+	switch {
+	case sendErr == nil:
+		update["status"] = models.EmailSent
+		update["sent_at"] = now
+		update["data"] = ""
+		update["last_error"] = ""
+	case email.Attempts >= w.MaxAttempts:
+		update["status"] = models.EmailFailed
+		update["last_error"] = sendErr.Error()
+		slog.ErrorContext(ctx, "email delivery failed permanently", "email_id", email.ID, "template", email.Template,
+			"attempts", email.Attempts, "error", sendErr)
+	default:
+		update["next_attempt_at"] = now.Add(w.backoff(email.Attempts))
+		update["last_error"] = sendErr.Error()
+		slog.WarnContext(ctx, "email delivery failed, will retry", "email_id", email.ID, "template", email.Template,
+			"attempts", email.Attempts, "error", sendErr)
+	}
+
+	return w.DB.WithContext(ctx).Model(&models.EmailOutbox{}).Where("id = ?", email.ID).UpdateColumns(update).Error
+}
+
+func (w *Worker) send(ctx context.Context, email *models.EmailOutbox) error {
+	var data utils.EmailData
+	if err := json.Unmarshal([]byte(email.Data), &data); err != nil {
+		return fmt.Errorf("invalid email data: %w", err)
+	}
+
+	recipient := models.User{Email: email.ToEmail, Name: email.ToName, Locale: email.Locale}
+	return utils.SendEmail(ctx, &recipient, &data, email.Template)
+}
+
+// backoff is the wait after the given number of failed attempts.
+func (w *Worker) backoff(attempts int) time.Duration {
+	delay := w.BaseDelay
+	for i := 1; i < attempts && delay < w.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, w.MaxDelay)
+}
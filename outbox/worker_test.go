@@ -0,0 +1,168 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Llane00/ramen-backend/mailer"
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/testdb"
+	"github.com/Llane00/ramen-backend/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func TestBackoff(t *testing.T) {
+	w := NewWorker(nil, 0, 0)
+	tests := map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		3:  2 * time.Minute,
+		7:  32 * time.Minute,
+		8:  time.Hour,
+		20: time.Hour,
+	}
+	for attempts, want := range tests {
+		if got := w.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+func TestNewWorkerFitsBatchInLease(t *testing.T) {
+	w := NewWorker(nil, 0, 0)
+	if time.Duration(w.BatchSize)*w.SendTimeout >= w.Lease {
+		t.Errorf("a batch of %d sends of up to %v can outlive the %v lease", w.BatchSize, w.SendTimeout, w.Lease)
+	}
+}
+
+// newTestOutbox returns a worker on a fresh outbox whose emails go to
+// recorder.
+func newTestOutbox(t *testing.T, recorder *mailer.Recorder) (*Worker, *gorm.DB) {
+	t.Helper()
+	db := testdb.Open(t, &models.EmailOutbox{})
+	if err := utils.InitEmail(recorder, "../templates", "noreply@ramen.example", "Ramen"); err != nil {
+		t.Fatalf("InitEmail: %v", err)
+	}
+	return NewWorker(db, time.Second, 2), db
+}
+
+func enqueueTestEmail(t *testing.T, db *gorm.DB, due time.Time) models.EmailOutbox {
+	t.Helper()
+	user := models.User{Email: uuid.NewString() + "@ramen.example", Name: "Taro Yamada", Locale: "en"}
+	data := utils.EmailData{URL: "https://ramen.example/verify", FirstName: "Taro"}
+	if err := Enqueue(db, &user, &data, "verificationCode.html"); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	var email models.EmailOutbox
+	db.Where("to_email = ?", user.Email).First(&email)
+	db.Model(&email).Update("next_attempt_at", due)
+	return email
+}
+
+func TestClaimSkipsLockedAndLeasedEmails(t *testing.T) {
+	w, db := newTestOutbox(t, &mailer.Recorder{})
+	past := time.Now().Add(-time.Minute)
+
+	locked := enqueueTestEmail(t, db, past)
+	due := []models.EmailOutbox{enqueueTestEmail(t, db, past), enqueueTestEmail(t, db, past)}
+	enqueueTestEmail(t, db, time.Now().Add(time.Hour))
+
+	// Another worker holds a row in its claim transaction.
+	tx := db.Begin()
+	defer tx.Rollback()
+	var held models.EmailOutbox
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&held, "id = ?", locked.ID).Error; err != nil {
+		t.Fatalf("lock: %v", err)
+	}
+
+	batch, err := w.claim(context.Background())
+	if err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	claimed := map[uuid.UUID]bool{}
+	for _, email := range batch {
+		claimed[email.ID] = true
+		if email.Attempts != 1 {
+			t.Errorf("claimed email has %d attempts, want 1", email.Attempts)
+		}
+	}
+	if len(batch) != 2 || !claimed[due[0].ID] || !claimed[due[1].ID] {
+		t.Fatalf("claimed %v, want the two due, unlocked emails", claimed)
+	}
+
+	// Claimed emails are leased, so a second claim finds nothing.
+	tx.Rollback()
+	var leased models.EmailOutbox
+	db.First(&leased, "id = ?", due[0].ID)
+	if leased.Attempts != 1 || time.Until(leased.NextAttemptAt) < w.Lease-time.Minute {
+		t.Errorf("claimed email = %d attempts, next attempt in %v, want 1 and the lease", leased.Attempts, time.Until(leased.NextAttemptAt))
+	}
+	batch, err = w.claim(context.Background())
+	if err != nil || len(batch) != 1 || batch[0].ID != locked.ID {
+		t.Fatalf("second claim = %v, %v, want only the previously locked email", batch, err)
+	}
+}
+
+func TestDrainRetriesAndDeadLetters(t *testing.T) {
+	recorder := &mailer.Recorder{Err: errors.New("mail server down")}
+	w, db := newTestOutbox(t, recorder)
+	email := enqueueTestEmail(t, db, time.Now().Add(-time.Minute))
+
+	reload := func() models.EmailOutbox {
+		var row models.EmailOutbox
+		db.First(&row, "id = ?", email.ID)
+		return row
+	}
+
+	if err := w.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+	row := reload()
+	if row.Status != models.EmailPending || row.Attempts != 1 || row.LastError != "mail server down" {
+		t.Fatalf("after a failed send = %s, %d attempts, %q, want a pending retry", row.Status, row.Attempts, row.LastError)
+	}
+	if wait := time.Until(row.NextAttemptAt); wait < w.BaseDelay-5*time.Second || wait > w.BaseDelay {
+		t.Errorf("retry in %v, want %v", wait, w.BaseDelay)
+	}
+
+	// The second failure exhausts MaxAttempts.
+	db.Model(&row).Update("next_attempt_at", time.Now().Add(-time.Second))
+	if err := w.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+	if row = reload(); row.Status != models.EmailFailed || row.Attempts != 2 {
+		t.Fatalf("after %d failed sends = %s, want failed", row.Attempts, row.Status)
+	}
+
+	// Failed emails are not retried.
+	recorder.Err = nil
+	db.Model(&row).Update("next_attempt_at", time.Now().Add(-time.Second))
+	if err := w.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+	if sent := recorder.Messages(); len(sent) != 0 {
+		t.Fatalf("failed email was sent %d times", len(sent))
+	}
+}
+
+func TestDrainSendsAndClearsData(t *testing.T) {
+	recorder := &mailer.Recorder{}
+	w, db := newTestOutbox(t, recorder)
+	email := enqueueTestEmail(t, db, time.Now().Add(-time.Minute))
+
+	if err := w.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+	var row models.EmailOutbox
+	db.First(&row, "id = ?", email.ID)
+	if row.Status != models.EmailSent || row.SentAt == nil || row.Data != "" {
+		t.Fatalf("after sending = %s, sent at %v, data %q, want sent with data cleared", row.Status, row.SentAt, row.Data)
+	}
+	if sent := recorder.To(email.ToEmail); len(sent) != 1 {
+		t.Fatalf("sent %d messages to %s, want 1", len(sent), email.ToEmail)
+	}
+}
@@ -0,0 +1,67 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// cursor is the position after the last row of a page. It records the sort it
+// was issued for so it cannot be replayed against a different ordering.
+type cursor struct {
+	Sort  string    `json:"s"`
+	Value string    `json:"v"`
+	ID    uuid.UUID `json:"id"`
+
+	value any
+}
+
+func (q *Query) sortKey() string {
+	if q.Descending {
+		return "-" + q.Sort.Param
+	}
+	return q.Sort.Param
+}
+
+func encodeCursor(q *Query, value any, id uuid.UUID) (string, error) {
+	var raw string
+	switch v := value.(type) {
+	case time.Time:
+		raw = v.UTC().Format(time.RFC3339Nano)
+	default:
+		raw = fmt.Sprint(v)
+	}
+
+	data, err := json.Marshal(cursor{Sort: q.sortKey(), Value: raw, ID: id})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(raw string, q *Query) (*cursor, error) {
+	invalid := errors.New("is invalid or expired")
+
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, invalid
+	}
+
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == uuid.Nil {
+		return nil, invalid
+	}
+	if c.Sort != q.sortKey() {
+		return nil, errors.New("was issued for a different sort order")
+	}
+
+	c.value, err = parseValue(q.Sort.Kind, c.Value)
+	if err != nil {
+		return nil, invalid
+	}
+	return &c, nil
+}
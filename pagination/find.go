@@ -0,0 +1,79 @@
+package pagination
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Find runs q against db, which may already carry scoping conditions such as
+// a shop ID, and returns one page of T together with the filtered total.
+func Find[T any](db *gorm.DB, q *Query) (*Page[T], error) {
+	filtered := db.Model(new(T))
+	for _, c := range q.conditions {
+		filtered = filtered.Where(c.clause, c.value)
+	}
+
+	var total int64
+	if err := filtered.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	direction, comparison := "ASC", ">"
+	if q.Descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	pageQuery := filtered.Session(&gorm.Session{})
+	if q.cursor != nil {
+		pageQuery = pageQuery.Where(
+			fmt.Sprintf("(%s, id) %s (?, ?)", q.Sort.Column, comparison),
+			q.cursor.value, q.cursor.ID,
+		)
+	}
+
+	rows := make([]T, 0, q.Limit+1)
+	err := pageQuery.
+		Order(fmt.Sprintf("%s %s, id %s", q.Sort.Column, direction, direction)).
+		Limit(q.Limit + 1).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	page := &Page[T]{Data: rows, Total: total}
+	if len(rows) > q.Limit {
+		page.Data = rows[:q.Limit]
+		next, err := nextCursor(db, q, &page.Data[q.Limit-1])
+		if err != nil {
+			return nil, err
+		}
+		page.NextCursor = next
+	}
+	return page, nil
+}
+
+func nextCursor[T any](db *gorm.DB, q *Query, last *T) (string, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(last); err != nil {
+		return "", err
+	}
+
+	sortField := stmt.Schema.LookUpField(q.Sort.Column)
+	idField := stmt.Schema.LookUpField("id")
+	if sortField == nil || idField == nil {
+		return "", fmt.Errorf("pagination: %T has no %s or id column", last, q.Sort.Column)
+	}
+
+	rv := reflect.ValueOf(last).Elem()
+	value, _ := sortField.ValueOf(db.Statement.Context, rv)
+	idValue, _ := idField.ValueOf(db.Statement.Context, rv)
+
+	id, ok := idValue.(uuid.UUID)
+	if !ok {
+		return "", fmt.Errorf("pagination: %T id is not a UUID", last)
+	}
+	return encodeCursor(q, value, id)
+}
@@ -0,0 +1,262 @@
+// Package pagination implements keyset (cursor) pagination, whitelisted
+// filters and sorting for list endpoints.
+//
+// Clients pass ?limit=, ?sort= (a whitelisted field, "-" prefix for
+// descending), ?cursor= (the next_cursor of the previous page) and any filter
+// parameter declared in the endpoint's Spec. Pages are ordered by the sort
+// column and then by id, so the cursor identifies a unique position even when
+// several rows share the same sort value.
+package pagination
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Llane00/ramen-backend/apperror"
+	"github.com/Llane00/ramen-backend/openapi"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+type Kind int
+
+const (
+	String Kind = iota
+	Int
+	Time
+	UUID
+)
+
+// Filter maps a query parameter onto a column comparison.
+type Filter struct {
+	Param  string
+	Column string
+	// Op is one of "=", ">=", "<=" or "contains" (case-insensitive substring).
+	Op   string
+	Kind Kind
+	// Values restricts the accepted values, e.g. the order statuses.
+	Values []string
+}
+
+// SortField whitelists a column clients may sort by.
+type SortField struct {
+	Param  string
+	Column string
+	Kind   Kind
+}
+
+// CreatedAt is the default sort field of every list endpoint.
+var CreatedAt = SortField{Param: "created_at", Column: "created_at", Kind: Time}
+
+type Spec struct {
+	Filters []Filter
+	// Sorts lists the accepted sort fields; the first one is the default,
+	// sorted descending.
+	Sorts    []SortField
+	MaxLimit int
+}
+
+// Page is the uniform response of list endpoints.
+type Page[T any] struct {
+	Data       []T    `json:"data"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      int64  `json:"total"`
+}
+
+type condition struct {
+	clause string
+	value  any
+}
+
+// Query is a parsed and validated list request.
+type Query struct {
+	Limit      int
+	Sort       SortField
+	Descending bool
+	cursor     *cursor
+	conditions []condition
+}
+
+// Parse validates the list parameters of the request against spec.
+func Parse(ctx *gin.Context, spec Spec) (*Query, error) {
+	sorts := spec.Sorts
+	if len(sorts) == 0 {
+		sorts = []SortField{CreatedAt}
+	}
+	maxLimit := spec.MaxLimit
+	if maxLimit == 0 {
+		maxLimit = MaxLimit
+	}
+
+	q := &Query{Limit: DefaultLimit, Sort: sorts[0], Descending: true}
+	var fields []apperror.FieldError
+
+	if raw := ctx.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxLimit {
+			fields = append(fields, apperror.FieldError{Field: "limit", Message: "must be an integer between 1 and " + strconv.Itoa(maxLimit)})
+		} else {
+			q.Limit = limit
+		}
+	}
+
+	if raw := ctx.Query("sort"); raw != "" {
+		name := strings.TrimPrefix(raw, "-")
+		q.Descending = strings.HasPrefix(raw, "-")
+		found := false
+		for _, s := range sorts {
+			if s.Param == name {
+				q.Sort, found = s, true
+				break
+			}
+		}
+		if !found {
+			fields = append(fields, apperror.FieldError{Field: "sort", Message: "must be one of: " + sortNames(sorts)})
+		}
+	}
+
+	for _, f := range spec.Filters {
+		raw, ok := ctx.GetQuery(f.Param)
+		if !ok || raw == "" {
+			continue
+		}
+		cond, err := f.condition(raw)
+		if err != nil {
+			fields = append(fields, apperror.FieldError{Field: f.Param, Message: err.Error()})
+			continue
+		}
+		q.conditions = append(q.conditions, cond)
+	}
+
+	if raw := ctx.Query("cursor"); raw != "" && len(fields) == 0 {
+		c, err := decodeCursor(raw, q)
+		if err != nil {
+			fields = append(fields, apperror.FieldError{Field: "cursor", Message: err.Error()})
+		} else {
+			q.cursor = c
+		}
+	}
+
+	if len(fields) > 0 {
+		return nil, apperror.Validation("invalid_list_parameters", "Invalid pagination, filter or sort parameters", fields...)
+	}
+	return q, nil
+}
+
+func (f Filter) condition(raw string) (condition, error) {
+	if len(f.Values) > 0 {
+		allowed := false
+		for _, v := range f.Values {
+			if v == raw {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return condition{}, errorf("must be one of: %s", strings.Join(f.Values, ", "))
+		}
+	}
+
+	value, err := parseValue(f.Kind, raw)
+	if err != nil {
+		return condition{}, err
+	}
+
+	switch f.Op {
+	case "contains":
+		escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(raw)
+		return condition{clause: f.Column + " ILIKE ?", value: "%" + escaped + "%"}, nil
+	case "<=":
+		// A date-only upper bound includes the whole day.
+		if f.Kind == Time && isDate(raw) {
+			return condition{clause: f.Column + " < ?", value: value.(time.Time).AddDate(0, 0, 1)}, nil
+		}
+		return condition{clause: f.Column + " <= ?", value: value}, nil
+	case ">=", "=":
+		return condition{clause: f.Column + " " + f.Op + " ?", value: value}, nil
+	default:
+		return condition{clause: f.Column + " = ?", value: value}, nil
+	}
+}
+
+func parseValue(kind Kind, raw string) (any, error) {
+	switch kind {
+	case Int:
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, errorf("must be an integer")
+		}
+		return v, nil
+	case Time:
+		if t, err := time.Parse(time.RFC3339Nano, raw); err == nil {
+			return t, nil
+		}
+		if t, err := time.Parse(time.DateOnly, raw); err == nil {
+			return t, nil
+		}
+		return nil, errorf("must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+	case UUID:
+		v, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, errorf("must be a valid UUID")
+		}
+		return v, nil
+	default:
+		return raw, nil
+	}
+}
+
+func isDate(raw string) bool {
+	_, err := time.Parse(time.DateOnly, raw)
+	return err == nil
+}
+
+func errorf(format string, args ...any) error {
+	return fmt.Errorf(format, args...)
+}
+
+func sortNames(sorts []SortField) string {
+	names := make([]string, len(sorts))
+	for i, s := range sorts {
+		names[i] = s.Param
+	}
+	return strings.Join(names, ", ")
+}
+
+// Params documents the list parameters of spec for the OpenAPI document.
+func (spec Spec) Params() []openapi.Param {
+	sorts := spec.Sorts
+	if len(sorts) == 0 {
+		sorts = []SortField{CreatedAt}
+	}
+
+	maxLimit := spec.MaxLimit
+	if maxLimit == 0 {
+		maxLimit = MaxLimit
+	}
+
+	params := []openapi.Param{
+		{Name: "limit", Type: "integer", Description: "Page size, at most " + strconv.Itoa(maxLimit)},
+		{Name: "cursor", Description: "next_cursor from the previous page"},
+		{Name: "sort", Description: "One of " + sortNames(sorts) + "; prefix with - for descending (default -" + sorts[0].Param + ")"},
+	}
+	for _, f := range spec.Filters {
+		typ := "string"
+		if f.Kind == Int {
+			typ = "integer"
+		}
+		desc := strings.Trim(f.Column, `"`) + " " + f.Op
+		if len(f.Values) > 0 {
+			desc += " (" + strings.Join(f.Values, ", ") + ")"
+		}
+		params = append(params, openapi.Param{Name: f.Param, Type: typ, Description: desc})
+	}
+	return params
+}
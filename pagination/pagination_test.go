@@ -0,0 +1,31 @@
+package pagination
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeFilterCondition(t *testing.T) {
+	before := Filter{Param: "created_before", Column: "created_at", Op: "<=", Kind: Time}
+	after := Filter{Param: "created_after", Column: "created_at", Op: ">=", Kind: Time}
+
+	tests := []struct {
+		filter     Filter
+		raw        string
+		wantClause string
+		wantValue  time.Time
+	}{
+		{before, "2026-10-19", "created_at < ?", time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC)},
+		{before, "2026-10-19T12:30:00Z", "created_at <= ?", time.Date(2026, 10, 19, 12, 30, 0, 0, time.UTC)},
+		{after, "2026-10-19", "created_at >= ?", time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		cond, err := tt.filter.condition(tt.raw)
+		if err != nil {
+			t.Fatalf("condition(%q): %v", tt.raw, err)
+		}
+		if cond.clause != tt.wantClause || !cond.value.(time.Time).Equal(tt.wantValue) {
+			t.Errorf("condition(%q) = %q %v, want %q %v", tt.raw, cond.clause, cond.value, tt.wantClause, tt.wantValue)
+		}
+	}
+}
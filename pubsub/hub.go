@@ -0,0 +1,126 @@
+// Package pubsub fans out real-time events, such as new notifications, to the
+// streams of connected clients. Events are sent through Postgres with Notify
+// so that clients connected to any server instance receive them; Listen
+// relays them to the Hub of each instance.
+package pubsub
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+)
+
+// ErrTooManySubscribers is returned by Subscribe when a topic already has
+// MaxSubscribers streams.
+var ErrTooManySubscribers = errors.New("too many subscribers")
+
+// Message is an event delivered to the subscribers of a topic.
+type Message struct {
+	Event string          `json:"event"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// NewMessage encodes data as the payload of an event.
+func NewMessage(event string, data any) (Message, error) {
+	raw, err := json.Marshal(data)
+	return Message{Event: event, Data: raw}, err
+}
+
+// subscriberBuffer is how many messages a slow subscriber may fall behind
+// before further messages to it are dropped.
+const subscriberBuffer = 16
+
+// Hub delivers messages to the subscribers of a topic in this process.
+// Publishing never blocks: a subscriber that is not keeping up misses
+// messages rather than stalling the publisher.
+type Hub struct {
+	// MaxSubscribers caps the streams per topic, e.g. open tabs of one
+	// user. Zero means no limit.
+	MaxSubscribers int
+
+	mu     sync.Mutex
+	topics map[string]map[*Subscription]struct{}
+	closed bool
+}
+
+func NewHub(maxSubscribers int) *Hub {
+	return &Hub{MaxSubscribers: maxSubscribers, topics: map[string]map[*Subscription]struct{}{}}
+}
+
+// Subscription receives the messages of one topic on C until it is closed
+// or the hub shuts down, which closes C.
+type Subscription struct {
+	C <-chan Message
+
+	c     chan Message
+	topic string
+	hub   *Hub
+}
+
+func (h *Hub) Subscribe(topic string) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	c := make(chan Message, subscriberBuffer)
+	sub := &Subscription{C: c, c: c, topic: topic, hub: h}
+	if h.closed {
+		close(c)
+		return sub, nil
+	}
+
+	subs := h.topics[topic]
+	if h.MaxSubscribers > 0 && len(subs) >= h.MaxSubscribers {
+		return nil, ErrTooManySubscribers
+	}
+	if subs == nil {
+		subs = map[*Subscription]struct{}{}
+		h.topics[topic] = subs
+	}
+	subs[sub] = struct{}{}
+	return sub, nil
+}
+
+// Close unsubscribes. It is safe to call more than once.
+func (s *Subscription) Close() {
+	h := s.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	subs := h.topics[s.topic]
+	if _, ok := subs[s]; !ok {
+		return
+	}
+	delete(subs, s)
+	if len(subs) == 0 {
+		delete(h.topics, s.topic)
+	}
+	close(s.c)
+}
+
+// Publish delivers msg to the current subscribers of topic.
+func (h *Hub) Publish(topic string, msg Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.topics[topic] {
+		select {
+		case sub.c <- msg:
+		default:
+		}
+	}
+}
+
+// Close ends every subscription, so open streams finish and the server can
+// shut down.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, subs := range h.topics {
+		for sub := range subs {
+			close(sub.c)
+		}
+	}
+	h.topics = map[string]map[*Subscription]struct{}{}
+	h.closed = true
+}
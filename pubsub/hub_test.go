@@ -0,0 +1,72 @@
+package pubsub
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestHubPublish(t *testing.T) {
+	hub := NewHub(2)
+
+	a, err := hub.Subscribe("user:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := hub.Subscribe("user:1")
+	other, _ := hub.Subscribe("user:2")
+
+	if _, err := hub.Subscribe("user:1"); !errors.Is(err, ErrTooManySubscribers) {
+		t.Errorf("third subscription = %v, want ErrTooManySubscribers", err)
+	}
+
+	msg, _ := NewMessage("notification", map[string]string{"title": "New order"})
+	hub.Publish("user:1", msg)
+
+	for _, sub := range []*Subscription{a, b} {
+		if got := <-sub.C; got.Event != "notification" || string(got.Data) != `{"title":"New order"}` {
+			t.Errorf("received %s %s", got.Event, got.Data)
+		}
+	}
+	select {
+	case got := <-other.C:
+		t.Errorf("other topic received %s", got.Event)
+	default:
+	}
+
+	a.Close()
+	a.Close()
+	if _, err := hub.Subscribe("user:1"); err != nil {
+		t.Errorf("subscribing after Close = %v", err)
+	}
+}
+
+func TestHubDropsForSlowSubscribers(t *testing.T) {
+	hub := NewHub(0)
+	sub, _ := hub.Subscribe("user:1")
+
+	msg, _ := NewMessage("notification", 1)
+	for i := 0; i < subscriberBuffer+5; i++ {
+		hub.Publish("user:1", msg)
+	}
+	if len(sub.C) != subscriberBuffer {
+		t.Errorf("buffered %d messages, want %d", len(sub.C), subscriberBuffer)
+	}
+}
+
+func TestHubClose(t *testing.T) {
+	hub := NewHub(0)
+	sub, _ := hub.Subscribe("user:1")
+
+	hub.Close()
+	for range sub.C {
+	}
+	sub.Close()
+
+	late, err := hub.Subscribe("user:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-late.C; ok {
+		t.Error("subscription after Close is open")
+	}
+}
@@ -0,0 +1,116 @@
+package pubsub
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/gorm"
+)
+
+// Channel is the Postgres NOTIFY channel that carries messages between
+// server instances.
+const Channel = "ramen_events"
+
+// maxPayload is the largest NOTIFY payload Postgres accepts.
+const maxPayload = 7999
+
+// envelope is a message on Channel together with the topics it goes to.
+type envelope struct {
+	Topics []string `json:"topics"`
+	Message
+}
+
+// Notify publishes msg to topics on every server instance, including this
+// one through Listen. Inside a transaction the message is only delivered
+// when the transaction commits, so subscribers never see a rolled back
+// change.
+func Notify(db *gorm.DB, msg Message, topics ...string) error {
+	payload, err := json.Marshal(envelope{Topics: topics, Message: msg})
+	if err != nil {
+		return err
+	}
+	if len(payload) > maxPayload {
+		return fmt.Errorf("pubsub: %q message is %d bytes, over the %d byte limit", msg.Event, len(payload), maxPayload)
+	}
+	return db.Exec("SELECT pg_notify(?, ?)", Channel, string(payload)).Error
+}
+
+// Listen relays the messages sent with Notify to the subscribers of hubs
+// until ctx is done. It holds one connection of db and reconnects with
+// backoff when the connection drops; messages sent while disconnected are
+// lost, so clients should reload their state when they reconnect.
+func Listen(ctx context.Context, db *sql.DB, hubs ...*Hub) {
+	const maxDelay = time.Minute
+	delay := time.Second
+
+	for {
+		listening, err := listen(ctx, db, hubs)
+		if ctx.Err() != nil {
+			return
+		}
+		if listening {
+			delay = time.Second
+		}
+		slog.Error("event listener disconnected", "channel", Channel, "error", err, "retry_in", delay)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxDelay)
+	}
+}
+
+// listen waits for notifications on one connection. listening reports
+// whether LISTEN succeeded before the connection failed.
+func listen(ctx context.Context, db *sql.DB, hubs []*Hub) (listening bool, err error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	err = conn.Raw(func(driverConn any) error {
+		pgConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("pubsub: %T is not a pgx connection", driverConn)
+		}
+		if _, err := pgConn.Conn().Exec(ctx, "LISTEN "+Channel); err != nil {
+			return fmt.Errorf("%w: %w", driver.ErrBadConn, err)
+		}
+		listening = true
+		slog.Info("listening for events", "channel", Channel)
+
+		for {
+			notification, err := pgConn.Conn().WaitForNotification(ctx)
+			if err != nil {
+				// The connection is still subscribed or broken; either
+				// way it must not go back to the pool.
+				return fmt.Errorf("%w: %w", driver.ErrBadConn, err)
+			}
+			relay(notification.Payload, hubs)
+		}
+	})
+	return listening, err
+}
+
+// relay publishes a Notify payload to hubs.
+func relay(payload string, hubs []*Hub) {
+	var env envelope
+	if err := json.Unmarshal([]byte(payload), &env); err != nil {
+		slog.Error("could not decode event", "channel", Channel, "error", err)
+		return
+	}
+	for _, topic := range env.Topics {
+		for _, hub := range hubs {
+			hub.Publish(topic, env.Message)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package pubsub
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRelay(t *testing.T) {
+	users, orders := NewHub(0), NewHub(0)
+	user, _ := users.Subscribe("user:1")
+	order, _ := orders.Subscribe("order:1")
+	shop, _ := orders.Subscribe("shop:1:orders")
+
+	msg, _ := NewMessage("order_status", map[string]string{"status": "paid"})
+	payload, _ := json.Marshal(envelope{Topics: []string{"order:1", "shop:1:orders"}, Message: msg})
+	relay(string(payload), []*Hub{users, orders})
+
+	for _, sub := range []*Subscription{order, shop} {
+		if got := <-sub.C; got.Event != "order_status" || string(got.Data) != `{"status":"paid"}` {
+			t.Errorf("received %s %s", got.Event, got.Data)
+		}
+	}
+	select {
+	case got := <-user.C:
+		t.Errorf("unrelated topic received %s", got.Event)
+	default:
+	}
+
+	// A malformed payload is dropped.
+	relay("{", []*Hub{orders})
+	select {
+	case got := <-order.C:
+		t.Errorf("received %s from a malformed payload", got.Event)
+	default:
+	}
+}
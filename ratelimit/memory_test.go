@@ -0,0 +1,51 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	now := time.Unix(0, 0)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	policy := Policy{Name: "test", Burst: 2, Period: time.Minute}
+	ctx := context.Background()
+
+	for i, want := range []bool{true, true, false} {
+		res, err := store.Take(ctx, "a", policy)
+		if err != nil || res.Allowed != want {
+			t.Fatalf("Take(a) #%d = %+v, %v, want allowed=%v", i, res, err, want)
+		}
+	}
+
+	// Buckets are independent per key.
+	if res, _ := store.Take(ctx, "b", policy); !res.Allowed {
+		t.Fatalf("Take(b) = %+v, want allowed", res)
+	}
+
+	now = now.Add(30 * time.Second)
+	if res, _ := store.Take(ctx, "a", policy); !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("Take(a) after refill = %+v, want allowed with 0 remaining", res)
+	}
+}
+
+func TestMemoryStoreSweepsFullBuckets(t *testing.T) {
+	now := time.Unix(0, 0)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	policy := Policy{Name: "test", Burst: 1, Period: time.Second}
+	ctx := context.Background()
+
+	for i := 0; i < sweepInterval-1; i++ {
+		store.Take(ctx, fmt.Sprint(i), policy)
+	}
+	now = now.Add(time.Second)
+	store.Take(ctx, "last", policy)
+
+	if len(store.buckets) != 1 {
+		t.Errorf("buckets after sweep = %d, want 1", len(store.buckets))
+	}
+}
@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/Llane00/ramen-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// pruneAge is how long a bucket may sit idle before it is deleted. It must be
+// longer than the period of every policy so only full buckets are dropped.
+const pruneAge = 24 * time.Hour
+
+// PostgresStore keeps buckets in the rate_limit_buckets table so every
+// instance shares the same limits. Each Take locks the bucket row for the
+// duration of a short transaction.
+type PostgresStore struct {
+	DB    *gorm.DB
+	calls atomic.Int64
+}
+
+func NewPostgresStore(DB *gorm.DB) *PostgresStore {
+	return &PostgresStore{DB: DB}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, policy Policy) (Result, error) {
+	var res Result
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		fresh := models.RateLimitBucket{Key: key, Tokens: float64(policy.Burst), UpdatedAt: now}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&fresh).Error; err != nil {
+			return err
+		}
+
+		var b models.RateLimitBucket
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&b, "key = ?", key).Error; err != nil {
+			return err
+		}
+
+		b.Tokens, res = take(b.Tokens, b.UpdatedAt, now, policy)
+		return tx.Model(&b).Updates(map[string]any{"tokens": b.Tokens, "updated_at": now}).Error
+	})
+
+	if s.calls.Add(1)%sweepInterval == 0 {
+		if _, err := s.Prune(ctx, pruneAge); err != nil {
+			slog.WarnContext(ctx, "could not prune rate limit buckets", "error", err)
+		}
+	}
+	return res, err
+}
+
+// Prune deletes buckets untouched for longer than maxAge. Any bucket idle for
+// longer than its policy period is full and can be dropped safely.
+func (s *PostgresStore) Prune(ctx context.Context, maxAge time.Duration) (int64, error) {
+	result := s.DB.WithContext(ctx).Where("updated_at < ?", time.Now().Add(-maxAge)).Delete(&models.RateLimitBucket{})
+	return result.RowsAffected, result.Error
+}
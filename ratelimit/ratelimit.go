@@ -0,0 +1,75 @@
+// Package ratelimit implements token bucket rate limiting with pluggable
+// bucket stores.
+//
+// A Policy allows Burst requests at once and refills the bucket at Burst
+// tokens per Period, so a client that stays under the average rate is never
+// limited while bursts beyond the bucket size are rejected until tokens refill.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+)
+
+type Policy struct {
+	// Name identifies the policy in bucket keys, headers and metrics.
+	Name   string
+	Burst  int
+	Period time.Duration
+}
+
+// rate is the refill rate in tokens per second.
+func (p Policy) rate() float64 {
+	return float64(p.Burst) / p.Period.Seconds()
+}
+
+// Header renders the policy for the RateLimit-Policy header, e.g. "5;w=900".
+func (p Policy) Header() string {
+	return strconv.Itoa(p.Burst) + ";w=" + strconv.Itoa(int(p.Period.Seconds()))
+}
+
+// Result is the outcome of taking a token from a bucket.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is the time until the next token is available. It is zero
+	// when the request was allowed.
+	RetryAfter time.Duration
+}
+
+// Store keeps bucket state. Take removes one token from the bucket stored
+// under key, creating a full bucket if none exists.
+type Store interface {
+	Take(ctx context.Context, key string, policy Policy) (Result, error)
+}
+
+// take refills a bucket holding tokens as of last and tries to remove one
+// token at now. It returns the new token count and the result.
+func take(tokens float64, last, now time.Time, p Policy) (float64, Result) {
+	rate := p.rate()
+	burst := float64(p.Burst)
+
+	if elapsed := now.Sub(last).Seconds(); elapsed > 0 {
+		tokens = math.Min(burst, tokens+elapsed*rate)
+	}
+
+	res := Result{Limit: p.Burst}
+	if tokens >= 1 {
+		tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - tokens) / rate)
+	}
+	res.Remaining = int(math.Floor(tokens))
+	res.Reset = seconds((burst - tokens) / rate)
+	return tokens, res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
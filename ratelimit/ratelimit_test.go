@@ -0,0 +1,44 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTakeBurstAndRefill(t *testing.T) {
+	policy := Policy{Name: "test", Burst: 3, Period: 3 * time.Second}
+	start := time.Unix(0, 0)
+
+	tokens := float64(policy.Burst)
+	var res Result
+	for i := 0; i < policy.Burst; i++ {
+		tokens, res = take(tokens, start, start, policy)
+		if !res.Allowed || res.Remaining != policy.Burst-1-i {
+			t.Fatalf("take %d = %+v, want allowed with %d remaining", i, res, policy.Burst-1-i)
+		}
+	}
+
+	tokens, res = take(tokens, start, start, policy)
+	if res.Allowed || res.RetryAfter != time.Second || res.Reset != 3*time.Second {
+		t.Fatalf("take over burst = %+v, want rejected with a 1s retry and 3s reset", res)
+	}
+
+	// One token per second refills.
+	tokens, res = take(tokens, start, start.Add(time.Second), policy)
+	if !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("take after 1s = %+v, want allowed with 0 remaining", res)
+	}
+
+	// Refilling never exceeds the burst.
+	_, res = take(tokens, start.Add(time.Second), start.Add(time.Hour), policy)
+	if !res.Allowed || res.Remaining != policy.Burst-1 {
+		t.Fatalf("take after an hour = %+v, want allowed with %d remaining", res, policy.Burst-1)
+	}
+}
+
+func TestPolicyHeader(t *testing.T) {
+	policy := Policy{Name: "login", Burst: 5, Period: 15 * time.Minute}
+	if got := policy.Header(); got != "5;w=900" {
+		t.Errorf("Header() = %q, want %q", got, "5;w=900")
+	}
+}
@@ -0,0 +1,43 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/Llane00/ramen-backend/controllers"
+	"github.com/Llane00/ramen-backend/middleware"
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/openapi"
+	"github.com/Llane00/ramen-backend/pagination"
+	"github.com/Llane00/ramen-backend/utils"
+	"github.com/gin-gonic/gin"
+)
+
+type AdminRouteController struct {
+	adminController controllers.AdminController
+}
+
+func NewAdminRouteController(adminController controllers.AdminController) AdminRouteController {
+	return AdminRouteController{adminController}
+}
+
+func (ac *AdminRouteController) AdminRoute(rg *gin.RouterGroup) {
+	router := rg.Group("/admin")
+	router.Use(middleware.DeserializeUser(), middleware.RequireRole(models.RoleSuperAdmin))
+	router.POST("/users/:userId/unlock", ac.adminController.UnlockUser)
+	router.GET("/emails", ac.adminController.FindEmails)
+	router.GET("/emails/:emailId", ac.adminController.FindEmailById)
+	router.POST("/emails/:emailId/retry", ac.adminController.RetryEmail)
+	router.GET("/email-templates", ac.adminController.ListEmailTemplates)
+	router.GET("/email-templates/:template/preview", ac.adminController.PreviewEmailTemplate)
+}
+
+var adminOperations = []openapi.Operation{
+	{Method: http.MethodPost, Path: "/api/admin/users/:userId/unlock", Summary: "Unlock an account locked after failed sign-ins", Tag: "admin", Auth: true},
+	{Method: http.MethodGet, Path: "/api/admin/emails", Summary: "List queued, sent and failed emails", Tag: "admin", Auth: true, Body: pagination.Page[models.EmailOutbox]{},
+		Query: controllers.EmailListSpec.Params()},
+	{Method: http.MethodGet, Path: "/api/admin/emails/:emailId", Summary: "Get an email's delivery status", Tag: "admin", Auth: true, Response: models.EmailOutbox{}},
+	{Method: http.MethodPost, Path: "/api/admin/emails/:emailId/retry", Summary: "Requeue a failed email", Tag: "admin", Auth: true, Response: models.EmailOutbox{}},
+	{Method: http.MethodGet, Path: "/api/admin/email-templates", Summary: "List email templates and their locales", Tag: "admin", Auth: true, Response: []controllers.EmailTemplateResponse{}},
+	{Method: http.MethodGet, Path: "/api/admin/email-templates/:template/preview", Summary: "Render an email template with sample data", Tag: "admin", Auth: true, Response: utils.RenderedEmail{},
+		Query: []openapi.Param{{Name: "locale"}}},
+}
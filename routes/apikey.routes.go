@@ -0,0 +1,34 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/Llane00/ramen-backend/controllers"
+	"github.com/Llane00/ramen-backend/middleware"
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/openapi"
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyRouteController struct {
+	apiKeyController controllers.APIKeyController
+}
+
+func NewAPIKeyRouteController(apiKeyController controllers.APIKeyController) APIKeyRouteController {
+	return APIKeyRouteController{apiKeyController}
+}
+
+func (kc *APIKeyRouteController) APIKeyRoute(rg *gin.RouterGroup) {
+	// Keys are managed with a session only, so a leaked key cannot mint more.
+	router := rg.Group("/users/me/api-keys")
+	router.Use(middleware.DeserializeUser())
+	router.GET("/", kc.apiKeyController.ListAPIKeys)
+	router.POST("/", kc.apiKeyController.CreateAPIKey)
+	router.DELETE("/:keyId", kc.apiKeyController.RevokeAPIKey)
+}
+
+var apiKeyOperations = []openapi.Operation{
+	{Method: http.MethodGet, Path: "/api/users/me/api-keys/", Summary: "List your API keys", Tag: "api-keys", Auth: true, Response: []models.APIKeyResponse{}},
+	{Method: http.MethodPost, Path: "/api/users/me/api-keys/", Summary: "Create an API key; the key is only shown once", Tag: "api-keys", Auth: true, Request: models.CreateAPIKeyInput{}, Response: models.APIKeyResponse{}, Status: http.StatusCreated},
+	{Method: http.MethodDelete, Path: "/api/users/me/api-keys/:keyId", Summary: "Revoke an API key", Tag: "api-keys", Auth: true, Status: http.StatusNoContent},
+}
@@ -1,27 +1,85 @@
 package routes
 
 import (
+	"net/http"
+	"time"
+
 	"github.com/Llane00/ramen-backend/controllers"
 	"github.com/Llane00/ramen-backend/middleware"
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/openapi"
+	"github.com/Llane00/ramen-backend/ratelimit"
 	"github.com/gin-gonic/gin"
 )
 
+// Policies of the unauthenticated auth endpoints. Per-IP limits slow down a
+// single client; per-email limits protect one account from distributed
+// guessing and cap the emails a victim can be sent.
+var (
+	loginIPPolicy             = ratelimit.Policy{Name: "login_ip", Burst: 20, Period: 15 * time.Minute}
+	loginEmailPolicy          = ratelimit.Policy{Name: "login_email", Burst: 5, Period: 15 * time.Minute}
+	registerIPPolicy          = ratelimit.Policy{Name: "register_ip", Burst: 5, Period: time.Hour}
+	forgotPasswordIPPolicy    = ratelimit.Policy{Name: "forgot_password_ip", Burst: 5, Period: time.Hour}
+	forgotPasswordEmailPolicy = ratelimit.Policy{Name: "forgot_password_email", Burst: 3, Period: time.Hour}
+	magicLinkIPPolicy         = ratelimit.Policy{Name: "magic_link_ip", Burst: 5, Period: time.Hour}
+	magicLinkEmailPolicy      = ratelimit.Policy{Name: "magic_link_email", Burst: 3, Period: time.Hour}
+	verifyResendIPPolicy      = ratelimit.Policy{Name: "verify_resend_ip", Burst: 5, Period: time.Hour}
+	verifyResendEmailPolicy   = ratelimit.Policy{Name: "verify_resend_email", Burst: 3, Period: time.Hour}
+)
+
 type AuthRouteController struct {
 	authController controllers.AuthController
+	limiter        ratelimit.Store
 }
 
-func NewAuthRouteController(authController controllers.AuthController) AuthRouteController {
-	return AuthRouteController{authController}
+func NewAuthRouteController(authController controllers.AuthController, limiter ratelimit.Store) AuthRouteController {
+	return AuthRouteController{authController, limiter}
 }
 
 func (rc *AuthRouteController) AuthRoute(rg *gin.RouterGroup) {
 	router := rg.Group("/auth")
 
-	router.POST("/register", rc.authController.SignUpUser)
-	router.POST("/login", rc.authController.SignInUser)
-	router.GET("/logout", middleware.DeserializeUser(), rc.authController.LogoutUser)
+	router.POST("/register",
+		middleware.RateLimit(rc.limiter, registerIPPolicy, middleware.KeyByIP),
+		rc.authController.SignUpUser)
+	router.POST("/login",
+		middleware.RateLimit(rc.limiter, loginIPPolicy, middleware.KeyByIP),
+		middleware.RateLimit(rc.limiter, loginEmailPolicy, middleware.KeyByEmail),
+		rc.authController.SignInUser)
+	router.POST("/login/mfa",
+		middleware.RateLimit(rc.limiter, loginIPPolicy, middleware.KeyByIP),
+		rc.authController.VerifyMFALogin)
+	router.POST("/magic-link",
+		middleware.RateLimit(rc.limiter, magicLinkIPPolicy, middleware.KeyByIP),
+		middleware.RateLimit(rc.limiter, magicLinkEmailPolicy, middleware.KeyByEmail),
+		rc.authController.RequestMagicLink)
+	router.POST("/magic-link/verify",
+		middleware.RateLimit(rc.limiter, loginIPPolicy, middleware.KeyByIP),
+		rc.authController.VerifyMagicLink)
+	router.GET("/logout", middleware.DeserializeUserPendingMFA(), rc.authController.LogoutUser)
 	router.GET("/verifyemail/:verificationCode", rc.authController.VerifyEmail)
-	router.POST("/forgotpassword", rc.authController.ForgotPassword)
+	router.POST("/verifyemail/resend",
+		middleware.RateLimit(rc.limiter, verifyResendIPPolicy, middleware.KeyByIP),
+		middleware.RateLimit(rc.limiter, verifyResendEmailPolicy, middleware.KeyByEmail),
+		rc.authController.ResendVerificationEmail)
+	router.GET("/confirmemail/:token", rc.authController.ConfirmEmailChange)
+	router.POST("/forgotpassword",
+		middleware.RateLimit(rc.limiter, forgotPasswordIPPolicy, middleware.KeyByIP),
+		middleware.RateLimit(rc.limiter, forgotPasswordEmailPolicy, middleware.KeyByEmail),
+		rc.authController.ForgotPassword)
 	router.PATCH("/resetpassword/:resetToken", rc.authController.ResetPassword)
-	router.GET("/oauth/google", rc.authController.GoogleOAuth)
+}
+
+var authOperations = []openapi.Operation{
+	{Method: http.MethodPost, Path: "/api/auth/register", Summary: "Sign up with email and password", Tag: "auth", Request: models.SignUpInput{}, Status: http.StatusCreated, RateLimited: true},
+	{Method: http.MethodPost, Path: "/api/auth/login", Summary: "Sign in with email and password; returns an mfa_token instead of a session when two-factor is enabled", Tag: "auth", Request: models.SignInInput{}, Body: tokenResponse{}, RateLimited: true},
+	{Method: http.MethodPost, Path: "/api/auth/login/mfa", Summary: "Complete a two-factor sign-in", Tag: "auth", Request: models.MFALoginInput{}, Body: tokenResponse{}, RateLimited: true},
+	{Method: http.MethodPost, Path: "/api/auth/magic-link", Summary: "Email a single-use passwordless sign-in link", Tag: "auth", Request: models.MagicLinkInput{}, RateLimited: true},
+	{Method: http.MethodPost, Path: "/api/auth/magic-link/verify", Summary: "Sign in with a magic link token; returns an mfa_token instead of a session when two-factor is enabled", Tag: "auth", Request: models.MagicLinkVerifyInput{}, Body: tokenResponse{}, RateLimited: true},
+	{Method: http.MethodGet, Path: "/api/auth/logout", Summary: "Clear the session cookies", Tag: "auth", Auth: true},
+	{Method: http.MethodGet, Path: "/api/auth/verifyemail/:verificationCode", Summary: "Verify an email address", Tag: "auth"},
+	{Method: http.MethodPost, Path: "/api/auth/verifyemail/resend", Summary: "Resend the verification email of an unverified account", Tag: "auth", Request: models.ResendVerificationInput{}, RateLimited: true},
+	{Method: http.MethodGet, Path: "/api/auth/confirmemail/:token", Summary: "Confirm a new email address", Tag: "auth"},
+	{Method: http.MethodPost, Path: "/api/auth/forgotpassword", Summary: "Request a password reset email", Tag: "auth", Request: models.ForgotPasswordInput{}, RateLimited: true},
+	{Method: http.MethodPatch, Path: "/api/auth/resetpassword/:resetToken", Summary: "Reset the password with a reset token", Tag: "auth", Request: models.ResetPasswordInput{}},
 }
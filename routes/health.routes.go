@@ -0,0 +1,38 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/Llane00/ramen-backend/controllers"
+	"github.com/Llane00/ramen-backend/openapi"
+	"github.com/gin-gonic/gin"
+)
+
+type HealthRouteController struct {
+	healthController controllers.HealthController
+}
+
+func NewHealthRouteController(healthController controllers.HealthController) HealthRouteController {
+	return HealthRouteController{healthController}
+}
+
+func (hc *HealthRouteController) HealthRoute(rg *gin.RouterGroup) {
+	rg.GET("/api/healthchecker", hc.healthController.Healthchecker)
+	rg.GET("/livez", hc.healthController.Livez)
+	rg.GET("/readyz", hc.healthController.Readyz)
+}
+
+type healthResponse struct {
+	Status string `json:"status"`
+	Checks map[string]struct {
+		Status   string `json:"status"`
+		Message  string `json:"message,omitempty"`
+		Duration string `json:"duration"`
+	} `json:"checks,omitempty"`
+}
+
+var healthOperations = []openapi.Operation{
+	{Method: http.MethodGet, Path: "/api/healthchecker", Summary: "Static welcome message", Tag: "health"},
+	{Method: http.MethodGet, Path: "/livez", Summary: "Liveness probe", Tag: "health", Body: healthResponse{}},
+	{Method: http.MethodGet, Path: "/readyz", Summary: "Readiness probe with dependency checks", Tag: "health", Body: healthResponse{}},
+}
@@ -0,0 +1,50 @@
+package routes
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Llane00/ramen-backend/controllers"
+	"github.com/Llane00/ramen-backend/middleware"
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/openapi"
+	"github.com/Llane00/ramen-backend/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// reauthUserPolicy limits password and second factor guesses made with a
+// stolen session.
+var reauthUserPolicy = ratelimit.Policy{Name: "reauth_user", Burst: 5, Period: 15 * time.Minute}
+
+type IdentityRouteController struct {
+	identityController controllers.IdentityController
+	limiter            ratelimit.Store
+}
+
+func NewIdentityRouteController(identityController controllers.IdentityController, limiter ratelimit.Store) IdentityRouteController {
+	return IdentityRouteController{identityController, limiter}
+}
+
+func (rc *IdentityRouteController) IdentityRoute(rg *gin.RouterGroup) {
+	router := rg.Group("/users/me/identities")
+	router.Use(middleware.DeserializeUser())
+	router.GET("/", rc.identityController.ListIdentities)
+	router.POST("/:provider",
+		middleware.RateLimit(rc.limiter, reauthUserPolicy, middleware.KeyByUser),
+		rc.identityController.LinkIdentity)
+	router.DELETE("/:identityId",
+		middleware.RateLimit(rc.limiter, reauthUserPolicy, middleware.KeyByUser),
+		rc.identityController.UnlinkIdentity)
+
+	rg.POST("/users/me/reauthentication",
+		middleware.DeserializeUser(),
+		middleware.RateLimit(rc.limiter, reauthUserPolicy, middleware.KeyByUser),
+		rc.identityController.RequestReauthentication)
+}
+
+var identityOperations = []openapi.Operation{
+	{Method: http.MethodGet, Path: "/api/users/me/identities/", Summary: "List the provider accounts linked to you", Tag: "users", Auth: true, Response: []models.UserIdentityResponse{}},
+	{Method: http.MethodPost, Path: "/api/users/me/identities/:provider", Summary: "Start linking a provider account; requires your password and second factor, or an emailed confirmation code", Tag: "users", Auth: true, Request: models.LinkIdentityInput{}, Response: models.LinkIdentityResponse{}, RateLimited: true},
+	{Method: http.MethodDelete, Path: "/api/users/me/identities/:identityId", Summary: "Unlink a provider account; requires your password and second factor, or an emailed confirmation code", Tag: "users", Auth: true, Request: models.ReauthenticateInput{}, Status: http.StatusNoContent, RateLimited: true},
+	{Method: http.MethodPost, Path: "/api/users/me/reauthentication", Summary: "Email a confirmation code to users without a password or second factor", Tag: "users", Auth: true, Status: http.StatusAccepted, RateLimited: true},
+}
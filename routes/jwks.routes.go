@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/Llane00/ramen-backend/controllers"
+	"github.com/Llane00/ramen-backend/keyset"
+	"github.com/Llane00/ramen-backend/openapi"
+	"github.com/gin-gonic/gin"
+)
+
+type JWKSRouteController struct {
+	jwksController controllers.JWKSController
+}
+
+func NewJWKSRouteController(jwksController controllers.JWKSController) JWKSRouteController {
+	return JWKSRouteController{jwksController}
+}
+
+// JWKSRoute mounts the key set at the well-known path on the root group.
+func (jc *JWKSRouteController) JWKSRoute(rg *gin.RouterGroup) {
+	rg.GET("/.well-known/jwks.json", jc.jwksController.JWKS)
+}
+
+var jwksOperations = []openapi.Operation{
+	{Method: http.MethodGet, Path: "/.well-known/jwks.json", Summary: "Public keys that verify access tokens", Tag: "auth", Body: keyset.JWKS{}},
+}
@@ -0,0 +1,34 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/Llane00/ramen-backend/controllers"
+	"github.com/Llane00/ramen-backend/middleware"
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/openapi"
+	"github.com/gin-gonic/gin"
+)
+
+type KitchenRouteController struct {
+	kitchenController controllers.KitchenController
+}
+
+func NewKitchenRouteController(kitchenController controllers.KitchenController) KitchenRouteController {
+	return KitchenRouteController{kitchenController}
+}
+
+func (kc *KitchenRouteController) KitchenRoute(rg *gin.RouterGroup) {
+	router := rg.Group("/shops/:shopId/kitchen")
+	router.Use(middleware.DeserializeUserOrAPIKey("orders"))
+	router.GET("/queue", kc.kitchenController.GetKitchenQueue)
+	router.PATCH("/items/:itemId/status", kc.kitchenController.UpdateKitchenItemStatus)
+	router.GET("/prep-times", kc.kitchenController.GetPrepTimes)
+}
+
+var kitchenOperations = withAPIKey("orders", []openapi.Operation{
+	{Method: http.MethodGet, Path: "/api/shops/:shopId/kitchen/queue", Summary: "List paid, undelivered orders with their items, longest paid first", Tag: "kitchen", Auth: true, Response: []models.Order{}},
+	{Method: http.MethodPatch, Path: "/api/shops/:shopId/kitchen/items/:itemId/status", Summary: "Mark an order item preparing or ready", Tag: "kitchen", Auth: true, Request: models.UpdateOrderItemStatusInput{}, Response: models.OrderItem{}},
+	{Method: http.MethodGet, Path: "/api/shops/:shopId/kitchen/prep-times", Summary: "Average preparation time per product", Tag: "kitchen", Auth: true, Response: []models.ProductPrepTime{},
+		Query: []openapi.Param{{Name: "since"}}},
+})
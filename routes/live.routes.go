@@ -0,0 +1,35 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/Llane00/ramen-backend/controllers"
+	"github.com/Llane00/ramen-backend/middleware"
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/openapi"
+	"github.com/gin-gonic/gin"
+)
+
+type LiveRouteController struct {
+	liveController controllers.LiveController
+}
+
+func NewLiveRouteController(liveController controllers.LiveController) LiveRouteController {
+	return LiveRouteController{liveController}
+}
+
+func (lc *LiveRouteController) LiveRoute(rg *gin.RouterGroup) {
+	router := rg.Group("/live")
+	router.Use(middleware.WebSocketToken(), middleware.DeserializeUser())
+	router.GET("/orders/:orderId", lc.liveController.SubscribeOrder)
+	router.GET("/shops/:shopId/orders", lc.liveController.SubscribeShopOrders)
+}
+
+// Both endpoints are WebSockets; each message is {"event", "data"} with an
+// order event as data.
+var liveOperations = []openapi.Operation{
+	{Method: http.MethodGet, Path: "/api/live/orders/:orderId", Summary: "Follow an order's status and payment over a WebSocket", Tag: "live", Auth: true,
+		Status: http.StatusSwitchingProtocols, Body: models.OrderEvent{}},
+	{Method: http.MethodGet, Path: "/api/live/shops/:shopId/orders", Summary: "Follow every order of your shop over a WebSocket", Tag: "live", Auth: true,
+		Status: http.StatusSwitchingProtocols, Body: models.OrderEvent{}},
+}
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/Llane00/ramen-backend/initializers"
+	"github.com/Llane00/ramen-backend/middleware"
+	"github.com/Llane00/ramen-backend/openapi"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func MetricsRoute(rg *gin.RouterGroup, config *initializers.Config) {
+	rg.GET("/metrics", middleware.MetricsAccess(config), gin.WrapH(promhttp.Handler()))
+}
+
+var metricsOperations = []openapi.Operation{
+	{Method: http.MethodGet, Path: "/metrics", Summary: "Prometheus metrics", Tag: "metrics", ContentType: "text/plain"},
+}
@@ -0,0 +1,36 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/Llane00/ramen-backend/controllers"
+	"github.com/Llane00/ramen-backend/middleware"
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/openapi"
+	"github.com/gin-gonic/gin"
+)
+
+type MFARouteController struct {
+	mfaController controllers.MFAController
+}
+
+func NewMFARouteController(mfaController controllers.MFAController) MFARouteController {
+	return MFARouteController{mfaController}
+}
+
+func (mc *MFARouteController) MFARoute(rg *gin.RouterGroup) {
+	router := rg.Group("/auth/2fa")
+	// Users whose role requires two-factor must be able to reach enrollment.
+	router.Use(middleware.DeserializeUserPendingMFA())
+	router.POST("/enroll", mc.mfaController.Enroll)
+	router.POST("/confirm", mc.mfaController.Confirm)
+	router.POST("/disable", mc.mfaController.Disable)
+	router.POST("/recovery-codes", mc.mfaController.RegenerateRecoveryCodes)
+}
+
+var mfaOperations = []openapi.Operation{
+	{Method: http.MethodPost, Path: "/api/auth/2fa/enroll", Summary: "Start TOTP enrollment", Tag: "2fa", Auth: true, Response: models.MFAEnrollmentResponse{}},
+	{Method: http.MethodPost, Path: "/api/auth/2fa/confirm", Summary: "Confirm TOTP enrollment and get recovery codes", Tag: "2fa", Auth: true, Request: models.MFACodeInput{}, Response: models.RecoveryCodesResponse{}},
+	{Method: http.MethodPost, Path: "/api/auth/2fa/disable", Summary: "Disable two-factor authentication", Tag: "2fa", Auth: true, Request: models.DisableMFAInput{}},
+	{Method: http.MethodPost, Path: "/api/auth/2fa/recovery-codes", Summary: "Replace the recovery codes", Tag: "2fa", Auth: true, Request: models.MFACodeInput{}, Response: models.RecoveryCodesResponse{}},
+}
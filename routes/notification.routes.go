@@ -0,0 +1,34 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/Llane00/ramen-backend/controllers"
+	"github.com/Llane00/ramen-backend/middleware"
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/openapi"
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationRouteController struct {
+	notificationController controllers.NotificationController
+}
+
+func NewNotificationRouteController(notificationController controllers.NotificationController) NotificationRouteController {
+	return NotificationRouteController{notificationController}
+}
+
+func (nc *NotificationRouteController) NotificationRoute(rg *gin.RouterGroup) {
+	router := rg.Group("/users/me/notifications")
+	router.Use(middleware.DeserializeUser())
+	router.GET("", nc.notificationController.ListNotifications)
+	router.POST("/read", nc.notificationController.MarkNotificationsRead)
+	router.GET("/stream", nc.notificationController.StreamNotifications)
+}
+
+var notificationOperations = []openapi.Operation{
+	{Method: http.MethodGet, Path: "/api/users/me/notifications", Summary: "List your notifications with the unread count", Tag: "notifications", Auth: true, Body: controllers.NotificationPage{},
+		Query: append(controllers.NotificationListSpec.Params(), openapi.Param{Name: "unread"})},
+	{Method: http.MethodPost, Path: "/api/users/me/notifications/read", Summary: "Mark the listed notifications, or all of them, as read", Tag: "notifications", Auth: true, Request: models.MarkNotificationsReadInput{}, Response: controllers.UnreadCount{}},
+	{Method: http.MethodGet, Path: "/api/users/me/notifications/stream", Summary: "Stream new notifications and unread counts as Server-Sent Events", Tag: "notifications", Auth: true, ContentType: "text/event-stream"},
+}
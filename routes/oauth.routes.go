@@ -0,0 +1,40 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/Llane00/ramen-backend/controllers"
+	"github.com/Llane00/ramen-backend/middleware"
+	"github.com/Llane00/ramen-backend/openapi"
+	"github.com/Llane00/ramen-backend/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+type OAuthRouteController struct {
+	oauthController controllers.OAuthController
+	limiter         ratelimit.Store
+}
+
+func NewOAuthRouteController(oauthController controllers.OAuthController, limiter ratelimit.Store) OAuthRouteController {
+	return OAuthRouteController{oauthController, limiter}
+}
+
+// OAuthRoute mounts the provider sign-in flow. The callback path is the
+// redirect URL registered with each provider.
+func (rc *OAuthRouteController) OAuthRoute(rg *gin.RouterGroup) {
+	router := rg.Group("/auth/oauth")
+
+	router.GET("/providers", rc.oauthController.ListProviders)
+	router.GET("/:provider/login", rc.oauthController.Begin)
+	router.GET("/:provider",
+		middleware.RateLimit(rc.limiter, loginIPPolicy, middleware.KeyByIP),
+		rc.oauthController.Callback)
+}
+
+var oauthOperations = []openapi.Operation{
+	{Method: http.MethodGet, Path: "/api/auth/oauth/providers", Summary: "List the configured sign-in providers", Tag: "auth", Response: oauthProvidersData{}},
+	{Method: http.MethodGet, Path: "/api/auth/oauth/:provider/login", Summary: "Redirect to the provider's consent page; redirect is the client path to return to", Tag: "auth", Status: http.StatusTemporaryRedirect,
+		Query: []openapi.Param{{Name: "redirect"}}},
+	{Method: http.MethodGet, Path: "/api/auth/oauth/:provider", Summary: "Provider sign-in callback", Tag: "auth", Status: http.StatusTemporaryRedirect, RateLimited: true,
+		Query: []openapi.Param{{Name: "code", Required: true}, {Name: "state", Required: true}, {Name: "error"}}},
+}
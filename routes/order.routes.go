@@ -1,25 +1,46 @@
 package routes
 
 import (
+	"net/http"
+	"time"
+
 	"github.com/Llane00/ramen-backend/controllers"
 	"github.com/Llane00/ramen-backend/middleware"
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/openapi"
+	"github.com/Llane00/ramen-backend/pagination"
+	"github.com/Llane00/ramen-backend/ratelimit"
 	"github.com/gin-gonic/gin"
 )
 
+// createOrderPolicy limits how fast one user can place orders.
+var createOrderPolicy = ratelimit.Policy{Name: "create_order_user", Burst: 10, Period: time.Minute}
+
 type OrderRouteController struct {
 	orderController controllers.OrderController
+	limiter         ratelimit.Store
 }
 
-func NewOrderRouteController(orderController controllers.OrderController) OrderRouteController {
-	return OrderRouteController{orderController}
+func NewOrderRouteController(orderController controllers.OrderController, limiter ratelimit.Store) OrderRouteController {
+	return OrderRouteController{orderController, limiter}
 }
 
 func (oc *OrderRouteController) OrderRoute(rg *gin.RouterGroup) {
 	router := rg.Group("/shops/:shopId/orders")
-	router.Use(middleware.DeserializeUser())
-	router.POST("/", oc.orderController.CreateOrder)
+	router.Use(middleware.DeserializeUserOrAPIKey("orders"))
+	router.POST("/", middleware.RateLimit(oc.limiter, createOrderPolicy, middleware.KeyByUser), oc.orderController.CreateOrder)
 	router.GET("/", oc.orderController.ListOrders)
 	router.GET("/:orderId", oc.orderController.GetOrder)
 	router.PATCH("/:orderId/status", oc.orderController.UpdateOrderStatus)
 	router.GET("/:orderId/payments", oc.orderController.GetOrderPayments)
 }
+
+var orderOperations = withAPIKey("orders", []openapi.Operation{
+	{Method: http.MethodPost, Path: "/api/shops/:shopId/orders/", Summary: "Place an order", Tag: "orders", Auth: true, Request: models.CreateOrderInput{}, Response: models.Order{}, Status: http.StatusCreated, RateLimited: true},
+	{Method: http.MethodGet, Path: "/api/shops/:shopId/orders/", Summary: "List orders", Tag: "orders", Auth: true, Body: pagination.Page[models.Order]{},
+		Query: controllers.OrderListSpec.Params()},
+	{Method: http.MethodGet, Path: "/api/shops/:shopId/orders/:orderId", Summary: "Get an order with its items", Tag: "orders", Auth: true, Response: models.Order{}},
+	{Method: http.MethodPatch, Path: "/api/shops/:shopId/orders/:orderId/status", Summary: "Update order status", Tag: "orders", Auth: true, Request: models.UpdateOrderStatusInput{}, Response: models.Order{}},
+	{Method: http.MethodGet, Path: "/api/shops/:shopId/orders/:orderId/payments", Summary: "List an order's payments", Tag: "orders", Auth: true, Body: pagination.Page[models.Payment]{},
+		Query: controllers.PaymentListSpec.Params()},
+})
@@ -1,8 +1,13 @@
 package routes
 
 import (
+	"net/http"
+
 	"github.com/Llane00/ramen-backend/controllers"
 	"github.com/Llane00/ramen-backend/middleware"
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/openapi"
+	"github.com/Llane00/ramen-backend/pagination"
 	"github.com/gin-gonic/gin"
 )
 
@@ -16,10 +21,18 @@ func NewPaymentRouteController(paymentController controllers.PaymentController)
 
 func (pc *PaymentRouteController) PaymentRoute(rg *gin.RouterGroup) {
 	router := rg.Group("/orders/:orderId/payments")
-	router.Use(middleware.DeserializeUser())
+	router.Use(middleware.DeserializeUserOrAPIKey("payments"))
 
 	router.POST("/", pc.paymentController.CreatePayment)
 	router.GET("/", pc.paymentController.ListPayments)
 	router.GET("/:id", pc.paymentController.GetPayment)
 	router.PATCH("/:id/status", pc.paymentController.UpdatePaymentStatus)
 }
+
+var paymentOperations = withAPIKey("payments", []openapi.Operation{
+	{Method: http.MethodPost, Path: "/api/orders/:orderId/payments/", Summary: "Create a payment", Tag: "payments", Auth: true, Request: models.CreatePaymentInput{}, Response: models.Payment{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/api/orders/:orderId/payments/", Summary: "List payments", Tag: "payments", Auth: true, Body: pagination.Page[models.Payment]{},
+		Query: controllers.PaymentListSpec.Params()},
+	{Method: http.MethodGet, Path: "/api/orders/:orderId/payments/:id", Summary: "Get a payment", Tag: "payments", Auth: true, Response: models.Payment{}},
+	{Method: http.MethodPatch, Path: "/api/orders/:orderId/payments/:id/status", Summary: "Update payment status", Tag: "payments", Auth: true, Request: models.UpdatePaymentStatusInput{}, Response: models.Payment{}},
+})
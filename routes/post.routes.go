@@ -1,8 +1,13 @@
 package routes
 
 import (
+	"net/http"
+
 	"github.com/Llane00/ramen-backend/controllers"
 	"github.com/Llane00/ramen-backend/middleware"
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/openapi"
+	"github.com/Llane00/ramen-backend/pagination"
 	"github.com/gin-gonic/gin"
 )
 
@@ -24,3 +29,12 @@ func (pc *PostRouteController) PostRoute(rg *gin.RouterGroup) {
 	router.GET("/:postId", pc.postController.FindPostById)
 	router.DELETE("/:postId", pc.postController.DeletePost)
 }
+
+var postOperations = []openapi.Operation{
+	{Method: http.MethodPost, Path: "/api/posts/", Summary: "Create a post", Tag: "posts", Auth: true, Request: models.CreatePostRequest{}, Response: models.Post{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/api/posts/", Summary: "List posts", Tag: "posts", Auth: true, Body: pagination.Page[models.Post]{},
+		Query: controllers.PostListSpec.Params()},
+	{Method: http.MethodPut, Path: "/api/posts/:postId", Summary: "Update a post", Tag: "posts", Auth: true, Request: models.UpdatePost{}, Response: models.Post{}},
+	{Method: http.MethodGet, Path: "/api/posts/:postId", Summary: "Get a post", Tag: "posts", Auth: true, Response: models.Post{}},
+	{Method: http.MethodDelete, Path: "/api/posts/:postId", Summary: "Delete a post", Tag: "posts", Auth: true, Status: http.StatusNoContent},
+}
@@ -1,8 +1,13 @@
 package routes
 
 import (
+	"net/http"
+
 	"github.com/Llane00/ramen-backend/controllers"
 	"github.com/Llane00/ramen-backend/middleware"
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/openapi"
+	"github.com/Llane00/ramen-backend/pagination"
 	"github.com/gin-gonic/gin"
 )
 
@@ -16,7 +21,7 @@ func NewProductRouteController(productController controllers.ProductController)
 
 func (pc *ProductRouteController) ProductRoute(rg *gin.RouterGroup) {
 	router := rg.Group("/shops/:shopId/products")
-	router.Use(middleware.DeserializeUser())
+	router.Use(middleware.DeserializeUserOrAPIKey("products"))
 
 	router.POST("/", pc.productController.CreateProduct)
 	router.GET("/", pc.productController.ListProducts)
@@ -25,3 +30,17 @@ func (pc *ProductRouteController) ProductRoute(rg *gin.RouterGroup) {
 	router.DELETE("/:productId", pc.productController.DeleteProduct)
 	router.PATCH("/:productId/stock", pc.productController.UpdateProductStock)
 }
+
+type updateProductStockInput struct {
+	Stock int `json:"stock" binding:"required"`
+}
+
+var productOperations = withAPIKey("products", []openapi.Operation{
+	{Method: http.MethodPost, Path: "/api/shops/:shopId/products/", Summary: "Create a product", Tag: "products", Auth: true, Request: models.CreateProductInput{}, Response: models.Product{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/api/shops/:shopId/products/", Summary: "List products", Tag: "products", Auth: true, Body: pagination.Page[models.Product]{},
+		Query: controllers.ProductListSpec.Params()},
+	{Method: http.MethodGet, Path: "/api/shops/:shopId/products/:productId", Summary: "Get a product", Tag: "products", Auth: true, Response: models.Product{}},
+	{Method: http.MethodPut, Path: "/api/shops/:shopId/products/:productId", Summary: "Update a product", Tag: "products", Auth: true, Request: models.UpdateProductInput{}, Response: models.Product{}},
+	{Method: http.MethodDelete, Path: "/api/shops/:shopId/products/:productId", Summary: "Delete a product", Tag: "products", Auth: true},
+	{Method: http.MethodPatch, Path: "/api/shops/:shopId/products/:productId/stock", Summary: "Update product stock", Tag: "products", Auth: true, Request: updateProductStockInput{}, Response: models.Product{}},
+})
@@ -1,8 +1,13 @@
 package routes
 
 import (
+	"net/http"
+
 	"github.com/Llane00/ramen-backend/controllers"
 	"github.com/Llane00/ramen-backend/middleware"
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/openapi"
+	"github.com/Llane00/ramen-backend/pagination"
 	"github.com/gin-gonic/gin"
 )
 
@@ -16,7 +21,7 @@ func NewShopRouteController(shopController controllers.ShopController) ShopRoute
 
 func (sc *ShopRouteController) ShopRoute(rg *gin.RouterGroup) {
 	router := rg.Group("/shops")
-	router.Use(middleware.DeserializeUser())
+	router.Use(middleware.DeserializeUserOrAPIKey("shops"))
 
 	router.POST("/", sc.shopController.CreateShop)
 	router.GET("/", sc.shopController.ListShops)
@@ -25,4 +30,25 @@ func (sc *ShopRouteController) ShopRoute(rg *gin.RouterGroup) {
 	router.DELETE("/:shopId", sc.shopController.DeleteShop)
 	router.GET("/:shopId/products", sc.shopController.GetShopProducts)
 	router.GET("/:shopId/orders", sc.shopController.GetShopOrders)
+	router.PUT("/:shopId/hours", sc.shopController.UpdateOpeningHours)
+	router.PUT("/:shopId/ordering", sc.shopController.UpdateOrdering)
+	router.POST("/:shopId/closures", sc.shopController.CreateClosure)
+	router.DELETE("/:shopId/closures/:closureId", sc.shopController.DeleteClosure)
 }
+
+var shopOperations = withAPIKey("shops", []openapi.Operation{
+	{Method: http.MethodPost, Path: "/api/shops/", Summary: "Create a shop", Tag: "shops", Auth: true, Request: models.CreateShopInput{}, Response: models.Shop{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/api/shops/", Summary: "List shops", Tag: "shops", Auth: true, Body: pagination.Page[models.Shop]{},
+		Query: controllers.ShopListSpec.Params()},
+	{Method: http.MethodGet, Path: "/api/shops/:shopId", Summary: "Get a shop", Tag: "shops", Auth: true, Response: models.Shop{}},
+	{Method: http.MethodPut, Path: "/api/shops/:shopId", Summary: "Update a shop", Tag: "shops", Auth: true, Request: models.UpdateShopInput{}, Response: models.Shop{}},
+	{Method: http.MethodDelete, Path: "/api/shops/:shopId", Summary: "Delete a shop", Tag: "shops", Auth: true},
+	{Method: http.MethodGet, Path: "/api/shops/:shopId/products", Summary: "List a shop's products", Tag: "shops", Auth: true, Body: pagination.Page[models.Product]{},
+		Query: controllers.ProductListSpec.Params()},
+	{Method: http.MethodGet, Path: "/api/shops/:shopId/orders", Summary: "List a shop's orders", Tag: "shops", Auth: true, Body: pagination.Page[models.Order]{},
+		Query: controllers.OrderListSpec.Params()},
+	{Method: http.MethodPut, Path: "/api/shops/:shopId/hours", Summary: "Replace a shop's weekly opening hours", Tag: "shops", Auth: true, Request: models.UpdateOpeningHoursInput{}, Response: models.Shop{}},
+	{Method: http.MethodPut, Path: "/api/shops/:shopId/ordering", Summary: "Pause or resume taking orders", Tag: "shops", Auth: true, Request: models.UpdateOrderingInput{}, Response: models.Shop{}},
+	{Method: http.MethodPost, Path: "/api/shops/:shopId/closures", Summary: "Close a shop for a holiday or other days", Tag: "shops", Auth: true, Request: models.CreateShopClosureInput{}, Response: models.ShopClosure{}, Status: http.StatusCreated},
+	{Method: http.MethodDelete, Path: "/api/shops/:shopId/closures/:closureId", Summary: "Remove a closure", Tag: "shops", Auth: true, Status: http.StatusNoContent},
+})
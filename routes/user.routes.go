@@ -1,21 +1,40 @@
 package routes
 
 import (
+	"net/http"
+
 	"github.com/Llane00/ramen-backend/controllers"
 	"github.com/Llane00/ramen-backend/middleware"
+	"github.com/Llane00/ramen-backend/models"
+	"github.com/Llane00/ramen-backend/openapi"
+	"github.com/Llane00/ramen-backend/ratelimit"
 	"github.com/gin-gonic/gin"
 )
 
 type UserRouteController struct {
 	userController controllers.UserController
+	limiter        ratelimit.Store
 }
 
-func NewRouteUserController(userController controllers.UserController) UserRouteController {
-	return UserRouteController{userController}
+func NewRouteUserController(userController controllers.UserController, limiter ratelimit.Store) UserRouteController {
+	return UserRouteController{userController, limiter}
 }
 
 func (uc *UserRouteController) UserRoute(rg *gin.RouterGroup) {
 
 	router := rg.Group("users")
-	router.GET("/me", middleware.DeserializeUser(), uc.userController.GetMe)
+	router.GET("/me", middleware.DeserializeUserPendingMFA(), uc.userController.GetMe)
+	router.POST("/me/email",
+		middleware.DeserializeUser(),
+		middleware.RateLimit(uc.limiter, reauthUserPolicy, middleware.KeyByUser),
+		uc.userController.ChangeEmail)
+	router.PUT("/me/locale", middleware.DeserializeUser(), uc.userController.UpdateLocale)
+	router.PATCH("/me/notification-preferences", middleware.DeserializeUser(), uc.userController.UpdateNotificationPreferences)
+}
+
+var userOperations = []openapi.Operation{
+	{Method: http.MethodGet, Path: "/api/users/me", Summary: "Current user", Tag: "users", Auth: true, Response: userData{}},
+	{Method: http.MethodPost, Path: "/api/users/me/email", Summary: "Change your email address; requires your password and second factor or an emailed confirmation code, and confirmation from the new address", Tag: "users", Auth: true, Request: models.ChangeEmailInput{}, Status: http.StatusAccepted, RateLimited: true},
+	{Method: http.MethodPut, Path: "/api/users/me/locale", Summary: "Set the language of your emails", Tag: "users", Auth: true, Request: models.UpdateLocaleInput{}, Response: localeData{}},
+	{Method: http.MethodPatch, Path: "/api/users/me/notification-preferences", Summary: "Opt in or out of order and payment emails", Tag: "users", Auth: true, Request: models.UpdateNotificationPreferencesInput{}, Response: models.NotificationPreferences{}},
 }
@@ -0,0 +1,41 @@
+package secret
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSeal(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	const plaintext = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	sealed, err := Seal(key, plaintext)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if !IsSealed(sealed) || IsSealed(plaintext) {
+		t.Fatalf("IsSealed does not tell %q from plaintext", sealed)
+	}
+	if again, _ := Seal(key, plaintext); again == sealed {
+		t.Error("Seal reused a nonce")
+	}
+
+	opened, err := Open(key, sealed)
+	if err != nil || opened != plaintext {
+		t.Fatalf("Open = %q, %v, want %q", opened, err, plaintext)
+	}
+
+	if _, err := Open(bytes.Repeat([]byte{2}, 32), sealed); err == nil {
+		t.Error("Open accepted the wrong key")
+	}
+	tampered := []byte(sealed)
+	tampered[len(sealedPrefix)+4] ^= 1
+	if _, err := Open(key, string(tampered)); err == nil {
+		t.Error("Open accepted a tampered value")
+	}
+	if _, err := Open(key, plaintext); err == nil {
+		t.Error("Open accepted plaintext")
+	}
+	if _, err := Seal(key[:16], plaintext); err == nil {
+		t.Error("Seal accepted a 16 byte key")
+	}
+}
@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"strings"
+
+	"github.com/thanhpk/randstr"
+)
+
+// API keys look like "rk_<prefix>_<secret>". The prefix identifies the key in
+// the database and in listings; only the HashToken of the key is stored.
+const apiKeyTag = "rk"
+
+// GenerateAPIKey returns a new key and its prefix.
+func GenerateAPIKey() (key string, prefix string) {
+	prefix = randstr.Hex(12)
+	return apiKeyTag + "_" + prefix + "_" + randstr.Base62(40), prefix
+}
+
+// ParseAPIKey returns the prefix of key, or false if key is not shaped like
+// an API key.
+func ParseAPIKey(key string) (prefix string, ok bool) {
+	parts := strings.Split(key, "_")
+	if len(parts) != 3 || parts[0] != apiKeyTag || len(parts[1]) != 12 || parts[2] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
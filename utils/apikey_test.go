@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateAPIKey(t *testing.T) {
+	key, prefix := GenerateAPIKey()
+	if !strings.HasPrefix(key, "rk_"+prefix+"_") {
+		t.Fatalf("key %q does not start with its prefix %q", key, prefix)
+	}
+	if got, ok := ParseAPIKey(key); !ok || got != prefix {
+		t.Fatalf("ParseAPIKey(%q) = %q, %v, want %q, true", key, got, ok, prefix)
+	}
+	if other, _ := GenerateAPIKey(); other == key {
+		t.Fatal("GenerateAPIKey returned the same key twice")
+	}
+}
+
+func TestParseAPIKeyRejectsMalformedKeys(t *testing.T) {
+	for _, key := range []string{
+		"",
+		"rk_0123456789ab_",
+		"rk_0123456789a_secret",
+		"xx_0123456789ab_secret",
+		"rk_0123456789ab_secret_extra",
+	} {
+		if _, ok := ParseAPIKey(key); ok {
+			t.Errorf("ParseAPIKey(%q) accepted", key)
+		}
+	}
+}
@@ -2,141 +2,271 @@ package utils
 
 import (
 	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
+	"html"
 	"html/template"
-	"io"
-	"log"
-	"net/http"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
-	"github.com/Llane00/ramen-backend/initializers"
+	"github.com/Llane00/ramen-backend/mailer"
+	"github.com/Llane00/ramen-backend/metrics"
 	"github.com/Llane00/ramen-backend/models"
 	"github.com/k3a/html2text"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type EmailData struct {
 	URL       string
 	FirstName string
-	Subject   string
+	// Subject is set from the template's "subject" block when rendering.
+	Subject string
+	// Locale is the locale the email is rendered in.
+	Locale string
+
+	// Sign-in details for security notifications.
+	IPAddress string
+	Device    string
+	Time      string
+
+	// NewEmail is the requested address in email change notices.
+	NewEmail string
+
+	// Order is set for order and payment emails.
+	Order *OrderEmailData
 }
 
-type EmailRequest struct {
-	From struct {
-		Email string `json:"email"`
-		Name  string `json:"name"`
-	} `json:"from"`
-	To []struct {
-		Email string `json:"email"`
-		Name  string `json:"name"`
-	} `json:"to"`
+// OrderEmailData describes an order with its amounts already formatted.
+type OrderEmailData struct {
+	Number   string
+	ShopName string
+	Status   string
+	Items    []OrderLineData
+	Total    string
+
+	// The payment of receipts and refund confirmations.
+	PaymentMethod string
+	Amount        string
+}
+
+type OrderLineData struct {
+	Name      string
+	Quantity  int
+	UnitPrice string
+	Subtotal  string
+}
+
+// RenderedEmail is a template rendered for one recipient.
+type RenderedEmail struct {
+	// Locale is the locale of the template used, after fallback.
+	Locale  string `json:"locale"`
 	Subject string `json:"subject"`
-	Text    string `json:"text"`
 	HTML    string `json:"html"`
+	Text    string `json:"text"`
+}
+
+// emailer is what SendEmail delivers with. InitEmail sets it at startup.
+var emailer struct {
+	mailer mailer.Mailer
+	// templates holds the pages of each locale, keyed by locale and then by
+	// file name.
+	templates map[string]map[string]*template.Template
+	from      string
+	fromName  string
 }
 
-const MailtrapApiUrl = "https://send.api.mailtrap.io/api/send"
-const MailtrapTestApiUrl = "https://sandbox.api.mailtrap.io/api/send/3171336"
+// InitEmail parses the templates in templateDir once and routes every email
+// through m, sent from the given address.
+func InitEmail(m mailer.Mailer, templateDir string, from string, fromName string) error {
+	templates, err := ParseTemplateDir(templateDir)
+	if err != nil {
+		return fmt.Errorf("could not parse templates: %w", err)
+	}
+
+	emailer.mailer = m
+	emailer.templates = templates
+	emailer.from = from
+	emailer.fromName = fromName
+	return nil
+}
 
-func ParseTemplateDir(dir string) (*template.Template, error) {
-	var paths []string
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+// ParseTemplateDir parses the email templates in dir, keyed by locale and
+// then by file name. Pages in dir are the DefaultLocale; a subdirectory named
+// after a locale, e.g. dir/ja, holds translations of some or all of them and
+// may override layouts in its own layouts directory.
+// Every page is parsed into its own set with the layouts in dir/layouts, so
+// each page can define its own "content" and "subject" blocks.
+func ParseTemplateDir(dir string) (map[string]map[string]*template.Template, error) {
+	layouts, err := template.ParseGlob(filepath.Join(dir, "layouts", "*.html"))
+	if err != nil {
+		return nil, err
+	}
+
+	templates := map[string]map[string]*template.Template{}
+	templates[DefaultLocale], err = parsePages(layouts, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == "layouts" {
+			continue
+		}
+		locale := NormalizeLocale(entry.Name())
+		if locale == "" {
+			return nil, fmt.Errorf("template directory %s is not named after a locale", entry.Name())
+		}
+
+		localeDir := filepath.Join(dir, entry.Name())
+		localeLayouts, err := overrideLayouts(layouts, filepath.Join(localeDir, "layouts"))
 		if err != nil {
-			return err
+			return nil, err
 		}
-		if !info.IsDir() {
-			paths = append(paths, path)
+		if templates[locale], err = parsePages(localeLayouts, localeDir); err != nil {
+			return nil, err
 		}
-		return nil
-	})
+	}
+	return templates, nil
+}
 
+// overrideLayouts returns layouts with the blocks redefined in dir, e.g. a
+// translated partial, or layouts itself if dir has none.
+func overrideLayouts(layouts *template.Template, dir string) (*template.Template, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil || len(files) == 0 {
+		return layouts, err
+	}
+
+	t, err := layouts.Clone()
 	if err != nil {
 		return nil, err
 	}
-
-	return template.ParseFiles(paths...)
+	return t.ParseFiles(files...)
 }
 
-func SendEmail(user *models.User, data *EmailData, emailTemp string) error {
-	config, err := initializers.LoadConfig(".")
-
+func parsePages(layouts *template.Template, dir string) (map[string]*template.Template, error) {
+	pages, err := filepath.Glob(filepath.Join(dir, "*.html"))
 	if err != nil {
-		log.Fatal("could not load config", err)
+		return nil, err
 	}
 
-	// Sender data
-	from := config.EmailFrom
-	to := user.Email
-	apiToken := config.SMTPApiToken // use API Token
+	templates := make(map[string]*template.Template, len(pages))
+	for _, page := range pages {
+		t, err := layouts.Clone()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := t.ParseFiles(page); err != nil {
+			return nil, err
+		}
+		if t.Lookup("subject") == nil {
+			return nil, fmt.Errorf("template %s has no subject", page)
+		}
+		templates[filepath.Base(page)] = t
+	}
+	return templates, nil
+}
 
-	var body bytes.Buffer
+// EmailTemplates lists the loaded templates with the locales each one is
+// translated to.
+func EmailTemplates() map[string][]string {
+	names := map[string][]string{}
+	for locale, pages := range emailer.templates {
+		for name := range pages {
+			names[name] = append(names[name], locale)
+		}
+	}
+	for _, locales := range names {
+		sort.Strings(locales)
+	}
+	return names
+}
 
-	template, err := ParseTemplateDir("templates")
-	if err != nil {
-		log.Fatal("Could not parse template", err)
-	}
-
-	template.ExecuteTemplate(&body, emailTemp, &data)
-
-	// Create email request
-	emailReq := EmailRequest{
-		From: struct {
-			Email string `json:"email"`
-			Name  string `json:"name"`
-		}{
-			Email: from,
-			Name:  "Sender Name",
-		},
-		To: []struct {
-			Email string `json:"email"`
-			Name  string `json:"name"`
-		}{
-			{
-				Email: to,
-				Name:  user.Name,
-			},
-		},
-		Subject: data.Subject,
-		Text:    html2text.HTML2Text(body.String()),
-		HTML:    body.String(),
+// RenderEmail renders the template name for a recipient preferring locale.
+// A template missing in locale falls back to its base language and then to
+// DefaultLocale.
+func RenderEmail(locale string, name string, data *EmailData) (*RenderedEmail, error) {
+	var tmpl *template.Template
+	for _, l := range localeFallbacks(NormalizeLocale(locale)) {
+		if tmpl = emailer.templates[l][name]; tmpl != nil {
+			locale = l
+			break
+		}
+	}
+	if tmpl == nil {
+		return nil, fmt.Errorf("unknown email template %s", name)
 	}
 
-	// Convert request to JSON
-	jsonData, err := json.Marshal(emailReq)
-	if err != nil {
-		log.Fatal("Error marshalling JSON:", err)
+	d := *data
+	d.Locale = locale
+
+	var subject bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&subject, "subject", &d); err != nil {
+		return nil, fmt.Errorf("could not render subject of %s: %w", name, err)
 	}
+	// The subject is a header, not HTML, so undo html/template's escaping.
+	d.Subject = html.UnescapeString(strings.TrimSpace(subject.String()))
 
-	// Create HTTP request
-	req, err := http.NewRequest("POST", MailtrapTestApiUrl, bytes.NewBuffer(jsonData))
-	if err != nil {
-		log.Fatal("Error creating request:", err)
+	var body bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&body, name, &d); err != nil {
+		return nil, fmt.Errorf("could not render template %s: %w", name, err)
 	}
 
-	// Set request headers
-	req.Header.Add("Authorization", "Bearer"+" "+apiToken)
-	req.Header.Add("Content-Type", "application/json")
+	return &RenderedEmail{
+		Locale:  locale,
+		Subject: d.Subject,
+		HTML:    body.String(),
+		Text:    html2text.HTML2Text(body.String()),
+	}, nil
+}
 
-	// Send request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("error sending request: %w", err)
+// SendEmail renders the template emailTemp in the user's locale and sends it
+// to the user.
+func SendEmail(ctx context.Context, user *models.User, data *EmailData, emailTemp string) (err error) {
+	ctx, span := tracer.Start(ctx, "utils.SendEmail", trace.WithAttributes(attribute.String("email.template", emailTemp)))
+	defer func() {
+		result := "sent"
+		if err != nil {
+			result = "failed"
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		metrics.Emails.WithLabelValues(emailTemp, result).Inc()
+		span.End()
+	}()
+
+	if emailer.mailer == nil {
+		return fmt.Errorf("email is not initialized")
 	}
-	defer resp.Body.Close()
 
-	// Read response
-	respBody, err := io.ReadAll(resp.Body)
+	rendered, err := RenderEmail(user.Locale, emailTemp, data)
 	if err != nil {
-		return fmt.Errorf("error reading response: %w", err)
+		return err
 	}
+	span.SetAttributes(attribute.String("email.locale", rendered.Locale))
 
-	// Check response status
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to send email. Status: %s, Response: %s", resp.Status, string(respBody))
+	msg := mailer.Message{
+		From:     emailer.from,
+		FromName: emailer.fromName,
+		To:       user.Email,
+		ToName:   user.Name,
+		Subject:  rendered.Subject,
+		Text:     rendered.Text,
+		HTML:     rendered.HTML,
+	}
+	if err := emailer.mailer.Send(ctx, &msg); err != nil {
+		return err
 	}
 
-	fmt.Println("Email sent successfully")
+	slog.InfoContext(ctx, "email sent", "template", emailTemp, "locale", rendered.Locale)
 	return nil
 }
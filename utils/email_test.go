@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Llane00/ramen-backend/mailer"
+)
+
+func TestRenderEmailLocales(t *testing.T) {
+	if err := InitEmail(&mailer.Recorder{}, "../templates", "noreply@ramen.example", "Ramen"); err != nil {
+		t.Fatalf("InitEmail: %v", err)
+	}
+
+	data := EmailData{URL: "https://ramen.example/verify", FirstName: "Taro"}
+	tests := []struct {
+		locale      string
+		wantLocale  string
+		wantSubject string
+	}{
+		{"", DefaultLocale, "Your account verification code"},
+		{"ja-JP", "ja", "アカウント確認コードのお知らせ"},
+		{"ja", "ja", "アカウント確認コードのお知らせ"},
+		{"fr", DefaultLocale, "Your account verification code"},
+	}
+	for _, tt := range tests {
+		rendered, err := RenderEmail(tt.locale, "verificationCode.html", &data)
+		if err != nil {
+			t.Fatalf("RenderEmail(%q): %v", tt.locale, err)
+		}
+		if rendered.Locale != tt.wantLocale || rendered.Subject != tt.wantSubject {
+			t.Errorf("RenderEmail(%q) = %s %q, want %s %q", tt.locale, rendered.Locale, rendered.Subject, tt.wantLocale, tt.wantSubject)
+		}
+		if !strings.Contains(rendered.HTML, `lang="`+tt.wantLocale+`"`) || !strings.Contains(rendered.HTML, data.URL) {
+			t.Errorf("RenderEmail(%q) body is missing the locale or URL", tt.locale)
+		}
+	}
+
+	// Order emails also need the order they are about.
+	data.Order = &OrderEmailData{
+		Number: "1A2B3C4D",
+		Status: "shipping",
+		Items:  []OrderLineData{{Name: "Shoyu ramen", Quantity: 2, UnitPrice: "$12.00", Subtotal: "$24.00"}},
+		Total:  "$24.00",
+	}
+
+	// Every template must render in every locale it is translated to.
+	for name, locales := range EmailTemplates() {
+		for _, locale := range locales {
+			rendered, err := RenderEmail(locale, name, &data)
+			if err != nil || rendered.Subject == "" {
+				t.Errorf("RenderEmail(%q, %s) = %v, %v", locale, name, rendered, err)
+			}
+		}
+	}
+}
+
+func TestLocaleFromAcceptLanguage(t *testing.T) {
+	tests := map[string]string{
+		"":                        "",
+		"*":                       "",
+		"ja,en-US;q=0.8":          "ja",
+		"en-US;q=0.5, pt-BR;q=.9": "pt-BR",
+		"not a header;;":          "",
+	}
+	for header, want := range tests {
+		if got := LocaleFromAcceptLanguage(header); got != want {
+			t.Errorf("LocaleFromAcceptLanguage(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestOrderEmailLayoutOverride(t *testing.T) {
+	if err := InitEmail(&mailer.Recorder{}, "../templates", "noreply@ramen.example", "Ramen"); err != nil {
+		t.Fatalf("InitEmail: %v", err)
+	}
+
+	data := EmailData{Order: &OrderEmailData{
+		Number: "1A2B3C4D",
+		Items:  []OrderLineData{{Name: "Shoyu ramen", Quantity: 2, UnitPrice: "$12.00", Subtotal: "$24.00"}},
+		Total:  "$24.00",
+	}}
+	for locale, header := range map[string]string{"en": "Subtotal", "ja": "小計"} {
+		rendered, err := RenderEmail(locale, "orderConfirmation.html", &data)
+		if err != nil {
+			t.Fatalf("RenderEmail(%s): %v", locale, err)
+		}
+		for _, want := range []string{header, "Shoyu ramen", "$24.00"} {
+			if !strings.Contains(rendered.HTML, want) {
+				t.Errorf("RenderEmail(%s) is missing %q", locale, want)
+			}
+		}
+	}
+}
+
+func TestRenderOrderStatusEmail(t *testing.T) {
+	if err := InitEmail(&mailer.Recorder{}, "../templates", "noreply@ramen.example", "Ramen"); err != nil {
+		t.Fatalf("InitEmail: %v", err)
+	}
+
+	tests := map[string]string{
+		"shipping":  "its way",
+		"delivered": "Enjoy your meal",
+		"cancelled": "been cancelled",
+		"pending":   "is now pending",
+	}
+	for status, want := range tests {
+		data := EmailData{FirstName: "Taro", Order: &OrderEmailData{Number: "1A2B3C4D", ShopName: "Menya", Status: status}}
+		rendered, err := RenderEmail("en", "orderStatus.html", &data)
+		if err != nil {
+			t.Fatalf("RenderEmail(%s): %v", status, err)
+		}
+		if !strings.Contains(rendered.HTML, want) {
+			t.Errorf("RenderEmail(%s) is missing %q", status, want)
+		}
+	}
+}
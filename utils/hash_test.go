@@ -0,0 +1,21 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHashToken(t *testing.T) {
+	key, _ := GenerateAPIKey()
+	hash := HashToken(key)
+	if len(hash) != 64 || hash != HashToken(key) {
+		t.Fatalf("HashToken(%q) = %q, want a stable hex SHA-256", key, hash)
+	}
+	if strings.Contains(hash, key) || hash == HashToken(key+"x") {
+		t.Fatalf("HashToken(%q) = %q does not hide the token", key, hash)
+	}
+	// Known answer: SHA-256 of "abc".
+	if got := HashToken("abc"); got != "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad" {
+		t.Errorf("HashToken(abc) = %s", got)
+	}
+}
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// DefaultLocale is the locale of users who have not chosen one and of the
+// templates in the root of the template directory.
+const DefaultLocale = "en"
+
+// NormalizeLocale returns the canonical BCP 47 form of tag, e.g. "pt-BR" for
+// "pt_br", or "" if tag is not a valid language tag.
+func NormalizeLocale(tag string) string {
+	t, err := language.Parse(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+	if err != nil || t == language.Und {
+		return ""
+	}
+	return t.String()
+}
+
+// LocaleFromAcceptLanguage returns the most preferred language of an
+// Accept-Language header, or "" if it names none.
+func LocaleFromAcceptLanguage(header string) string {
+	tags, _, err := language.ParseAcceptLanguage(header)
+	if err != nil {
+		return ""
+	}
+	for _, t := range tags {
+		// "*" parses as "mul", multiple languages.
+		if t != language.Und && t != language.Make("mul") {
+			return t.String()
+		}
+	}
+	return ""
+}
+
+// localeFallbacks lists the locales to try for locale, most specific first:
+// "pt-BR" tries "pt-BR", "pt" and then DefaultLocale.
+func localeFallbacks(locale string) []string {
+	var locales []string
+	for locale != "" {
+		locales = append(locales, locale)
+		i := strings.LastIndex(locale, "-")
+		if i < 0 {
+			break
+		}
+		locale = locale[:i]
+	}
+	return append(locales, DefaultLocale)
+}
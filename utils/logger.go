@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type requestIDKey struct{}
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are matched against log attribute keys and query parameters.
+// Any key containing one of them has its value replaced before it is written.
+var sensitiveKeys = []string{
+	"password",
+	"token",
+	"secret",
+	"authorization",
+	"cookie",
+	"api_key",
+	"api-key",
+	"apikey",
+}
+
+// sensitiveCodes are one-time codes. They only match the whole key, so keys
+// such as status_code or country_code are still logged.
+var sensitiveCodes = map[string]bool{
+	"code":              true,
+	"otp":               true,
+	"totp_code":         true,
+	"recovery_code":     true,
+	"recovery_codes":    true,
+	"verification_code": true,
+}
+
+// WithRequestID returns a copy of ctx carrying the request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID stored in ctx, if any.
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// IsSensitiveKey reports whether values stored under key must not be logged.
+func IsSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	if sensitiveCodes[key] {
+		return true
+	}
+	for _, s := range sensitiveKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactQuery returns the encoded query string with sensitive values masked.
+func RedactQuery(values url.Values) string {
+	if len(values) == 0 {
+		return ""
+	}
+	masked := make(url.Values, len(values))
+	for key, vals := range values {
+		if IsSensitiveKey(key) {
+			masked[key] = []string{redacted}
+			continue
+		}
+		masked[key] = vals
+	}
+	return masked.Encode()
+}
+
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	if IsSensitiveKey(a.Key) {
+		return slog.String(a.Key, redacted)
+	}
+	return a
+}
+
+// contextHandler adds the request ID and trace ID found in the record's context
+// to every record.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestIDFromContext(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// NewLogger builds a structured logger. Production writes JSON, every other
+// environment writes human readable text.
+func NewLogger(env string, level string, w io.Writer) *slog.Logger {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		lvl = slog.LevelInfo
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl, ReplaceAttr: redactAttr}
+
+	var handler slog.Handler
+	if env == "production" {
+		handler = slog.NewJSONHandler(w, opts)
+	} else {
+		handler = slog.NewTextHandler(w, opts)
+	}
+
+	return slog.New(contextHandler{handler})
+}
+
+// InitLogger installs the structured logger as the process wide default.
+func InitLogger(env string, level string) {
+	slog.SetDefault(NewLogger(env, level, os.Stdout))
+}
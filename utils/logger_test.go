@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestIsSensitiveKey(t *testing.T) {
+	tests := map[string]bool{
+		"password":         true,
+		"new_password":     true,
+		"refresh_token":    true,
+		"Authorization":    true,
+		"code":             true,
+		"recovery_code":    true,
+		"status_code":      false,
+		"country_code":     false,
+		"error_code":       false,
+		"postal_code":      false,
+		"email":            false,
+		"code_challenge":   false,
+		"X-Api-Key":        true,
+		"x_api_key":        true,
+		"client_secret":    true,
+		"session_cookie":   true,
+		"verification_url": false,
+	}
+	for key, want := range tests {
+		if got := IsSensitiveKey(key); got != want {
+			t.Errorf("IsSensitiveKey(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestRedactQuery(t *testing.T) {
+	query := url.Values{"code": {"abc"}, "state": {"xyz"}, "country_code": {"JP"}}
+	if got, want := RedactQuery(query), "code=%5BREDACTED%5D&country_code=JP&state=xyz"; got != want {
+		t.Errorf("RedactQuery() = %q, want %q", got, want)
+	}
+}
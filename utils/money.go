@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/currency"
+)
+
+// currencySymbols are the symbols written before amounts. Other currencies
+// are written with their ISO 4217 code, e.g. "CHF 12.50".
+var currencySymbols = map[string]string{
+	"USD": "$",
+	"CAD": "CA$",
+	"AUD": "A$",
+	"EUR": "€",
+	"GBP": "£",
+}
+
+// FormatCents formats an amount in the currency's minor units, as prices are
+// stored, e.g. "$1,234.50" for 123450 USD and "JPY 1,234" for 1234 JPY.
+// Currencies unknown to ISO 4217 are assumed to have two decimals.
+func FormatCents(cents int64, code string) string {
+	var b strings.Builder
+	if cents < 0 {
+		b.WriteByte('-')
+		cents = -cents
+	}
+
+	if symbol, ok := currencySymbols[code]; ok {
+		b.WriteString(symbol)
+	} else {
+		b.WriteString(code + " ")
+	}
+
+	scale := minorDigits(code)
+	divisor := int64(1)
+	for range scale {
+		divisor *= 10
+	}
+
+	units := strconv.FormatInt(cents/divisor, 10)
+	for i, digit := range units {
+		if i > 0 && (len(units)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(digit)
+	}
+
+	if scale > 0 {
+		fraction := strconv.FormatInt(cents%divisor, 10)
+		b.WriteByte('.')
+		b.WriteString(strings.Repeat("0", scale-len(fraction)))
+		b.WriteString(fraction)
+	}
+	return b.String()
+}
+
+// minorDigits returns the number of decimals in the ISO 4217 currency code.
+func minorDigits(code string) int {
+	unit, err := currency.ParseISO(code)
+	if err != nil {
+		return 2
+	}
+	scale, _ := currency.Standard.Rounding(unit)
+	return scale
+}
@@ -0,0 +1,28 @@
+package utils
+
+import "testing"
+
+func TestFormatCents(t *testing.T) {
+	tests := []struct {
+		cents    int64
+		currency string
+		want     string
+	}{
+		{0, "USD", "$0.00"},
+		{5, "USD", "$0.05"},
+		{123450, "USD", "$1,234.50"},
+		{-99, "EUR", "-€0.99"},
+		{100000000, "GBP", "£1,000,000.00"},
+		{1250, "CHF", "CHF 12.50"},
+		{1234, "JPY", "JPY 1,234"},
+		{-500, "KRW", "-KRW 500"},
+		{12345, "BHD", "BHD 12.345"},
+		{5, "KWD", "KWD 0.005"},
+		{1250, "XYZ", "XYZ 12.50"},
+	}
+	for _, tt := range tests {
+		if got := FormatCents(tt.cents, tt.currency); got != tt.want {
+			t.Errorf("FormatCents(%d, %s) = %q, want %q", tt.cents, tt.currency, got, tt.want)
+		}
+	}
+}
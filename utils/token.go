@@ -1,22 +1,39 @@
 package utils
 
 import (
-	"encoding/base64"
 	"fmt"
 	"time"
 
+	"github.com/Llane00/ramen-backend/initializers"
+	"github.com/Llane00/ramen-backend/keyset"
 	"github.com/golang-jwt/jwt"
 )
 
-func CreateToken(ttl time.Duration, payload interface{}, privateKey string) (string, error) {
-	decodedPrivateKey, err := base64.StdEncoding.DecodeString(privateKey)
-	if err != nil {
-		return "", fmt.Errorf("could not decode key: %w", err)
-	}
-	key, err := jwt.ParseRSAPrivateKeyFromPEM(decodedPrivateKey)
+// purposeClaim marks tokens that are only good for one step of a flow, such as
+// the MFA challenge issued between password and second factor. ValidateToken
+// rejects them so they can never be used as access or refresh tokens.
+const purposeClaim = "purpose"
+
+const (
+	PurposeMFA        = "mfa"
+	PurposeOAuthState = "oauth_state"
+)
+
+// CreateToken signs a token for payload with the current signing key of use.
+func CreateToken(ttl time.Duration, payload interface{}, use keyset.Use) (string, error) {
+	return createToken(ttl, payload, "", use)
+}
+
+// CreatePurposeToken creates a token that only ValidatePurposeToken accepts,
+// and only for the same purpose.
+func CreatePurposeToken(ttl time.Duration, payload interface{}, purpose string, use keyset.Use) (string, error) {
+	return createToken(ttl, payload, purpose, use)
+}
 
+func createToken(ttl time.Duration, payload interface{}, purpose string, use keyset.Use) (string, error) {
+	key, err := initializers.Keys.SigningKey(use)
 	if err != nil {
-		return "", fmt.Errorf("create: parse key: %w", err)
+		return "", fmt.Errorf("create: %w", err)
 	}
 
 	now := time.Now().UTC()
@@ -26,33 +43,42 @@ func CreateToken(ttl time.Duration, payload interface{}, privateKey string) (str
 	claims["exp"] = now.Add(ttl).Unix()
 	claims["iat"] = now.Unix()
 	claims["nbf"] = now.Unix()
+	if purpose != "" {
+		claims[purposeClaim] = purpose
+	}
 
-	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(key)
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
 
+	signed, err := token.SignedString(key.Private)
 	if err != nil {
 		return "", fmt.Errorf("create: sign token: %w", err)
 	}
 
-	return token, nil
+	return signed, nil
 }
 
-func ValidateToken(token string, publicKey string) (interface{}, error) {
-	decodedPublicKey, err := base64.StdEncoding.DecodeString(publicKey)
-	if err != nil {
-		return nil, fmt.Errorf("could not decode: %w", err)
-	}
-
-	key, err := jwt.ParseRSAPublicKeyFromPEM(decodedPublicKey)
+// ValidateToken verifies a token of use with the key named by its kid header
+// and returns its subject.
+func ValidateToken(token string, use keyset.Use) (interface{}, error) {
+	return validateToken(token, "", use)
+}
 
-	if err != nil {
-		return "", fmt.Errorf("validate: parse key: %w", err)
-	}
+// ValidatePurposeToken validates a token created by CreatePurposeToken for
+// purpose and returns its subject.
+func ValidatePurposeToken(token string, purpose string, use keyset.Use) (interface{}, error) {
+	return validateToken(token, purpose, use)
+}
 
+func validateToken(token string, purpose string, use keyset.Use) (interface{}, error) {
 	parsedToken, err := jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
 		if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
 			return nil, fmt.Errorf("unexpected method: %s", t.Header["alg"])
 		}
-		return key, nil
+		kid, _ := t.Header["kid"].(string)
+		return initializers.Keys.VerificationKey(use, kid)
 	})
 
 	if err != nil {
@@ -64,5 +90,9 @@ func ValidateToken(token string, publicKey string) (interface{}, error) {
 		return nil, fmt.Errorf("validate: invalid token")
 	}
 
+	if got, _ := claims[purposeClaim].(string); got != purpose {
+		return nil, fmt.Errorf("validate: token purpose %q, want %q", got, purpose)
+	}
+
 	return claims["sub"], nil
 }
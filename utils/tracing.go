@@ -0,0 +1,20 @@
+package utils
+
+import (
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+)
+
+var tracer = otel.Tracer("github.com/Llane00/ramen-backend/utils")
+
+// NewHTTPClient returns an HTTP client whose requests are traced as client
+// spans and carry the W3C trace context to the remote service.
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: otelhttp.NewTransport(http.DefaultTransport),
+	}
+}